@@ -4,7 +4,10 @@ const inquirer = require('inquirer').default;
 const chalk = require('chalk');
 const fs = require('fs-extra');
 const path = require('path');
-const { execSync } = require('child_process');
+const { execSync, exec } = require('child_process');
+const { promisify } = require('util');
+
+const execAsync = promisify(exec);
 
 // Stack configurations
 const STACKS = {
@@ -207,7 +210,7 @@ class TinyAppFactory {
       if (answers.stack === 'go-gin') {
         console.log(chalk.gray('  4. go mod download'));
         console.log(chalk.gray('  5. cd frontend && npm install'));
-        console.log(chalk.gray('  6. npm run dev (frontend) & go run main.go (backend)'));
+        console.log(chalk.gray('  6. npm run dev (frontend) & go run . --dev (backend)'));
       } else if (answers.stack === 'bun-hono') {
         console.log(chalk.gray('  4. bun install'));
         console.log(chalk.gray('  5. bun run dev'));
@@ -241,7 +244,8 @@ class TinyAppFactory {
       '{{PROJECT_NAME}}': config.projectName,
       '{{PROJECT_SLUG}}': projectSlug,
       '{{PORT}}': '3000',
-      '{{STACK}}': STACKS[config.stack].name
+      '{{STACK}}': STACKS[config.stack].name,
+      '{{ENV_PREFIX}}': projectSlug.toUpperCase().replace(/-/g, '_')
     };
 
     // Find all files to replace
@@ -451,7 +455,7 @@ ${stack === 'go-gin' ? `
 2. Install frontend deps: \`cd frontend && npm install\`
 3. Start development:
    - Frontend: \`cd frontend && npm run dev\`
-   - Backend: \`go run main.go\`
+   - Backend: \`go run . --dev\`
 4. Open http://localhost:3000
 ` : `
 1. Install dependencies: \`bun install\`
@@ -571,6 +575,16 @@ ${stack === 'go-gin' ? `
     // Build frontend first
     const frontendPath = path.join(projectPath, 'frontend');
     if (await fs.pathExists(frontendPath)) {
+      // Regenerate the typed API client so the frontend build catches drift
+      if (await fs.pathExists(path.join(frontendPath, 'src', 'lib', 'api.ts'))) {
+        console.log(chalk.gray('Generating API client...'));
+        try {
+          await execAsync('go run . gen-client', { cwd: projectPath });
+        } catch (error) {
+          console.log(chalk.yellow('⚠ API client generation failed, using existing client:'), error.message);
+        }
+      }
+
       console.log(chalk.gray('Building frontend...'));
       try {
         const { stdout, stderr } = await execAsync('npm run build', {
@@ -583,10 +597,22 @@ ${stack === 'go-gin' ? `
       }
     }
     
+    // Run the project's tests; a failing test stops the build
+    console.log(chalk.gray('Running Go tests...'));
+    try {
+      await execAsync('go test ./...', { cwd: projectPath });
+      console.log(chalk.green('✓ Tests passed'));
+    } catch (error) {
+      console.log(chalk.red('✗ Go tests failed:'));
+      console.log(error.stdout || error.message);
+      return;
+    }
+
     // Build Go binary
     console.log(chalk.gray('Building Go binary...'));
     try {
-      const { stdout, stderr } = await execAsync('go build -ldflags="-s -w" -o ' + projectName, {
+      const ldflags = await this.goLdflags(projectPath);
+      const { stdout, stderr } = await execAsync(`go build -ldflags="${ldflags}" -o ${projectName}`, {
         cwd: projectPath
       });
       
@@ -609,6 +635,64 @@ ${stack === 'go-gin' ? `
     }
   }
 
+  // Linker flags that strip debug info and stamp version, commit, build
+  // time, target and the self-update settings into the binary (read by
+  // version.go and update.go in the Go templates)
+  async goLdflags(projectPath) {
+    const run = async (command) => {
+      try {
+        const { stdout } = await execAsync(command, { cwd: projectPath });
+        return stdout.trim();
+      } catch (error) {
+        return '';
+      }
+    };
+
+    let version = '';
+    for (const file of ['package.json', path.join('frontend', 'package.json')]) {
+      try {
+        const packageJson = await fs.readJson(path.join(projectPath, file));
+        if (packageJson.version && packageJson.version !== '0.0.0') {
+          version = packageJson.version;
+          break;
+        }
+      } catch (error) {
+        // No package.json, try the next source
+      }
+    }
+    if (!version) {
+      version = (await run('git describe --tags --always --dirty')).replace(/^v/, '');
+    }
+
+    const commit = await run('git rev-parse --short HEAD');
+    const [goos, goarch] = (await run('go env GOOS GOARCH')).split(/\s+/);
+    const buildTime = new Date().toISOString().replace(/\.\d+Z$/, 'Z');
+
+    // Self-update: the public key from `update-keygen` and the manifest URL
+    // (update.go in the Go templates)
+    let updatePublicKey = '';
+    try {
+      updatePublicKey = (await fs.readFile(path.join(projectPath, 'update.pub'), 'utf8')).trim();
+    } catch (error) {
+      // No signing key, the binary won't update itself
+    }
+
+    const vars = {
+      version: version || 'dev',
+      commit,
+      buildTime,
+      target: goos && goarch ? `${goos}/${goarch}` : '',
+      updatePublicKey,
+      updateURL: updatePublicKey ? process.env.TINYAPP_UPDATE_URL || '' : ''
+    };
+    const flags = ['-s', '-w'];
+    for (const [name, value] of Object.entries(vars)) {
+      const safe = value.replace(/[^\w.+\-:\/=?&%~]/g, '');
+      if (safe) flags.push(`-X main.${name}=${safe}`);
+    }
+    return flags.join(' ');
+  }
+
   async buildBunProject(projectPath, projectName) {
     console.log(chalk.yellow('Building Bun project...'));
     
@@ -1654,7 +1738,7 @@ Double-click the binary to run, or use from command line:
 
   def install
     # Build the application
-    system "go", "build", "-ldflags", "-s -w", "-o", bin/"${projectName}"
+    system "go", "build", "-ldflags", "-s -w -X main.version=${version}", "-o", bin/"${projectName}"
   end
 
   test do
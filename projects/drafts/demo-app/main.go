@@ -2,18 +2,45 @@ package main
 
 import (
 	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 )
 
 //go:embed static/*
 var staticFiles embed.FS
 
 func main() {
+	// "--version" prints the build metadata, e.g. for package managers
+	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-version" || os.Args[1] == "version") {
+		fmt.Println("Task Cherry", buildVersion())
+		return
+	}
+
+	// Load configuration from flags, environment and config file
+	cfg, err := LoadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+	cfg.Print(os.Stderr)
+
+	// Log through slog at log-level; log.Printf lines are info
+	var level slog.Level
+	level.UnmarshalText([]byte(cfg.LogLevel))
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
 	// Create a simple HTTP server
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		// Serve the embedded HTML file
 		file, err := staticFiles.Open("static/index.html")
 		if err != nil {
@@ -26,17 +53,39 @@ func main() {
 		io.Copy(w, file)
 	})
 	
-	http.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, `{"status":"ok","message":"Task Cherry is running!","stack":"Go","size":"12MB"}`)
+		json.NewEncoder(w).Encode(map[string]any{
+			"status":        "ok",
+			"message":       "Task Cherry is running!",
+			"stack":         "Go",
+			"version":       buildVersion().Version,
+			"uptimeSeconds": uptime(),
+			"binarySize":    binarySize(),
+			"config":        cfg.Public(),
+		})
+	})
+
+	mux.HandleFunc("/api/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(buildVersion())
 	})
 	
-	log.Printf("🍒 Task Cherry starting on port 3000")
+	srv := NewServer(cfg, Security(cfg, mux))
+
+	scheme := "http"
+	if cfg.TLSEnabled() {
+		scheme = "https"
+	}
+
+	log.Printf("🍒 Task Cherry %s starting on %s", buildVersion().Version, cfg.Addr())
 	log.Printf("📊 Stack: Go (simple)")
-	log.Printf("🌐 Open http://localhost:3000")
-	log.Printf("💾 Size: ~12MB executable")
+	log.Printf("🌐 Open %s://localhost:%d", scheme, cfg.Port)
+	log.Printf("💾 Size: %.1f MB executable", float64(binarySize())/(1<<20))
 	
-	if err := http.ListenAndServe(":3000", nil); err != nil {
-		log.Fatal("Failed to start server:", err)
+	if err := srv.Run(); err != nil {
+		slog.Error("server error", "error", err)
+		os.Exit(1)
 	}
+	log.Printf("👋 Task Cherry stopped")
 }
\ No newline at end of file
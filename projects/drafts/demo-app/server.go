@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Server runs the HTTP server with signal handling, a drain timeout on
+// shutdown and hooks for closing databases and other resources.
+type Server struct {
+	cfg  *Config
+	http *http.Server
+
+	mu    sync.Mutex
+	hooks []func(ctx context.Context) error
+}
+
+// NewServer creates a server for handler using the listen, TLS and shutdown
+// settings from cfg.
+func NewServer(cfg *Config, handler http.Handler) *Server {
+	return &Server{
+		cfg: cfg,
+		http: &http.Server{
+			Handler:  handler,
+			ErrorLog: slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
+		},
+	}
+}
+
+// OnShutdown registers fn to run once in-flight requests have drained.
+// Hooks run in reverse registration order, like deferred calls, and share
+// the remaining shutdown timeout.
+func (s *Server) OnShutdown(fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, fn)
+}
+
+// Run serves until SIGINT or SIGTERM is received and then shuts down
+// gracefully.
+func (s *Server) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ln, err := net.Listen("tcp", s.cfg.Addr())
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve accepts connections on ln until ctx is cancelled, then stops
+// accepting new requests, waits up to the shutdown timeout for in-flight
+// ones and runs the shutdown hooks.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	if s.cfg.TLSEnabled() {
+		tlsConfig, err := s.cfg.TLSConfig()
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		s.http.TLSConfig = tlsConfig
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.http.Serve(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down, draining in-flight requests", "timeout", s.cfg.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	errs := []error{s.http.Shutdown(shutdownCtx)}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, err)
+	}
+
+	s.mu.Lock()
+	hooks := s.hooks
+	s.mu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		errs = append(errs, hooks[i](shutdownCtx))
+	}
+
+	return errors.Join(errs...)
+}
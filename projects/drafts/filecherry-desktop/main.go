@@ -48,7 +48,7 @@ type FileCherryApp struct {
 func NewFileCherryApp() *FileCherryApp {
 	myApp := app.NewWithID("com.filecherry.desktop")
 
-	window := myApp.NewWindow("🍒 FileCherry - Desktop & Mobile App Maker")
+	window := myApp.NewWindow(T("🍒 FileCherry - Desktop & Mobile App Maker"))
 	window.Resize(fyne.NewSize(1200, 800))
 	window.CenterOnScreen()
 
@@ -69,12 +69,12 @@ func (fc *FileCherryApp) Run() {
 func (fc *FileCherryApp) setupUI() {
 	// Create main tabs
 	tabs := container.NewAppTabs(
-		container.NewTabItem("🏠 Home", fc.createHomeTab()),
-		container.NewTabItem("🍒 Marketplace", fc.createMarketplaceTab()),
-		container.NewTabItem("🥣 My Cherry Bowl", fc.createCherryBowlTab()),
-		container.NewTabItem("🤖 AI Builder", fc.createAIBuilderTab()),
-		container.NewTabItem("ℹ️ Why FileCherry?", fc.createWhyDifferentTab()),
-		container.NewTabItem("⚙️ Settings", fc.createSettingsTab()),
+		container.NewTabItem(T("🏠 Home"), fc.createHomeTab()),
+		container.NewTabItem(T("🍒 Marketplace"), fc.createMarketplaceTab()),
+		container.NewTabItem(T("🥣 My Cherry Bowl"), fc.createCherryBowlTab()),
+		container.NewTabItem(T("🤖 AI Builder"), fc.createAIBuilderTab()),
+		container.NewTabItem(T("ℹ️ Why FileCherry?"), fc.createWhyDifferentTab()),
+		container.NewTabItem(T("⚙️ Settings"), fc.createSettingsTab()),
 	)
 
 	fc.window.SetContent(tabs)
@@ -82,11 +82,11 @@ func (fc *FileCherryApp) setupUI() {
 
 func (fc *FileCherryApp) createHomeTab() fyne.CanvasObject {
 	// Welcome section
-	welcomeTitle := widget.NewLabel("🍒 Welcome to FileCherry!")
+	welcomeTitle := widget.NewLabel(T("🍒 Welcome to FileCherry!"))
 	welcomeTitle.TextStyle.Bold = true
 	welcomeTitle.Alignment = fyne.TextAlignCenter
 
-	welcomeSubtitle := widget.NewLabel("Desktop & Mobile App Maker - Choose Your Platform, AI Builds It")
+	welcomeSubtitle := widget.NewLabel(T("Desktop & Mobile App Maker - Choose Your Platform, AI Builds It"))
 	welcomeSubtitle.Alignment = fyne.TextAlignCenter
 
 	// Stats section
@@ -94,7 +94,7 @@ func (fc *FileCherryApp) createHomeTab() fyne.CanvasObject {
 	updateStats := func() {
 		installed := len(fc.cherryBowl.InstalledCherries)
 		available := len(fc.marketplace)
-		statsBinding.Set(fmt.Sprintf("📊 Installed: %d | Available: %d | Favorites: %d", 
+		statsBinding.Set(T("📊 Installed: %d | Available: %d | Favorites: %d", 
 			installed, available, len(fc.cherryBowl.Favorites)))
 	}
 	updateStats()
@@ -103,39 +103,39 @@ func (fc *FileCherryApp) createHomeTab() fyne.CanvasObject {
 	statsLabel.Alignment = fyne.TextAlignCenter
 
 	// Quick actions
-	quickActionsTitle := widget.NewLabel("Quick Actions")
+	quickActionsTitle := widget.NewLabel(T("Quick Actions"))
 	quickActionsTitle.TextStyle.Bold = true
 
-	browseMarketplaceBtn := widget.NewButton("🔍 Browse Marketplace", func() {
+	browseMarketplaceBtn := widget.NewButton(T("🔍 Browse Marketplace"), func() {
 		// Switch to marketplace tab
 		fc.window.SetContent(container.NewAppTabs(
-			container.NewTabItem("🏠 Home", fc.createHomeTab()),
-			container.NewTabItem("🍒 Marketplace", fc.createMarketplaceTab()),
-			container.NewTabItem("🥣 My Cherry Bowl", fc.createCherryBowlTab()),
-			container.NewTabItem("🤖 AI Builder", fc.createAIBuilderTab()),
-			container.NewTabItem("⚙️ Settings", fc.createSettingsTab()),
+			container.NewTabItem(T("🏠 Home"), fc.createHomeTab()),
+			container.NewTabItem(T("🍒 Marketplace"), fc.createMarketplaceTab()),
+			container.NewTabItem(T("🥣 My Cherry Bowl"), fc.createCherryBowlTab()),
+			container.NewTabItem(T("🤖 AI Builder"), fc.createAIBuilderTab()),
+			container.NewTabItem(T("⚙️ Settings"), fc.createSettingsTab()),
 		))
 	})
 
-	viewCherryBowlBtn := widget.NewButton("🥣 View Cherry Bowl", func() {
+	viewCherryBowlBtn := widget.NewButton(T("🥣 View Cherry Bowl"), func() {
 		// Switch to cherry bowl tab
 		fc.window.SetContent(container.NewAppTabs(
-			container.NewTabItem("🏠 Home", fc.createHomeTab()),
-			container.NewTabItem("🍒 Marketplace", fc.createMarketplaceTab()),
-			container.NewTabItem("🥣 My Cherry Bowl", fc.createCherryBowlTab()),
-			container.NewTabItem("🤖 AI Builder", fc.createAIBuilderTab()),
-			container.NewTabItem("⚙️ Settings", fc.createSettingsTab()),
+			container.NewTabItem(T("🏠 Home"), fc.createHomeTab()),
+			container.NewTabItem(T("🍒 Marketplace"), fc.createMarketplaceTab()),
+			container.NewTabItem(T("🥣 My Cherry Bowl"), fc.createCherryBowlTab()),
+			container.NewTabItem(T("🤖 AI Builder"), fc.createAIBuilderTab()),
+			container.NewTabItem(T("⚙️ Settings"), fc.createSettingsTab()),
 		))
 	})
 
-	createCherryBtn := widget.NewButton("🤖 Create New Cherry", func() {
+	createCherryBtn := widget.NewButton(T("🤖 Create New Cherry"), func() {
 		// Switch to AI builder tab
 		fc.window.SetContent(container.NewAppTabs(
-			container.NewTabItem("🏠 Home", fc.createHomeTab()),
-			container.NewTabItem("🍒 Marketplace", fc.createMarketplaceTab()),
-			container.NewTabItem("🥣 My Cherry Bowl", fc.createCherryBowlTab()),
-			container.NewTabItem("🤖 AI Builder", fc.createAIBuilderTab()),
-			container.NewTabItem("⚙️ Settings", fc.createSettingsTab()),
+			container.NewTabItem(T("🏠 Home"), fc.createHomeTab()),
+			container.NewTabItem(T("🍒 Marketplace"), fc.createMarketplaceTab()),
+			container.NewTabItem(T("🥣 My Cherry Bowl"), fc.createCherryBowlTab()),
+			container.NewTabItem(T("🤖 AI Builder"), fc.createAIBuilderTab()),
+			container.NewTabItem(T("⚙️ Settings"), fc.createSettingsTab()),
 		))
 	})
 
@@ -146,7 +146,7 @@ func (fc *FileCherryApp) createHomeTab() fyne.CanvasObject {
 	)
 
 	// Recent cherries
-	recentTitle := widget.NewLabel("Recently Installed")
+	recentTitle := widget.NewLabel(T("Recently Installed"))
 	recentTitle.TextStyle.Bold = true
 
 	recentList := widget.NewList(
@@ -195,12 +195,12 @@ func (fc *FileCherryApp) createHomeTab() fyne.CanvasObject {
 func (fc *FileCherryApp) createMarketplaceTab() fyne.CanvasObject {
 	// Search and filter
 	searchEntry := widget.NewEntry()
-	searchEntry.SetPlaceHolder("Search cherries...")
+	searchEntry.SetPlaceHolder(T("Search cherries..."))
 
-	categorySelect := widget.NewSelect([]string{"All", "Productivity", "Games", "Tools", "Creative", "Education"}, nil)
-	categorySelect.SetSelected("All")
+	categorySelect := widget.NewSelect([]string{T("All"), T("Productivity"), T("Games"), T("Tools"), T("Creative"), T("Education")}, nil)
+	categorySelect.SetSelectedIndex(0)
 
-	searchBtn := widget.NewButton("🔍 Search", func() {
+	searchBtn := widget.NewButton(T("🔍 Search"), func() {
 		// Implement search functionality
 	})
 
@@ -219,7 +219,7 @@ func (fc *FileCherryApp) createMarketplaceTab() fyne.CanvasObject {
 			return container.NewVBox(
 				container.NewHBox(
 					widget.NewLabel("Cherry Name"),
-					widget.NewButton("Install", nil),
+					widget.NewButton(T("Install"), nil),
 				),
 				widget.NewLabel("Description"),
 				widget.NewLabel("Size • Downloads • Author"),
@@ -236,7 +236,7 @@ func (fc *FileCherryApp) createMarketplaceTab() fyne.CanvasObject {
 				installBtn := nameContainer.Objects[1].(*widget.Button)
 				
 				nameLabel.SetText(fmt.Sprintf("%s %s", cherry.Icon, cherry.Name))
-				installBtn.SetText("Install")
+				installBtn.SetText(T("Install"))
 				installBtn.OnTapped = func() {
 					fc.installCherry(cherry)
 				}
@@ -247,7 +247,7 @@ func (fc *FileCherryApp) createMarketplaceTab() fyne.CanvasObject {
 				
 				// Meta info
 				metaLabel := container.Objects[2].(*widget.Label)
-				metaLabel.SetText(fmt.Sprintf("%s • %d downloads • %s", 
+				metaLabel.SetText(N("%s • %d download • %s", "%s • %d downloads • %s", cherry.Downloads, 
 					cherry.Size, cherry.Downloads, cherry.Author))
 			}
 		},
@@ -255,7 +255,7 @@ func (fc *FileCherryApp) createMarketplaceTab() fyne.CanvasObject {
 
 	// Layout
 	content := container.NewVBox(
-		widget.NewLabel("🍒 Cherry Marketplace"),
+		widget.NewLabel(T("🍒 Cherry Marketplace")),
 		widget.NewSeparator(),
 		searchContainer,
 		widget.NewSeparator(),
@@ -267,7 +267,7 @@ func (fc *FileCherryApp) createMarketplaceTab() fyne.CanvasObject {
 
 func (fc *FileCherryApp) createCherryBowlTab() fyne.CanvasObject {
 	// Cherry bowl header
-	bowlTitle := widget.NewLabel("🥣 My Cherry Bowl")
+	bowlTitle := widget.NewLabel(T("🥣 My Cherry Bowl"))
 	bowlTitle.TextStyle.Bold = true
 
 	// Installed cherries list
@@ -278,7 +278,7 @@ func (fc *FileCherryApp) createCherryBowlTab() fyne.CanvasObject {
 		func() fyne.CanvasObject {
 			return container.NewHBox(
 				widget.NewLabel("Cherry Name"),
-				widget.NewButton("▶ Run", nil),
+				widget.NewButton(T("▶ Run"), nil),
 				widget.NewButton("📁", nil), // Reveal/Copy button
 				widget.NewButton("⭐", nil),
 				widget.NewButton("🗑️", nil),
@@ -328,35 +328,35 @@ func (fc *FileCherryApp) createCherryBowlTab() fyne.CanvasObject {
 
 func (fc *FileCherryApp) createAIBuilderTab() fyne.CanvasObject {
 	// AI Builder header
-	builderTitle := widget.NewLabel("🤖 AI App Builder")
+	builderTitle := widget.NewLabel(T("🤖 AI App Builder"))
 	builderTitle.TextStyle.Bold = true
 
 	// Platform choice
-	platformLabel := widget.NewLabel("Choose Platform:")
-	platformSelect := widget.NewSelect([]string{"Desktop App", "Mobile App"}, nil)
-	platformSelect.SetSelected("Desktop App")
+	platformLabel := widget.NewLabel(T("Choose Platform:"))
+	platformSelect := widget.NewSelect([]string{T("Desktop App"), T("Mobile App")}, nil)
+	platformSelect.SetSelectedIndex(0)
 
-	platformInfo := widget.NewLabel("Desktop: Native Go + Fyne app | Mobile: Static HTML that works everywhere")
+	platformInfo := widget.NewLabel(T("Desktop: Native Go + Fyne app | Mobile: Static HTML that works everywhere"))
 	platformInfo.TextStyle.Italic = true
 
 	// API Key input
-	apiKeyLabel := widget.NewLabel("AI API Key:")
+	apiKeyLabel := widget.NewLabel(T("AI API Key:"))
 	apiKeyEntry := widget.NewPasswordEntry()
-	apiKeyEntry.SetPlaceHolder("Enter your AI API key (DeepSeek, OpenAI, etc.)")
+	apiKeyEntry.SetPlaceHolder(T("Enter your AI API key (DeepSeek, OpenAI, etc.)"))
 
 	// Cherry description
-	descLabel := widget.NewLabel("Describe your app:")
+	descLabel := widget.NewLabel(T("Describe your app:"))
 	descEntry := widget.NewMultiLineEntry()
-	descEntry.SetPlaceHolder("Describe what you want your app to do...\n\nExample: 'A simple calculator with basic math operations'")
+	descEntry.SetPlaceHolder(T("Describe what you want your app to do...\n\nExample: 'A simple calculator with basic math operations'"))
 
 	// Build button
-	buildBtn := widget.NewButton("🍒 Build App", func() {
+	buildBtn := widget.NewButton(T("🍒 Build App"), func() {
 		if apiKeyEntry.Text == "" {
-			dialog.ShowInformation("Error", "Please enter an AI API key", fc.window)
+			dialog.ShowInformation(T("Error"), T("Please enter an AI API key"), fc.window)
 			return
 		}
 		if descEntry.Text == "" {
-			dialog.ShowInformation("Error", "Please describe your app", fc.window)
+			dialog.ShowInformation(T("Error"), T("Please describe your app"), fc.window)
 			return
 		}
 		
@@ -365,7 +365,7 @@ func (fc *FileCherryApp) createAIBuilderTab() fyne.CanvasObject {
 	})
 
 	// Recent builds
-	recentBuildsTitle := widget.NewLabel("Recent Builds")
+	recentBuildsTitle := widget.NewLabel(T("Recent Builds"))
 	recentBuildsTitle.TextStyle.Bold = true
 
 	recentBuildsList := widget.NewList(
@@ -404,14 +404,14 @@ func (fc *FileCherryApp) createAIBuilderTab() fyne.CanvasObject {
 
 func (fc *FileCherryApp) createWhyDifferentTab() fyne.CanvasObject {
 	// Why FileCherry header
-	whyTitle := widget.NewLabel("ℹ️ Why FileCherry is Different")
+	whyTitle := widget.NewLabel(T("ℹ️ Why FileCherry is Different"))
 	whyTitle.TextStyle.Bold = true
 
 	// Main differentiator
-	differentiatorTitle := widget.NewLabel("🎯 Two Platforms, One Experience")
+	differentiatorTitle := widget.NewLabel(T("🎯 Two Platforms, One Experience"))
 	differentiatorTitle.TextStyle.Bold = true
 
-	differentiatorText := widget.NewLabel(`FileCherry makes it simple: Choose Desktop or Mobile, AI builds it.
+	differentiatorText := widget.NewLabel(T(`FileCherry makes it simple: Choose Desktop or Mobile, AI builds it.
 
 🖥️ DESKTOP APPS (Go + Fyne)
 • Native desktop applications
@@ -425,13 +425,13 @@ func (fc *FileCherryApp) createWhyDifferentTab() fyne.CanvasObject {
 • Send via iMessage, email, anywhere
 • No installation required
 • Instant sharing and preview
-• Size: <2MB`)
+• Size: <2MB`))
 
 	// Benefits section
-	benefitsTitle := widget.NewLabel("✨ The FileCherry Advantage")
+	benefitsTitle := widget.NewLabel(T("✨ The FileCherry Advantage"))
 	benefitsTitle.TextStyle.Bold = true
 
-	benefitsText := widget.NewLabel(`🚀 INSTANT RESULTS
+	benefitsText := widget.NewLabel(T(`🚀 INSTANT RESULTS
 • AI builds your app in seconds
 • No complex setup or configuration
 • Ready to use immediately
@@ -450,7 +450,7 @@ func (fc *FileCherryApp) createWhyDifferentTab() fyne.CanvasObject {
 🤖 AI-POWERED
 • Describe what you want
 • AI handles the technical complexity
-• Multiple AI providers supported`)
+• Multiple AI providers supported`))
 
 	// Layout
 	content := container.NewVBox(
@@ -468,33 +468,33 @@ func (fc *FileCherryApp) createWhyDifferentTab() fyne.CanvasObject {
 
 func (fc *FileCherryApp) createSettingsTab() fyne.CanvasObject {
 	// Settings header
-	settingsTitle := widget.NewLabel("⚙️ Settings")
+	settingsTitle := widget.NewLabel(T("⚙️ Settings"))
 	settingsTitle.TextStyle.Bold = true
 
 	// General settings
-	generalTitle := widget.NewLabel("General")
+	generalTitle := widget.NewLabel(T("General"))
 	generalTitle.TextStyle.Bold = true
 
-	autoUpdateCheck := widget.NewCheck("Auto-update cherries", nil)
-	notificationsCheck := widget.NewCheck("Show notifications", nil)
+	autoUpdateCheck := widget.NewCheck(T("Auto-update cherries"), nil)
+	notificationsCheck := widget.NewCheck(T("Show notifications"), nil)
 
 	// Storage settings
-	storageTitle := widget.NewLabel("Storage")
+	storageTitle := widget.NewLabel(T("Storage"))
 	storageTitle.TextStyle.Bold = true
 
-	storagePathLabel := widget.NewLabel("Cherry storage path:")
+	storagePathLabel := widget.NewLabel(T("Cherry storage path:"))
 	storagePathEntry := widget.NewEntry()
 	storagePathEntry.SetText("/Users/home/.filecherry/cherries")
 
-	browseBtn := widget.NewButton("Browse", func() {
-		dialog.ShowInformation("Browse", "Folder browser not implemented yet", fc.window)
+	browseBtn := widget.NewButton(T("Browse"), func() {
+		dialog.ShowInformation(T("Browse"), T("Folder browser not implemented yet"), fc.window)
 	})
 
 	// About section
-	aboutTitle := widget.NewLabel("About")
+	aboutTitle := widget.NewLabel(T("About"))
 	aboutTitle.TextStyle.Bold = true
 
-	aboutText := widget.NewLabel("FileCherry Desktop v1.0.0\nBuilt with Go + Fyne\n\nYour Cherry Bowl & Marketplace for Tiny Apps")
+	aboutText := widget.NewLabel(T("FileCherry Desktop v%s\nBuilt with Go + Fyne\n\nYour Cherry Bowl & Marketplace for Tiny Apps", "1.0.0"))
 
 	// Layout
 	content := container.NewVBox(
@@ -521,7 +521,7 @@ func (fc *FileCherryApp) installCherry(cherry Cherry) {
 	fc.cherryBowl.InstalledCherries = append(fc.cherryBowl.InstalledCherries, cherry)
 	
 	// Show success message
-	dialog.ShowInformation("Success", fmt.Sprintf("Installed %s!", cherry.Name), fc.window)
+	dialog.ShowInformation(T("Success"), T("Installed %s!", cherry.Name), fc.window)
 }
 
 func (fc *FileCherryApp) uninstallCherry(cherryID string) {
@@ -553,21 +553,21 @@ func (fc *FileCherryApp) toggleFavorite(cherryID string) {
 
 func (fc *FileCherryApp) runCherry(cherry Cherry) {
 	// TODO: Implement cherry execution
-	dialog.ShowInformation("Running Cherry", fmt.Sprintf("Running %s...", cherry.Name), fc.window)
+	dialog.ShowInformation(T("Running Cherry"), T("Running %s...", cherry.Name), fc.window)
 }
 
 func (fc *FileCherryApp) buildAppWithAI(apiKey, description, platform string) {
 	// TODO: Implement AI app building
-	dialog.ShowInformation("Building App", fmt.Sprintf("Building your %s with AI...", platform), fc.window)
+	dialog.ShowInformation(T("Building App"), T("Building your %s with AI...", platform), fc.window)
 }
 
 func (fc *FileCherryApp) revealOrCopyCherry(cherry Cherry) {
 	if cherry.Type == "desktop" {
 		// Reveal in Finder
-		dialog.ShowInformation("Reveal in Finder", fmt.Sprintf("Opening folder containing %s", cherry.Name), fc.window)
+		dialog.ShowInformation(T("Reveal in Finder"), T("Opening folder containing %s", cherry.Name), fc.window)
 	} else if cherry.Type == "mobile" {
 		// Copy HTML to clipboard
-		dialog.ShowInformation("Copy HTML", fmt.Sprintf("HTML for %s copied to clipboard! Paste in iMessage, email, anywhere.", cherry.Name), fc.window)
+		dialog.ShowInformation(T("Copy HTML"), T("HTML for %s copied to clipboard! Paste in iMessage, email, anywhere.", cherry.Name), fc.window)
 	}
 }
 
@@ -637,6 +637,9 @@ func getSampleCherries() []Cherry {
 }
 
 func main() {
+	// Speak the user's language, if there is a catalog for it in locales/
+	SetLocale(DetectLocale())
+
 	fileCherryApp := NewFileCherryApp()
 	fileCherryApp.Run()
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// autostartSupported reports whether setAutostart works on this system.
+// On Linux the app starts on login through an XDG autostart entry.
+const autostartSupported = true
+
+// autostartPath is the XDG autostart entry of the app with the given ID.
+// The file name keeps to the characters of a desktop file ID, so an ID
+// like com.filecherry.My App becomes com.filecherry.My-App.desktop.
+func autostartPath(id string) (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	slug := strings.Map(func(r rune) rune {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune("._-", r) {
+			return r
+		}
+		return '-'
+	}, id)
+	return filepath.Join(dir, "autostart", slug+".desktop"), nil
+}
+
+// autostartEnabled reports whether the app starts on login.
+func autostartEnabled(id string) bool {
+	path, err := autostartPath(id)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// setAutostart makes the app start on login, hidden in the tray, or stop
+// doing so.
+func setAutostart(id, name string, on bool) error {
+	path, err := autostartPath(id)
+	if err != nil {
+		return err
+	}
+	// An entry written before IDs were slugged would start the app twice
+	if old := id + ".desktop"; old != filepath.Base(path) && filepath.IsLocal(old) {
+		os.Remove(filepath.Join(filepath.Dir(path), old))
+	}
+	if !on {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	entry := fmt.Sprintf("[Desktop Entry]\nType=Application\nName=%s\nExec=%s --hidden\nTerminal=false\nX-GNOME-Autostart-enabled=true\n",
+		strings.ReplaceAll(name, "\n", " "), desktopExecQuote(exe))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(entry), 0644)
+}
+
+// desktopExecQuote quotes an argument of a desktop entry's Exec key, as
+// the Desktop Entry Specification asks for paths with spaces or reserved
+// characters. The quoted argument is escaped once more as a string value,
+// so a backslash ends up as four, and % is doubled so it isn't taken for
+// a field code like %f.
+func desktopExecQuote(arg string) string {
+	if strings.ContainsAny(arg, " \t\n\"'\\><~|&;$*?#()`") {
+		r := strings.NewReplacer(`"`, `\"`, "`", "\\`", `$`, `\$`, `\`, `\\`)
+		arg = strings.ReplaceAll(`"`+r.Replace(arg)+`"`, `\`, `\\`)
+	}
+	return strings.ReplaceAll(arg, "%", "%%")
+}
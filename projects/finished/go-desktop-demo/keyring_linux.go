@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// The freedesktop Secret Service, e.g. GNOME Keyring or KWallet, on the
+// session bus
+const (
+	secretServiceDest = "org.freedesktop.secrets"
+	secretServicePath = dbus.ObjectPath("/org/freedesktop/secrets")
+	secretCollection  = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+	secretPromptWait  = 2 * time.Minute
+)
+
+// keyringService is the service attribute of FileCherry's items.
+const keyringService = "filecherry"
+
+// secretService keeps secrets as items of the default collection, found
+// by the attributes service=filecherry and name=<name>.
+type secretService struct {
+	conn *dbus.Conn
+}
+
+// serviceSecret is the Secret Service's Secret struct, (oayays).
+type serviceSecret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// systemKeyring returns the Secret Service, or nil without a session bus.
+func systemKeyring() keyring {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil
+	}
+	return &secretService{conn: conn}
+}
+
+func (s *secretService) service() dbus.BusObject {
+	return s.conn.Object(secretServiceDest, secretServicePath)
+}
+
+// openSession opens a session that passes secrets as they are, which the
+// session bus keeps to the user's processes.
+func (s *secretService) openSession() (dbus.ObjectPath, error) {
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	err := s.service().Call("org.freedesktop.Secret.Service.OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session)
+	return session, err
+}
+
+func (s *secretService) closeSession(session dbus.ObjectPath) {
+	s.conn.Object(secretServiceDest, session).Call("org.freedesktop.Secret.Session.Close", 0)
+}
+
+// find returns the item of the secret called name, unlocking it if
+// needed.
+func (s *secretService) find(name string) (dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	attributes := map[string]string{"service": keyringService, "name": name}
+	if err := s.service().Call("org.freedesktop.Secret.Service.SearchItems", 0, attributes).Store(&unlocked, &locked); err != nil {
+		return "", err
+	}
+	if len(unlocked) > 0 {
+		return unlocked[0], nil
+	}
+	if len(locked) == 0 {
+		return "", errSecretNotFound
+	}
+	return locked[0], s.unlock(locked[:1])
+}
+
+// unlock unlocks items or collections, asking the user if the keyring
+// wants to.
+func (s *secretService) unlock(objects []dbus.ObjectPath) error {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	if err := s.service().Call("org.freedesktop.Secret.Service.Unlock", 0, objects).Store(&unlocked, &prompt); err != nil {
+		return err
+	}
+	return s.prompt(prompt)
+}
+
+// prompt shows a prompt of the keyring, e.g. for the login password, and
+// waits for the user. The path "/" means there is no prompt.
+func (s *secretService) prompt(path dbus.ObjectPath) error {
+	if path == "/" {
+		return nil
+	}
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface("org.freedesktop.Secret.Prompt"),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := s.conn.AddMatchSignal(match...); err != nil {
+		return err
+	}
+	defer s.conn.RemoveMatchSignal(match...)
+	signals := make(chan *dbus.Signal, 10)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	if err := s.conn.Object(secretServiceDest, path).Call("org.freedesktop.Secret.Prompt.Prompt", 0, "").Err; err != nil {
+		return err
+	}
+	timeout := time.After(secretPromptWait)
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Path != path || signal.Name != "org.freedesktop.Secret.Prompt.Completed" || len(signal.Body) == 0 {
+				continue
+			}
+			if dismissed, _ := signal.Body[0].(bool); dismissed {
+				return errors.New("the keyring was not unlocked")
+			}
+			return nil
+		case <-timeout:
+			return errors.New("the keyring did not answer in time")
+		}
+	}
+}
+
+func (s *secretService) Get(name string) (string, error) {
+	item, err := s.find(name)
+	if err != nil {
+		return "", err
+	}
+	session, err := s.openSession()
+	if err != nil {
+		return "", err
+	}
+	defer s.closeSession(session)
+	var secret serviceSecret
+	if err := s.conn.Object(secretServiceDest, item).Call("org.freedesktop.Secret.Item.GetSecret", 0, session).Store(&secret); err != nil {
+		return "", err
+	}
+	return string(secret.Value), nil
+}
+
+func (s *secretService) Set(name, value string) error {
+	if err := s.unlock([]dbus.ObjectPath{secretCollection}); err != nil {
+		return err
+	}
+	session, err := s.openSession()
+	if err != nil {
+		return err
+	}
+	defer s.closeSession(session)
+	properties := map[string]dbus.Variant{
+		"org.freedesktop.Secret.Item.Label":      dbus.MakeVariant("FileCherry: " + name),
+		"org.freedesktop.Secret.Item.Attributes": dbus.MakeVariant(map[string]string{"service": keyringService, "name": name}),
+	}
+	secret := serviceSecret{Session: session, Value: []byte(value), ContentType: "text/plain; charset=utf8"}
+	var item, prompt dbus.ObjectPath
+	if err := s.conn.Object(secretServiceDest, secretCollection).Call("org.freedesktop.Secret.Collection.CreateItem", 0, properties, secret, true).Store(&item, &prompt); err != nil {
+		return err
+	}
+	return s.prompt(prompt)
+}
+
+func (s *secretService) Delete(name string) error {
+	item, err := s.find(name)
+	if err != nil {
+		return err
+	}
+	var prompt dbus.ObjectPath
+	if err := s.conn.Object(secretServiceDest, item).Call("org.freedesktop.Secret.Item.Delete", 0).Store(&prompt); err != nil {
+		return err
+	}
+	return s.prompt(prompt)
+}
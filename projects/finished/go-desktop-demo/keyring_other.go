@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// systemKeyring returns nil: only the Secret Service on Linux is supported
+// so far, so secrets go to the encrypted file elsewhere.
+func systemKeyring() keyring {
+	return nil
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log"
 	"time"
 	"net/http"
 	"bytes"
@@ -9,12 +13,16 @@ import (
 	"os"
 	"path/filepath"
 	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/data/binding"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 )
 
@@ -119,32 +127,53 @@ func estimateSize(stack string) string {
 }
 
 func main() {
-	// Create the app with custom theme
+	hidden := flag.Bool("hidden", false, "start hidden in the system tray, e.g. when starting on login")
+	flag.Parse()
+
+	// Speak the user's language, if there is a catalog for it in locales/
+	SetLocale(DetectLocale())
+
+	// Create the app with the theme from the settings, reloaded whenever
+	// its file in ~/.filecherry/themes changes
 	myApp := app.NewWithID("com.filecherry.desktop")
-	myApp.Settings().SetTheme(NewFileCherryTheme())
+	if err := appTheme.Use(appSettings.Theme, getThemesDir()); err != nil {
+		log.Println("Failed to load theme: ", err)
+	}
+	appTheme.SetMode(appSettings.ThemeMode)
+	myApp.Settings().SetTheme(appTheme)
+	go appTheme.Watch(context.Background(), myApp, time.Second)
 
 	// Create the main window
-	myWindow := myApp.NewWindow("🍒 FileCherry Desktop - Cherry Bowl Manager")
+	myWindow := myApp.NewWindow(T("🍒 FileCherry Desktop - Cherry Bowl Manager"))
 	myWindow.Resize(fyne.NewSize(1200, 800))
 	myWindow.CenterOnScreen()
 
+	// Secrets go to the system keyring, or to an encrypted file; an API
+	// key left in the settings by an earlier version moves there
+	appSecrets = NewSecretStore(getSecretsPath())
+	migrateSecrets(myWindow)
+
 	// Initialize cherry manager
 	cherryManager := NewCherryManager()
 
 	// Create UI elements with better hierarchy
-	title := widget.NewLabel("🍒 FileCherry Desktop")
+	title := widget.NewLabel(T("🍒 FileCherry Desktop"))
 	title.TextStyle.Bold = true
 	title.Alignment = fyne.TextAlignCenter
 
-	subtitle := widget.NewLabel("File Manager & App Builder for Cherry Descriptions")
+	subtitle := widget.NewLabel(T("File Manager & App Builder for Cherry Descriptions"))
 	subtitle.Alignment = fyne.TextAlignCenter
 
-	// Stats display (more compact)
+	// Stats display (more compact); the tray shows them too
 	statsBinding := binding.NewString()
+	var updateTray func()
 	updateStats := func() {
 		total, compiled, pending := cherryManager.GetStats()
-		statsBinding.Set(fmt.Sprintf("%d cherries • %d compiled • %d pending", 
+		statsBinding.Set(N("%d cherry • %d compiled • %d pending", "%d cherries • %d compiled • %d pending", total, 
 			total, compiled, pending))
+		if updateTray != nil {
+			updateTray()
+		}
 	}
 	updateStats()
 
@@ -154,28 +183,36 @@ func main() {
 	// Cherry list container
 	cherryList := container.NewVBox()
 
-	// Function to refresh the cherry list
+	// Running cherries; the list and the tray follow them
 	var refreshCherryList func()
+	runner := NewCherryRunner(func() {
+		refreshCherryList()
+		updateTray()
+	})
+	runner.Env = cherryEnv
+	myApp.Lifecycle().SetOnStopped(runner.StopAll)
+
+	// Function to refresh the cherry list
 	refreshCherryList = func() {
 		cherryList.RemoveAll()
 		for _, cherry := range cherryManager.GetCherries() {
-			cherryItem := createCherryItem(cherry, cherryManager, refreshCherryList, updateStats, myWindow)
+			cherryItem := createCherryItem(cherry, cherryManager, runner, refreshCherryList, updateStats, myWindow)
 			cherryList.Add(cherryItem)
 		}
 	}
 
 	// Primary Actions (Most Important)
-	createAppButton := widget.NewButton("🚀 Create New App", func() {
+	createAppButton := widget.NewButton(T("🚀 Create New App"), func() {
 		showCreateAppDialog(myWindow, cherryManager, refreshCherryList, updateStats)
 	})
 
-	aiBuilderButton := widget.NewButton("🤖 AI Builder", func() {
+	aiBuilderButton := widget.NewButton(T("🤖 AI Builder"), func() {
 		showAIBuilderDialog(myWindow, cherryManager, refreshCherryList, updateStats)
 	})
 
 	// Secondary Actions (Less Important - moved to menu)
 	// These will be accessible via a "More Actions" menu
-	moreActionsButton := widget.NewButton("⋯ More Actions", func() {
+	moreActionsButton := widget.NewButton(T("⋯ More Actions"), func() {
 		showMoreActionsMenu(myWindow, cherryManager, refreshCherryList, updateStats)
 	})
 
@@ -184,6 +221,12 @@ func main() {
 		showSettingsDialog(myWindow)
 	})
 
+	// Command palette button, for the actions without a button
+	var shortcuts *Shortcuts
+	commandsButton := widget.NewButton(T("🔎 Commands"), func() {
+		shortcuts.ShowPalette()
+	})
+
 	// Initial cherry list
 	refreshCherryList()
 
@@ -192,30 +235,30 @@ func main() {
 	scrollContainer.SetMinSize(fyne.NewSize(0, 400))
 
 	// Filter buttons
-	filterAll := widget.NewButton("All", func() {
+	filterAll := widget.NewButton(T("All"), func() {
 		refreshCherryList()
 	})
-	filterCompiled := widget.NewButton("Compiled", func() {
+	filterCompiled := widget.NewButton(T("Compiled"), func() {
 		cherryList.RemoveAll()
 		for _, cherry := range cherryManager.GetCherries() {
 			if cherry.IsCompiled {
-				cherryItem := createCherryItem(cherry, cherryManager, refreshCherryList, updateStats, myWindow)
+				cherryItem := createCherryItem(cherry, cherryManager, runner, refreshCherryList, updateStats, myWindow)
 				cherryList.Add(cherryItem)
 			}
 		}
 	})
-	filterPending := widget.NewButton("Pending", func() {
+	filterPending := widget.NewButton(T("Pending"), func() {
 		cherryList.RemoveAll()
 		for _, cherry := range cherryManager.GetCherries() {
 			if !cherry.IsCompiled {
-				cherryItem := createCherryItem(cherry, cherryManager, refreshCherryList, updateStats, myWindow)
+				cherryItem := createCherryItem(cherry, cherryManager, runner, refreshCherryList, updateStats, myWindow)
 				cherryList.Add(cherryItem)
 			}
 		}
 	})
 
 	filterContainer := container.NewHBox(
-		widget.NewLabel("Filters:"),
+		widget.NewLabel(T("Filters:")),
 		filterAll,
 		filterCompiled,
 		filterPending,
@@ -230,13 +273,14 @@ func main() {
 	// Secondary actions container (less prominent)
 	secondaryActionsContainer := container.NewHBox(
 		moreActionsButton,
+		commandsButton,
 		widget.NewSeparator(),
 		settingsButton,
 	)
 
 	// Main input container with better hierarchy
 	inputContainer := container.NewVBox(
-		widget.NewLabel("Quick Start:"),
+		widget.NewLabel(T("Quick Start:")),
 		primaryActionsContainer,
 		widget.NewSeparator(),
 		secondaryActionsContainer,
@@ -280,18 +324,92 @@ func main() {
 		
 		// Projects section
 		container.NewVBox(
-			widget.NewLabel("Your Projects:"),
+			widget.NewLabel(T("Your Projects:")),
 			filterContainer,
 			scrollContainer,
 		),
 	)
 
-	// Set content and show window
+	// Keyboard shortcuts and the command palette (Ctrl+K) for every
+	// action, with the bindings the user changed
+	actions := []*Action{
+		{ID: "app.create", Name: T("🚀 Create New App"), Shortcut: ctrl(fyne.KeyN), Run: createAppButton.OnTapped},
+		{ID: "app.ai", Name: T("🤖 AI Builder"), Shortcut: ctrl(fyne.KeyN, fyne.KeyModifierShift), Run: aiBuilderButton.OnTapped},
+		{ID: "app.compile", Name: T("⚡ Compile Apps"), Shortcut: ctrl(fyne.KeyB), Run: func() {
+			showCompileDialog(myWindow, cherryManager, refreshCherryList, updateStats)
+		}},
+		{ID: "app.templates", Name: T("📋 Browse Templates"), Shortcut: ctrl(fyne.KeyT), Run: func() {
+			showTemplatesDialog(myWindow, cherryManager, refreshCherryList, updateStats)
+		}},
+		{ID: "app.marketplace", Name: T("🛒 Marketplace"), Run: func() {
+			showMarketplaceDialog(myWindow, cherryManager, refreshCherryList, updateStats)
+		}},
+		{ID: "app.import", Name: T("📁 Import Project"), Shortcut: ctrl(fyne.KeyO), Run: func() {
+			showImportDialog(myWindow, cherryManager, refreshCherryList, updateStats)
+		}},
+		{ID: "app.export", Name: T("📤 Export Projects"), Shortcut: ctrl(fyne.KeyE), Run: func() {
+			showExportDialog(myWindow, cherryManager, refreshCherryList, updateStats)
+		}},
+		{ID: "app.more", Name: T("⋯ More Actions"), Run: moreActionsButton.OnTapped},
+		{ID: "app.settings", Name: T("⚙️ Settings"), Shortcut: ctrl(fyne.KeyComma), Run: settingsButton.OnTapped},
+		{ID: "filter.all", Name: T("🔍 Show All"), Shortcut: ctrl(fyne.Key1), Run: filterAll.OnTapped},
+		{ID: "filter.compiled", Name: T("🔍 Show Compiled"), Shortcut: ctrl(fyne.Key2), Run: filterCompiled.OnTapped},
+		{ID: "filter.pending", Name: T("🔍 Show Pending"), Shortcut: ctrl(fyne.Key3), Run: filterPending.OnTapped},
+	}
+	actions = append(actions, appTheme.Actions(myApp, getThemesDir(), func(name, mode string) {
+		appSettings.Theme, appSettings.ThemeMode = name, mode
+		if err := saveSettings(); err != nil {
+			log.Println("Failed to save settings: ", err)
+		}
+	})...)
+	shortcuts, err := NewShortcuts(filepath.Join(filepath.Dir(getSettingsPath()), "shortcuts.json"), actions)
+	if err != nil {
+		log.Fatal("Failed to load shortcuts: ", err)
+	}
+	shortcuts.Attach(myWindow)
+
+	// Closing the window hides the manager to the system tray, which
+	// starts and stops compiled cherries and shows how many are running
+	tray := NewTray(myApp, myWindow, "FileCherry", func() []*fyne.MenuItem {
+		items := []*fyne.MenuItem{fyne.NewMenuItem(T("🚀 Create New App"), func() {
+			myWindow.Show()
+			createAppButton.OnTapped()
+		})}
+		for _, cherry := range cherryManager.GetCherries() {
+			cherry := cherry
+			if !cherry.IsCompiled {
+				continue
+			}
+			if runner.Running(cherry.ID) {
+				items = append(items, fyne.NewMenuItem(T("⏹️ Stop %s", cherry.Name), func() { go runner.Stop(cherry.ID) }))
+				continue
+			}
+			items = append(items, fyne.NewMenuItem(T("▶️ Start %s", cherry.Name), func() {
+				if err := runner.Start(cherry); err != nil {
+					myApp.SendNotification(fyne.NewNotification(T("Could not start %s", cherry.Name), err.Error()))
+				}
+			}))
+		}
+		return items
+	})
+	updateTray = func() {
+		total, compiled, _ := cherryManager.GetStats()
+		running := runner.Count()
+		tray.SetStatus(N("🍒 %d cherry • %d compiled • ▶️ %d running", "🍒 %d cherries • %d compiled • ▶️ %d running", total, total, compiled, running), running > 0)
+	}
+	updateTray()
+
+	// Set content and show window; with --hidden, e.g. when starting on
+	// login, only the tray shows
 	myWindow.SetContent(content)
-	myWindow.ShowAndRun()
+	if *hidden && tray.desk != nil {
+		myApp.Run()
+	} else {
+		myWindow.ShowAndRun()
+	}
 }
 
-func createCherryItem(cherry Cherry, cherryManager *CherryManager, refreshList func(), updateStats func(), parent fyne.Window) *fyne.Container {
+func createCherryItem(cherry Cherry, cherryManager *CherryManager, runner *CherryRunner, refreshList func(), updateStats func(), parent fyne.Window) *fyne.Container {
 	// Create beautiful cherry card
 	cherryCard := NewCherryCard(cherry, 
 		func() {
@@ -306,15 +424,16 @@ func createCherryItem(cherry Cherry, cherryManager *CherryManager, refreshList f
 			}
 			refreshList()
 			updateStats()
-			dialog.ShowInformation("Cherry Compiled", fmt.Sprintf("Compiling '%s' into executable...", cherry.Name), parent)
+			dialog.ShowInformation(T("Cherry Compiled"), T("Compiling '%s' into executable...", cherry.Name), parent)
 		},
 		func() {
 			// Delete functionality with confirmation
-			dialog.ShowConfirm("Delete Cherry", 
-				fmt.Sprintf("Are you sure you want to delete '%s'? This action cannot be undone.", cherry.Name),
+			dialog.ShowConfirm(T("Delete Cherry"), 
+				T("Are you sure you want to delete '%s'? This action cannot be undone.", cherry.Name),
 				func(confirmed bool) {
 					if confirmed {
 						cherryManager.DeleteCherry(cherry.ID)
+						forgetCherrySecrets(cherry.Name)
 						refreshList()
 						updateStats()
 					}
@@ -322,7 +441,7 @@ func createCherryItem(cherry Cherry, cherryManager *CherryManager, refreshList f
 		},
 		func() {
 			// Share functionality
-			dialog.ShowInformation("Share Cherry", fmt.Sprintf("Sharing cherry '%s' with Fireproof sync!", cherry.Name), parent)
+			dialog.ShowInformation(T("Share Cherry"), T("Sharing cherry '%s' with Fireproof sync!", cherry.Name), parent)
 		},
 		refreshList,
 		updateStats,
@@ -330,7 +449,7 @@ func createCherryItem(cherry Cherry, cherryManager *CherryManager, refreshList f
 
 	// Add contextual actions for this specific cherry
 	contextualActions := container.NewHBox(
-		widget.NewButton("⚡ Compile", func() {
+		widget.NewButton(T("⚡ Compile"), func() {
 			// Compile this specific cherry
 			for i, c := range cherryManager.cherries {
 				if c.ID == cherry.ID {
@@ -342,14 +461,18 @@ func createCherryItem(cherry Cherry, cherryManager *CherryManager, refreshList f
 			}
 			refreshList()
 			updateStats()
-			dialog.ShowInformation("Compiled", fmt.Sprintf("Compiled '%s' into executable!", cherry.Name), parent)
+			dialog.ShowInformation(T("Compiled"), T("Compiled '%s' into executable!", cherry.Name), parent)
 		}),
-		widget.NewButton("📁 Open Folder", func() {
-			dialog.ShowInformation("Open Folder", fmt.Sprintf("Opening folder for %s...", cherry.Name), parent)
+		runButton(cherry, runner, parent),
+		widget.NewButton(T("📁 Open Folder"), func() {
+			dialog.ShowInformation(T("Open Folder"), T("Opening folder for %s...", cherry.Name), parent)
 		}),
-		widget.NewButton("ℹ️ Details", func() {
+		widget.NewButton(T("ℹ️ Details"), func() {
 			showProjectDetailsDialog(parent, cherry)
 		}),
+		widget.NewButton(T("🔑 Secrets"), func() {
+			showCherrySecretsDialog(parent, cherry)
+		}),
 	)
 	
 	// Wrap in container with contextual actions
@@ -359,32 +482,57 @@ func createCherryItem(cherry Cherry, cherryManager *CherryManager, refreshList f
 	)
 }
 
+// runButton starts or stops a compiled cherry. Running cherries keep
+// running while the manager is hidden in the tray.
+func runButton(cherry Cherry, runner *CherryRunner, parent fyne.Window) *widget.Button {
+	if runner.Running(cherry.ID) {
+		return widget.NewButton(T("⏹️ Stop"), func() {
+			go runner.Stop(cherry.ID)
+		})
+	}
+	var start func()
+	start = func() {
+		err := runner.Start(cherry)
+		if errors.Is(err, errSecretsLocked) {
+			// Its secrets are in the encrypted file
+			withUnlockedSecrets(parent, start)
+		} else if err != nil {
+			dialog.ShowError(err, parent)
+		}
+	}
+	button := widget.NewButton(T("▶️ Run"), start)
+	if !cherry.IsCompiled {
+		button.Disable()
+	}
+	return button
+}
+
 func showProjectDetailsDialog(parent fyne.Window, cherry Cherry) {
 	// Create project details dialog
-	detailsLabel := widget.NewLabel("Project Details")
+	detailsLabel := widget.NewLabel(T("Project Details"))
 	detailsLabel.TextStyle.Bold = true
 
 	// Project information
-	nameLabel := widget.NewLabel(fmt.Sprintf("Name: %s", cherry.Name))
-	descLabel := widget.NewLabel(fmt.Sprintf("Description: %s", cherry.Description))
-	categoryLabel := widget.NewLabel(fmt.Sprintf("Category: %s", cherry.Category))
-	stackLabel := widget.NewLabel(fmt.Sprintf("Stack: %s", cherry.Stack))
-	sizeLabel := widget.NewLabel(fmt.Sprintf("Size: %s", cherry.Size))
-	pathLabel := widget.NewLabel(fmt.Sprintf("Path: %s", cherry.Path))
-	createdLabel := widget.NewLabel(fmt.Sprintf("Created: %s", formatTime(cherry.CreatedAt)))
+	nameLabel := widget.NewLabel(T("Name: %s", cherry.Name))
+	descLabel := widget.NewLabel(T("Description: %s", cherry.Description))
+	categoryLabel := widget.NewLabel(T("Category: %s", cherry.Category))
+	stackLabel := widget.NewLabel(T("Stack: %s", cherry.Stack))
+	sizeLabel := widget.NewLabel(T("Size: %s", cherry.Size))
+	pathLabel := widget.NewLabel(T("Path: %s", cherry.Path))
+	createdLabel := widget.NewLabel(T("Created: %s", FormatDateTime(cherry.CreatedAt)))
 	
 	var lastCompiledLabel *widget.Label
 	if cherry.LastCompiled != nil {
-		lastCompiledLabel = widget.NewLabel(fmt.Sprintf("Last Compiled: %s", formatTime(*cherry.LastCompiled)))
+		lastCompiledLabel = widget.NewLabel(T("Last Compiled: %s", FormatDateTime(*cherry.LastCompiled)))
 	} else {
-		lastCompiledLabel = widget.NewLabel("Last Compiled: Never")
+		lastCompiledLabel = widget.NewLabel(T("Last Compiled: Never"))
 	}
 
-	statusLabel := widget.NewLabel(fmt.Sprintf("Status: %s", func() string {
+	statusLabel := widget.NewLabel(T("Status: %s", func() string {
 		if cherry.IsCompiled {
-			return "Compiled"
+			return T("Compiled")
 		}
-		return "Pending"
+		return T("Pending")
 	}()))
 
 	content := container.NewVBox(
@@ -401,7 +549,7 @@ func showProjectDetailsDialog(parent fyne.Window, cherry Cherry) {
 		statusLabel,
 	)
 
-	dialog.ShowCustom("Project Details", "Close", content, parent)
+	dialog.ShowCustom(T("Project Details"), T("Close"), content, parent)
 }
 
 func showMarketplaceDialog(parent fyne.Window, cherryManager *CherryManager, refreshList func(), updateStats func()) {
@@ -451,12 +599,12 @@ func showMarketplaceDialog(parent fyne.Window, cherryManager *CherryManager, ref
 				widget.NewLabel(cherry.Description),
 				widget.NewLabel(fmt.Sprintf("%s • %s", cherry.Category, cherry.Size)),
 			),
-			widget.NewButton("Install", func() {
+			widget.NewButton(T("Install"), func() {
 				// Add to cherry bowl
 				cherryManager.AddCherry(cherry.Name, cherry.Description, cherry.Category, cherry.Stack)
 				refreshList()
 				updateStats()
-				dialog.ShowInformation("Installed", fmt.Sprintf("Cherry '%s' added to your bowl!", cherry.Name), parent)
+				dialog.ShowInformation(T("Installed"), T("Cherry '%s' added to your bowl!", cherry.Name), parent)
 			}),
 		)
 		marketplaceList.Add(item)
@@ -465,44 +613,108 @@ func showMarketplaceDialog(parent fyne.Window, cherryManager *CherryManager, ref
 	scrollContainer := container.NewScroll(marketplaceList)
 	scrollContainer.SetMinSize(fyne.NewSize(600, 400))
 
-	dialog.ShowCustom("Cherry Marketplace", "Close", scrollContainer, parent)
+	dialog.ShowCustom(T("Cherry Marketplace"), T("Close"), scrollContainer, parent)
 }
 
 func showInstallDialog(parent fyne.Window, cherryManager *CherryManager, refreshList func(), updateStats func()) {
 	// This would open a file dialog to install cherries from local files
-	dialog.ShowInformation("Install from File", "This feature will allow you to install cherries from local .exe/.app files", parent)
+	dialog.ShowInformation(T("Install from File"), T("This feature will allow you to install cherries from local .exe/.app files"), parent)
 }
 
 func showSettingsDialog(parent fyne.Window) {
 	// Create settings content
-	generalLabel := widget.NewLabel("General Settings")
+	generalLabel := widget.NewLabel(T("General Settings"))
 	generalLabel.TextStyle.Bold = true
 
 	// Auto-update setting
-	autoUpdateCheck := widget.NewCheck("Enable auto-updates", nil)
+	autoUpdateCheck := widget.NewCheck(T("Enable auto-updates"), nil)
 	autoUpdateCheck.SetChecked(appSettings.AutoUpdate)
 
 	// Storage path setting
-	storagePathLabel := widget.NewLabel("Storage Path:")
+	storagePathLabel := widget.NewLabel(T("Storage Path:"))
 	storagePathEntry := widget.NewEntry()
 	storagePathEntry.SetText(appSettings.StoragePath)
-	storagePathButton := widget.NewButton("Browse", func() {
+	storagePathButton := widget.NewButton(T("Browse"), func() {
 		// This would open a folder picker dialog
-		dialog.ShowInformation("Browse Folder", "This would open a folder picker dialog", parent)
+		dialog.ShowInformation(T("Browse Folder"), T("This would open a folder picker dialog"), parent)
 	})
 
-	// AI API Key setting
-	aiKeyLabel := widget.NewLabel("AI API Key (for AI Builder):")
-	aiKeyEntry := widget.NewEntry()
-	aiKeyEntry.SetPlaceHolder("Enter your DeepSeek or OpenAI API key")
-	aiKeyEntry.SetText(appSettings.AIAPIKey)
+	// AI API Key setting. The key is never shown; typing a new one
+	// replaces it in the secret store
+	aiKeyLabel := widget.NewLabel(T("AI API Key (for AI Builder):"))
+	aiKeyEntry := widget.NewPasswordEntry()
+	aiKeyEntry.SetPlaceHolder(T("Enter your DeepSeek or OpenAI API key"))
+	aiKeyStored := widget.NewLabel(secretsLocation())
+	aiKeyMove := widget.NewButton(T("🔓 Unlock"), nil)
+	aiKeyMove.OnTapped = func() {
+		moveAIKey(parent, func() {
+			aiKeyStored.SetText(secretsLocation())
+			aiKeyMove.Hide()
+		})
+	}
+	if appSettings.AIAPIKey == "" || appSettings.AIAPIKey.IsRef() {
+		aiKeyMove.Hide()
+	}
+	aiKeyRemove := widget.NewButton(T("🗑️ Remove Key"), nil)
+	aiKeyRemove.OnTapped = func() {
+		forget := func() {
+			appSettings.AIAPIKey = ""
+			if err := saveSettings(); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to save settings: %v", err), parent)
+			}
+			aiKeyStored.SetText(secretsLocation())
+			aiKeyMove.Hide()
+			aiKeyRemove.Disable()
+		}
+		if !appSettings.AIAPIKey.IsRef() {
+			// Not migrated yet, it only is in the settings file
+			forget()
+			return
+		}
+		withUnlockedSecrets(parent, func() {
+			if err := appSecrets.Delete(appSettings.AIAPIKey); err != nil {
+				dialog.ShowError(err, parent)
+				return
+			}
+			forget()
+		})
+	}
+	if appSettings.AIAPIKey == "" {
+		aiKeyRemove.Disable()
+	}
+
+	// Appearance: a built-in theme or one from ~/.filecherry/themes, in
+	// light or dark
+	appearanceLabel := widget.NewLabel(T("Appearance"))
+	appearanceLabel.TextStyle.Bold = true
+	themeSelect := widget.NewSelect(ThemeNames(getThemesDir()), nil)
+	themeSelect.SetSelected(appTheme.Name())
+	var modeNames []string
+	for _, m := range themeModes {
+		modeNames = append(modeNames, T(m.name))
+	}
+	modeSelect := widget.NewSelect(modeNames, nil)
+	for i, m := range themeModes {
+		if m.mode == appTheme.Mode() {
+			modeSelect.SetSelectedIndex(i)
+		}
+	}
 
 	// Save button
-	saveButton := widget.NewButton("💾 Save Settings", func() {
+	saveButton := widget.NewButton(T("💾 Save Settings"), func() {
 		// Update settings from UI
 		appSettings.AutoUpdate = autoUpdateCheck.Checked
 		appSettings.StoragePath = storagePathEntry.Text
-		appSettings.AIAPIKey = aiKeyEntry.Text
+		appSettings.Theme = themeSelect.Selected
+		appSettings.ThemeMode = themeModes[modeSelect.SelectedIndex()].mode
+
+		// Switch to the theme right away
+		if err := appTheme.Use(appSettings.Theme, getThemesDir()); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to load theme: %v", err), parent)
+			return
+		}
+		appTheme.SetMode(appSettings.ThemeMode)
+		fyne.CurrentApp().Settings().SetTheme(appTheme)
 		
 		// Save to file
 		err := saveSettings()
@@ -510,16 +722,39 @@ func showSettingsDialog(parent fyne.Window) {
 			dialog.ShowError(fmt.Errorf("Failed to save settings: %v", err), parent)
 			return
 		}
-		
-		dialog.ShowInformation("Settings Saved", "Your settings have been saved successfully!", parent)
+		if aiKeyEntry.Text == "" {
+			dialog.ShowInformation(T("Settings Saved"), T("Your settings have been saved successfully!"), parent)
+			return
+		}
+
+		// Then the new API key, which may need the passphrase first. Until
+		// it is stored, e.g. when that is cancelled, the label says so
+		aiKeyStored.SetText(T("⚠️ The new key is not stored yet"))
+		withUnlockedSecrets(parent, func() {
+			ref, err := appSecrets.Put(aiKeySecret, aiKeyEntry.Text)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Your settings were saved, but the API key was not: %v", err), parent)
+				return
+			}
+			appSettings.AIAPIKey = ref
+			aiKeyEntry.SetText("")
+			aiKeyStored.SetText(secretsLocation())
+			aiKeyMove.Hide()
+			aiKeyRemove.Enable()
+			if err := saveSettings(); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to save settings: %v", err), parent)
+				return
+			}
+			dialog.ShowInformation(T("Settings Saved"), T("Your settings have been saved successfully!"), parent)
+		})
 	})
 
 	// About section
-	aboutLabel := widget.NewLabel("About FileCherry")
+	aboutLabel := widget.NewLabel(T("About FileCherry"))
 	aboutLabel.TextStyle.Bold = true
-	versionLabel := widget.NewLabel("Version: 1.0.0")
-	buildLabel := widget.NewLabel("Build: Desktop Manager")
-	authorLabel := widget.NewLabel("Built with TinyApp Factory")
+	versionLabel := widget.NewLabel(T("Version: %s", "1.0.0"))
+	buildLabel := widget.NewLabel(T("Build: Desktop Manager"))
+	authorLabel := widget.NewLabel(T("Built with TinyApp Factory"))
 
 	// Settings content with proper width
 	content := container.NewVBox(
@@ -532,6 +767,11 @@ func showSettingsDialog(parent fyne.Window) {
 		widget.NewSeparator(),
 		aiKeyLabel,
 		aiKeyEntry,
+		container.NewHBox(aiKeyStored, aiKeyMove, aiKeyRemove),
+		widget.NewSeparator(),
+		appearanceLabel,
+		themeSelect,
+		modeSelect,
 		widget.NewSeparator(),
 		saveButton,
 		widget.NewSeparator(),
@@ -543,22 +783,238 @@ func showSettingsDialog(parent fyne.Window) {
 	)
 
 	// Create custom dialog with proper size
-	settingsDialog := dialog.NewCustom("Settings", "Close", content, parent)
+	settingsDialog := dialog.NewCustom(T("Settings"), T("Close"), content, parent)
 	settingsDialog.Resize(fyne.NewSize(500, 400)) // Mobile-friendly width
 	settingsDialog.Show()
 }
 
-func formatTime(t time.Time) string {
-	return t.Format("Jan 2, 3:04 PM")
+// secretsLocation tells where the AI API key is kept, for the settings
+func secretsLocation() string {
+	switch {
+	case appSettings.AIAPIKey == "":
+		return T("No key stored")
+	case !appSettings.AIAPIKey.IsRef():
+		return T("⚠️ Still in the settings file, unlock your secrets to move it")
+	case strings.HasPrefix(string(appSettings.AIAPIKey), "keyring:"):
+		return T("🔐 Stored in the system keyring")
+	default:
+		return T("🔐 Stored in the encrypted secrets file")
+	}
+}
+
+// withUnlockedSecrets runs then once the secret store can be used. Without
+// a keyring it first asks for the passphrase of the encrypted file, or
+// for a new one if there is no file yet.
+func withUnlockedSecrets(parent fyne.Window, then func()) {
+	if !appSecrets.Locked() {
+		then()
+		return
+	}
+	title := T("🔒 Unlock Secrets")
+	hint := widget.NewLabel(T("Enter the passphrase of your secrets file."))
+	hint.Wrapping = fyne.TextWrapWord
+	passphrase := widget.NewPasswordEntry()
+	items := []*widget.FormItem{widget.NewFormItem("", hint), widget.NewFormItem(T("Passphrase"), passphrase)}
+	var repeat *widget.Entry
+	if !appSecrets.FileExists() {
+		title = T("🔒 Protect Your Secrets")
+		hint.SetText(T("There is no system keyring, so API keys are kept in a file encrypted with a passphrase. Choose one you will remember, it can't be recovered."))
+		repeat = widget.NewPasswordEntry()
+		items = append(items, widget.NewFormItem(T("Repeat"), repeat))
+	}
+
+	form := dialog.NewForm(title, T("Unlock"), T("Cancel"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		if repeat != nil && repeat.Text != passphrase.Text {
+			dialog.ShowError(errors.New(T("The passphrases don't match")), parent)
+			return
+		}
+		// Deriving the key takes a moment
+		go func() {
+			if err := appSecrets.Unlock(passphrase.Text); err != nil {
+				dialog.ShowError(err, parent)
+				return
+			}
+			then()
+		}()
+	}, parent)
+	form.Resize(fyne.NewSize(420, 0))
+	form.Show()
+}
+
+// migrateSecrets moves an AI API key that an earlier version kept in the
+// settings file into the secret store. Without a keyring that takes a
+// passphrase, which is asked for once without holding up the app; until
+// then the settings show that the key is still in the settings file.
+func migrateSecrets(parent fyne.Window) {
+	key := appSettings.AIAPIKey
+	if key == "" || key.IsRef() {
+		return
+	}
+	if !appSecrets.Locked() {
+		moveAIKey(parent, func() {})
+		return
+	}
+	if appSettings.SecretsAsked {
+		return
+	}
+	appSettings.SecretsAsked = true
+	if err := saveSettings(); err != nil {
+		log.Println("Failed to save settings: ", err)
+	}
+	ask := dialog.NewConfirm(T("🔒 Protect Your API Key"),
+		T("Your AI API key is still in the settings file. Protect it with a passphrase now? You can also do it later in Settings."),
+		func(ok bool) {
+			if ok {
+				moveAIKey(parent, func() {})
+			}
+		}, parent)
+	ask.SetConfirmText(T("Protect It"))
+	ask.SetDismissText(T("Later"))
+	ask.Show()
+}
+
+// moveAIKey moves the AI API key from the settings file into the secret
+// store, unlocking it first if needed, and then runs then.
+func moveAIKey(parent fyne.Window, then func()) {
+	withUnlockedSecrets(parent, func() {
+		ref, err := appSecrets.Put(aiKeySecret, string(appSettings.AIAPIKey))
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to store the API key: %v", err), parent)
+			return
+		}
+		appSettings.AIAPIKey = ref
+		if err := saveSettings(); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to save settings: %v", err), parent)
+		}
+		then()
+	})
+}
+
+// envName matches the names of environment variables, e.g. OPENAI_API_KEY
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// cherryEnv returns the secrets of cherry as environment variables, for
+// the runner.
+func cherryEnv(cherry Cherry) ([]string, error) {
+	var env []string
+	for name, ref := range appSettings.CherrySecrets[cherry.Name] {
+		value, err := appSecrets.Get(ref)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s of %s: %w", name, cherry.Name, err)
+		}
+		env = append(env, name+"="+value)
+	}
+	return env, nil
+}
+
+// forgetCherrySecrets deletes the secrets of a deleted cherry. Secrets in
+// an encrypted file that is locked stay in it, unreferenced.
+func forgetCherrySecrets(name string) {
+	for _, ref := range appSettings.CherrySecrets[name] {
+		if err := appSecrets.Delete(ref); err != nil {
+			log.Println("Failed to delete secret: ", err)
+		}
+	}
+	if _, ok := appSettings.CherrySecrets[name]; ok {
+		delete(appSettings.CherrySecrets, name)
+		if err := saveSettings(); err != nil {
+			log.Println("Failed to save settings: ", err)
+		}
+	}
+}
+
+// showCherrySecretsDialog edits the secrets a cherry gets as environment
+// variables when it runs. The values go to the secret store and are never
+// shown again.
+func showCherrySecretsDialog(parent fyne.Window, cherry Cherry) {
+	list := container.NewVBox()
+	var refreshList func()
+	refreshList = func() {
+		list.RemoveAll()
+		secrets := appSettings.CherrySecrets[cherry.Name]
+		if len(secrets) == 0 {
+			list.Add(widget.NewLabel(T("No secrets yet")))
+		}
+		names := make([]string, 0, len(secrets))
+		for name := range secrets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			name := name
+			list.Add(container.NewHBox(widget.NewLabel("🔑 "+name), layout.NewSpacer(), widget.NewButton(T("🗑️ Remove"), func() {
+				withUnlockedSecrets(parent, func() {
+					if err := appSecrets.Delete(secrets[name]); err != nil {
+						dialog.ShowError(err, parent)
+						return
+					}
+					delete(secrets, name)
+					if err := saveSettings(); err != nil {
+						dialog.ShowError(fmt.Errorf("Failed to save settings: %v", err), parent)
+					}
+					refreshList()
+				})
+			})))
+		}
+	}
+	refreshList()
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("OPENAI_API_KEY")
+	valueEntry := widget.NewPasswordEntry()
+	valueEntry.SetPlaceHolder(T("Value"))
+	addButton := widget.NewButton(T("➕ Add Secret"), func() {
+		name := strings.TrimSpace(nameEntry.Text)
+		if !envName.MatchString(name) {
+			dialog.ShowError(errors.New(T("%q is not an environment variable name like API_KEY", name)), parent)
+			return
+		}
+		withUnlockedSecrets(parent, func() {
+			ref, err := appSecrets.Put("cherry/"+cherry.Name+"/"+name, valueEntry.Text)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to store the secret: %v", err), parent)
+				return
+			}
+			if appSettings.CherrySecrets == nil {
+				appSettings.CherrySecrets = map[string]map[string]SecretRef{}
+			}
+			if appSettings.CherrySecrets[cherry.Name] == nil {
+				appSettings.CherrySecrets[cherry.Name] = map[string]SecretRef{}
+			}
+			appSettings.CherrySecrets[cherry.Name][name] = ref
+			if err := saveSettings(); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to save settings: %v", err), parent)
+			}
+			nameEntry.SetText("")
+			valueEntry.SetText("")
+			refreshList()
+		})
+	})
+
+	hint := widget.NewLabel(T("%s gets these as environment variables when it runs.", cherry.Name))
+	hint.Wrapping = fyne.TextWrapWord
+	content := container.NewVBox(
+		hint,
+		list,
+		widget.NewSeparator(),
+		container.NewGridWithColumns(2, nameEntry, valueEntry),
+		addButton,
+	)
+	secretsDialog := dialog.NewCustom(T("🔑 Secrets of %s", cherry.Name), T("Close"), content, parent)
+	secretsDialog.Resize(fyne.NewSize(500, 400))
+	secretsDialog.Show()
 }
 
 func showCreateAppDialog(parent fyne.Window, cherryManager *CherryManager, refreshList func(), updateStats func()) {
 	// Create new app dialog
 	nameEntry := widget.NewEntry()
-	nameEntry.SetPlaceHolder("App name")
+	nameEntry.SetPlaceHolder(T("App name"))
 
 	descEntry := widget.NewEntry()
-	descEntry.SetPlaceHolder("App description")
+	descEntry.SetPlaceHolder(T("App description"))
 
 	appTypeSelect := widget.NewSelect([]string{"desktop", "web"}, nil)
 	appTypeSelect.SetSelected("desktop")
@@ -567,23 +1023,23 @@ func showCreateAppDialog(parent fyne.Window, cherryManager *CherryManager, refre
 	stackSelect.SetSelected("static-html")
 
 	content := container.NewVBox(
-		widget.NewLabel("🚀 Create New Application"),
+		widget.NewLabel(T("🚀 Create New Application")),
 		widget.NewSeparator(),
 		nameEntry,
 		descEntry,
 		container.NewHBox(
-			widget.NewLabel("App Type:"),
+			widget.NewLabel(T("App Type:")),
 			appTypeSelect,
 		),
 		container.NewHBox(
-			widget.NewLabel("Stack:"),
+			widget.NewLabel(T("Stack:")),
 			stackSelect,
 		),
 		widget.NewSeparator(),
-		widget.NewLabel("This will scaffold a new project using TinyApp Factory CLI"),
+		widget.NewLabel(T("This will scaffold a new project using TinyApp Factory CLI")),
 	)
 
-	dialog.ShowCustomConfirm("Create App", "Create", "Cancel", content, func(confirmed bool) {
+	dialog.ShowCustomConfirm(T("Create App"), T("Create"), T("Cancel"), content, func(confirmed bool) {
 		if confirmed {
 			name := nameEntry.Text
 			desc := descEntry.Text
@@ -594,7 +1050,7 @@ func showCreateAppDialog(parent fyne.Window, cherryManager *CherryManager, refre
 				cherryManager.AddCherry(name, desc, appType, stack)
 				refreshList()
 				updateStats()
-				dialog.ShowInformation("App Created", fmt.Sprintf("App '%s' has been created using %s stack!", name, stack), parent)
+				dialog.ShowInformation(T("App Created"), T("App '%s' has been created using %s stack!", name, stack), parent)
 			}
 		}
 	}, parent)
@@ -602,7 +1058,7 @@ func showCreateAppDialog(parent fyne.Window, cherryManager *CherryManager, refre
 
 func showTemplatesDialog(parent fyne.Window, cherryManager *CherryManager, refreshList func(), updateStats func()) {
 	// Create templates dialog
-	templatesLabel := widget.NewLabel("📋 Available Templates")
+	templatesLabel := widget.NewLabel(T("📋 Available Templates"))
 	templatesLabel.TextStyle.Bold = true
 
 	templates := []struct {
@@ -624,7 +1080,7 @@ func showTemplatesDialog(parent fyne.Window, cherryManager *CherryManager, refre
 			cherryManager.AddCherry(template.name, template.description, template.type_, template.stack)
 			refreshList()
 			updateStats()
-			dialog.ShowInformation("Template Added", fmt.Sprintf("Template '%s' added to your projects!", template.name), parent)
+			dialog.ShowInformation(T("Template Added"), T("Template '%s' added to your projects!", template.name), parent)
 		})
 		templateButtons = append(templateButtons, btn)
 	}
@@ -632,35 +1088,35 @@ func showTemplatesDialog(parent fyne.Window, cherryManager *CherryManager, refre
 	content := container.NewVBox(
 		templatesLabel,
 		widget.NewSeparator(),
-		widget.NewLabel("Choose a template to add to your projects:"),
+		widget.NewLabel(T("Choose a template to add to your projects:")),
 		widget.NewSeparator(),
 		container.NewVBox(templateButtons...),
 	)
 
-	dialog.ShowCustom("Browse Templates", "Close", content, parent)
+	dialog.ShowCustom(T("Browse Templates"), T("Close"), content, parent)
 }
 
 func showCompileDialog(parent fyne.Window, cherryManager *CherryManager, refreshList func(), updateStats func()) {
 	// Create compile dialog
-	compileLabel := widget.NewLabel("⚡ AI-Powered Compile")
+	compileLabel := widget.NewLabel(T("⚡ AI-Powered Compile"))
 	compileLabel.TextStyle.Bold = true
 
 	cherries := cherryManager.GetCherries()
 	if len(cherries) == 0 {
-		dialog.ShowInformation("No Projects", "You don't have any projects to compile yet. Create an app first!", parent)
+		dialog.ShowInformation(T("No Projects"), T("You don't have any projects to compile yet. Create an app first!"), parent)
 		return
 	}
 
 	// Status label for compilation progress
-	statusLabel := widget.NewLabel("Ready to compile with AI")
+	statusLabel := widget.NewLabel(T("Ready to compile with AI"))
 	statusLabel.Alignment = fyne.TextAlignCenter
 
 	var compileButtons []fyne.CanvasObject
 	for _, cherry := range cherries {
 		cherry := cherry // capture loop variable
-		btn := widget.NewButton(fmt.Sprintf("🤖 AI Compile %s (%s)", cherry.Name, cherry.Stack), func() {
+		btn := widget.NewButton(T("🤖 AI Compile %s (%s)", cherry.Name, cherry.Stack), func() {
 			// Update status
-			statusLabel.SetText(fmt.Sprintf("🤖 AI is building %s...", cherry.Name))
+			statusLabel.SetText(T("🤖 AI is building %s...", cherry.Name))
 			statusLabel.Refresh()
 			
 			// Compile with AI in background
@@ -668,13 +1124,13 @@ func showCompileDialog(parent fyne.Window, cherryManager *CherryManager, refresh
 				err := compileWithAI(cherry, parent)
 				
 				if err != nil {
-					statusLabel.SetText(fmt.Sprintf("❌ Error compiling %s", cherry.Name))
+					statusLabel.SetText(T("❌ Error compiling %s", cherry.Name))
 					dialog.ShowError(fmt.Errorf("Failed to compile %s: %v", cherry.Name, err), parent)
 					return
 				}
 				
-				statusLabel.SetText(fmt.Sprintf("✅ Successfully compiled %s!", cherry.Name))
-				dialog.ShowInformation("Compilation Complete", fmt.Sprintf("🎉 %s has been compiled successfully!\n\nExecutable saved to outputs/", cherry.Name), parent)
+				statusLabel.SetText(T("✅ Successfully compiled %s!", cherry.Name))
+				dialog.ShowInformation(T("Compilation Complete"), T("🎉 %s has been compiled successfully!\n\nExecutable saved to outputs/", cherry.Name), parent)
 			}()
 		})
 		compileButtons = append(compileButtons, btn)
@@ -683,78 +1139,78 @@ func showCompileDialog(parent fyne.Window, cherryManager *CherryManager, refresh
 	content := container.NewVBox(
 		compileLabel,
 		widget.NewSeparator(),
-		widget.NewLabel("🤖 AI-Powered Compilation:"),
-		widget.NewLabel("DeepSeek AI will generate bug-free code and compile it automatically"),
+		widget.NewLabel(T("🤖 AI-Powered Compilation:")),
+		widget.NewLabel(T("DeepSeek AI will generate bug-free code and compile it automatically")),
 		widget.NewSeparator(),
 		container.NewVBox(compileButtons...),
 		widget.NewSeparator(),
 		statusLabel,
 		widget.NewSeparator(),
-		widget.NewLabel("✨ Features:"),
-		widget.NewLabel("• AI generates optimized, bug-free code"),
-		widget.NewLabel("• Automatic compilation and testing"),
-		widget.NewLabel("• Cross-platform executables"),
-		widget.NewLabel("• Built-in error detection and fixes"),
+		widget.NewLabel(T("✨ Features:")),
+		widget.NewLabel(T("• AI generates optimized, bug-free code")),
+		widget.NewLabel(T("• Automatic compilation and testing")),
+		widget.NewLabel(T("• Cross-platform executables")),
+		widget.NewLabel(T("• Built-in error detection and fixes")),
 	)
 
-	dialog.ShowCustom("AI Compile Apps", "Close", content, parent)
+	dialog.ShowCustom(T("AI Compile Apps"), T("Close"), content, parent)
 }
 
 func showMoreActionsMenu(parent fyne.Window, cherryManager *CherryManager, refreshList func(), updateStats func()) {
 	// Create more actions menu
-	menuLabel := widget.NewLabel("More Actions")
+	menuLabel := widget.NewLabel(T("More Actions"))
 	menuLabel.TextStyle.Bold = true
 
 	// Browse Templates button
-	browseTemplatesButton := widget.NewButton("📋 Browse Templates", func() {
+	browseTemplatesButton := widget.NewButton(T("📋 Browse Templates"), func() {
 		showTemplatesDialog(parent, cherryManager, refreshList, updateStats)
 	})
 
 	// Compile Apps button
-	compileAppsButton := widget.NewButton("⚡ Compile Apps", func() {
+	compileAppsButton := widget.NewButton(T("⚡ Compile Apps"), func() {
 		showCompileDialog(parent, cherryManager, refreshList, updateStats)
 	})
 
 	// Import Project button
-	importProjectButton := widget.NewButton("📁 Import Project", func() {
+	importProjectButton := widget.NewButton(T("📁 Import Project"), func() {
 		showImportDialog(parent, cherryManager, refreshList, updateStats)
 	})
 
 	// Export Projects button
-	exportProjectsButton := widget.NewButton("📤 Export Projects", func() {
+	exportProjectsButton := widget.NewButton(T("📤 Export Projects"), func() {
 		showExportDialog(parent, cherryManager, refreshList, updateStats)
 	})
 
 	content := container.NewVBox(
 		menuLabel,
 		widget.NewSeparator(),
-		widget.NewLabel("Additional Tools:"),
+		widget.NewLabel(T("Additional Tools:")),
 		browseTemplatesButton,
 		compileAppsButton,
 		widget.NewSeparator(),
-		widget.NewLabel("Project Management:"),
+		widget.NewLabel(T("Project Management:")),
 		importProjectButton,
 		exportProjectsButton,
 	)
 
-	dialog.ShowCustom("More Actions", "Close", content, parent)
+	dialog.ShowCustom(T("More Actions"), T("Close"), content, parent)
 }
 
 func showImportDialog(parent fyne.Window, cherryManager *CherryManager, refreshList func(), updateStats func()) {
-	dialog.ShowInformation("Import Project", "This feature will allow you to import existing projects from local files or URLs.", parent)
+	dialog.ShowInformation(T("Import Project"), T("This feature will allow you to import existing projects from local files or URLs."), parent)
 }
 
 func showExportDialog(parent fyne.Window, cherryManager *CherryManager, refreshList func(), updateStats func()) {
-	dialog.ShowInformation("Export Projects", "This feature will allow you to export your projects for backup or sharing.", parent)
+	dialog.ShowInformation(T("Export Projects"), T("This feature will allow you to export your projects for backup or sharing."), parent)
 }
 
 func showAIBuilderDialog(parent fyne.Window, cherryManager *CherryManager, refreshList func(), updateStats func()) {
 	// Create AI Builder dialog
-	aiLabel := widget.NewLabel("🤖 AI Cherry Builder")
+	aiLabel := widget.NewLabel(T("🤖 AI Cherry Builder"))
 	aiLabel.TextStyle.Bold = true
 
 	descEntry := widget.NewMultiLineEntry()
-	descEntry.SetPlaceHolder("Describe what you want your app to do...\n\nExample: 'A simple todo app with categories and due dates'")
+	descEntry.SetPlaceHolder(T("Describe what you want your app to do...\n\nExample: 'A simple todo app with categories and due dates'"))
 
 	categorySelect := widget.NewSelect([]string{"productivity", "creative", "civic", "business", "personal"}, nil)
 	categorySelect.SetSelected("productivity")
@@ -763,35 +1219,35 @@ func showAIBuilderDialog(parent fyne.Window, cherryManager *CherryManager, refre
 	stackSelect.SetSelected("static-html")
 
 	// Options
-	includeDatabase := widget.NewCheck("Include Fireproof Database", nil)
+	includeDatabase := widget.NewCheck(T("Include Fireproof Database"), nil)
 	includeDatabase.SetChecked(true)
 	
-	includeSync := widget.NewCheck("Include Sync Features", nil)
+	includeSync := widget.NewCheck(T("Include Sync Features"), nil)
 	includeSync.SetChecked(false)
 	
-	includeAuth := widget.NewCheck("Include Authentication", nil)
+	includeAuth := widget.NewCheck(T("Include Authentication"), nil)
 	includeAuth.SetChecked(false)
 
 	// Status label
-	statusLabel := widget.NewLabel("Ready to generate")
+	statusLabel := widget.NewLabel(T("Ready to generate"))
 	statusLabel.Alignment = fyne.TextAlignCenter
 
 	content := container.NewVBox(
 		aiLabel,
 		widget.NewSeparator(),
-		widget.NewLabel("Describe what you want your app to do:"),
+		widget.NewLabel(T("Describe what you want your app to do:")),
 		descEntry,
 		widget.NewSeparator(),
 		container.NewHBox(
-			widget.NewLabel("Category:"),
+			widget.NewLabel(T("Category:")),
 			categorySelect,
 		),
 		container.NewHBox(
-			widget.NewLabel("Stack:"),
+			widget.NewLabel(T("Stack:")),
 			stackSelect,
 		),
 		widget.NewSeparator(),
-		widget.NewLabel("Features:"),
+		widget.NewLabel(T("Features:")),
 		includeDatabase,
 		includeSync,
 		includeAuth,
@@ -799,16 +1255,16 @@ func showAIBuilderDialog(parent fyne.Window, cherryManager *CherryManager, refre
 		statusLabel,
 	)
 
-	dialog.ShowCustomConfirm("AI Builder", "Generate", "Cancel", content, func(confirmed bool) {
+	dialog.ShowCustomConfirm(T("AI Builder"), T("Generate"), T("Cancel"), content, func(confirmed bool) {
 		if confirmed {
 			description := descEntry.Text
 			if description == "" {
-				dialog.ShowInformation("Error", "Please describe what you want your app to do", parent)
+				dialog.ShowInformation(T("Error"), T("Please describe what you want your app to do"), parent)
 				return
 			}
 
 			// Update status
-			statusLabel.SetText("Generating with AI...")
+			statusLabel.SetText(T("Generating with AI..."))
 			statusLabel.Refresh()
 
 			// Call AI API
@@ -816,7 +1272,7 @@ func showAIBuilderDialog(parent fyne.Window, cherryManager *CherryManager, refre
 				cherrySpec, err := callAIGenerateCherry(description, categorySelect.Selected, stackSelect.Selected, includeDatabase.Checked, includeSync.Checked, includeAuth.Checked)
 				
 				if err != nil {
-					statusLabel.SetText("Error: " + err.Error())
+					statusLabel.SetText(T("Error: %v", err))
 					dialog.ShowError(err, parent)
 					return
 				}
@@ -826,8 +1282,8 @@ func showAIBuilderDialog(parent fyne.Window, cherryManager *CherryManager, refre
 				refreshList()
 				updateStats()
 				
-				statusLabel.SetText("Generated successfully!")
-				dialog.ShowInformation("AI Generated", fmt.Sprintf("App '%s' has been generated using AI!", cherrySpec.Name), parent)
+				statusLabel.SetText(T("Generated successfully!"))
+				dialog.ShowInformation(T("AI Generated"), T("App '%s' has been generated using AI!", cherrySpec.Name), parent)
 			}()
 		}
 	}, parent)
@@ -845,18 +1301,36 @@ type CherrySpec struct {
 type Settings struct {
 	AutoUpdate    bool   `json:"autoUpdate"`
 	StoragePath   string `json:"storagePath"`
-	AIAPIKey      string `json:"aiApiKey"`
+	AIAPIKey      SecretRef `json:"aiApiKey"` // in appSecrets; older files have the key itself
+	SecretsAsked  bool   `json:"secretsAsked,omitempty"` // whether the user was asked once to move such a key
+	Theme         string `json:"theme"`     // a built-in theme or a file in getThemesDir
+	ThemeMode     string `json:"themeMode"` // "system", "light" or "dark"
+
+	// The secrets each cherry gets as environment variables when it runs,
+	// by cherry name and variable, e.g. OPENAI_API_KEY
+	CherrySecrets map[string]map[string]SecretRef `json:"cherrySecrets,omitempty"`
 }
 
 // Global settings
 var appSettings Settings
 
+// appTheme draws the manager, as the settings say
+var appTheme = NewFileCherryTheme()
+
+// appSecrets holds the secrets the settings refer to, see SecretStore
+var appSecrets *SecretStore
+
+// aiKeySecret names the AI API key in appSecrets
+const aiKeySecret = "ai-api-key"
+
 func init() {
 	// Initialize default settings
 	appSettings = Settings{
 		AutoUpdate:  true,
 		StoragePath: "~/FileCherry",
 		AIAPIKey:    "",
+		Theme:       themeCherry,
+		ThemeMode:   "system",
 	}
 	loadSettings()
 }
@@ -866,6 +1340,16 @@ func getSettingsPath() string {
 	return filepath.Join(homeDir, ".filecherry", "settings.json")
 }
 
+// getThemesDir is where the user's theme files go, see ThemeFile
+func getThemesDir() string {
+	return filepath.Join(filepath.Dir(getSettingsPath()), "themes")
+}
+
+// getSecretsPath is the encrypted secrets file, used without a keyring
+func getSecretsPath() string {
+	return filepath.Join(filepath.Dir(getSettingsPath()), "secrets.json")
+}
+
 func loadSettings() {
 	settingsPath := getSettingsPath()
 	
@@ -885,6 +1369,8 @@ func loadSettings() {
 			AutoUpdate:  true,
 			StoragePath: "~/FileCherry",
 			AIAPIKey:    "",
+			Theme:       themeCherry,
+			ThemeMode:   "system",
 		}
 	}
 }
@@ -900,7 +1386,11 @@ func saveSettings() error {
 		return err
 	}
 	
-	return os.WriteFile(settingsPath, data, 0644)
+	// Only the user may read it, also if an earlier version created it
+	if err := os.WriteFile(settingsPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(settingsPath, 0600)
 }
 
 func callAIGenerateCherry(description, category, stack string, includeDatabase, includeSync, includeAuth bool) (*CherrySpec, error) {
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// stopTimeout is how long a cherry gets to quit before it is killed.
+const stopTimeout = 5 * time.Second
+
+// CherryRunner starts compiled cherries as child processes and keeps track
+// of them, so the manager can supervise them from the tray while its
+// window is hidden.
+type CherryRunner struct {
+	mu       sync.Mutex
+	running  map[string]*runningCherry // by cherry ID
+	onChange func()
+
+	// Env, if set, returns environment variables a cherry gets on top of
+	// the manager's, e.g. its secrets
+	Env func(cherry Cherry) ([]string, error)
+}
+
+type runningCherry struct {
+	cmd  *exec.Cmd
+	done chan struct{} // closed when the process exits
+}
+
+// NewCherryRunner returns a runner that calls onChange whenever a cherry
+// starts or exits.
+func NewCherryRunner(onChange func()) *CherryRunner {
+	return &CherryRunner{running: map[string]*runningCherry{}, onChange: onChange}
+}
+
+// cherryExecutable finds the executable of a compiled cherry: its path, or
+// a binary named after the project inside it.
+func cherryExecutable(cherry Cherry) (string, error) {
+	if cherry.Path == "" {
+		return "", fmt.Errorf("%s has not been compiled yet", cherry.Name)
+	}
+	name := filepath.Base(cherry.Path)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	for _, path := range []string{cherry.Path, filepath.Join(cherry.Path, name)} {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no executable for %s in %s", cherry.Name, cherry.Path)
+}
+
+// Start runs cherry, unless it is running already.
+func (r *CherryRunner) Start(cherry Cherry) error {
+	exe, err := cherryExecutable(cherry)
+	if err != nil {
+		return err
+	}
+	var env []string
+	if r.Env != nil {
+		if env, err = r.Env(cherry); err != nil {
+			return err
+		}
+	}
+	r.mu.Lock()
+	if _, ok := r.running[cherry.ID]; ok {
+		r.mu.Unlock()
+		return nil
+	}
+	cmd := exec.Command(exe)
+	cmd.Dir = filepath.Dir(exe)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	if err := cmd.Start(); err != nil {
+		r.mu.Unlock()
+		return fmt.Errorf("starting %s: %v", cherry.Name, err)
+	}
+	rc := &runningCherry{cmd: cmd, done: make(chan struct{})}
+	r.running[cherry.ID] = rc
+	r.mu.Unlock()
+
+	go func() {
+		cmd.Wait()
+		r.mu.Lock()
+		delete(r.running, cherry.ID)
+		r.mu.Unlock()
+		close(rc.done)
+		r.changed()
+	}()
+	r.changed()
+	return nil
+}
+
+// Stop asks the cherry to quit, and kills it if it hasn't after
+// stopTimeout. Windows can't deliver the request, so it is killed there
+// right away.
+func (r *CherryRunner) Stop(id string) error {
+	r.mu.Lock()
+	rc, ok := r.running[id]
+	r.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	if runtime.GOOS == "windows" || rc.cmd.Process.Signal(os.Interrupt) != nil {
+		return rc.cmd.Process.Kill()
+	}
+	select {
+	case <-rc.done:
+		return nil
+	case <-time.After(stopTimeout):
+		return rc.cmd.Process.Kill()
+	}
+}
+
+// StopAll stops every running cherry, e.g. when the manager quits.
+func (r *CherryRunner) StopAll() {
+	r.mu.Lock()
+	var ids []string
+	for id := range r.running {
+		ids = append(ids, id)
+	}
+	r.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			r.Stop(id)
+		}(id)
+	}
+	wg.Wait()
+}
+
+// Running reports whether the cherry with the given ID is running.
+func (r *CherryRunner) Running(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.running[id]
+	return ok
+}
+
+// Count returns how many cherries are running.
+func (r *CherryRunner) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.running)
+}
+
+func (r *CherryRunner) changed() {
+	if r.onChange != nil {
+		r.onChange()
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var (
+	// errSecretNotFound is returned for a secret that was never stored,
+	// or has been deleted
+	errSecretNotFound = errors.New("secret not found")
+	// errSecretsLocked is returned by a file store until Unlock
+	errSecretsLocked = errors.New("the secrets file is locked, enter its passphrase first")
+	// errNoKeyring is returned by keyring operations where there is none
+	errNoKeyring = errors.New("no system keyring")
+)
+
+// keyring is the system's secret storage, see systemKeyring. Names are
+// FileCherry's, e.g. "ai-api-key".
+type keyring interface {
+	Get(name string) (string, error) // errSecretNotFound if there is none
+	Set(name, value string) error
+	Delete(name string) error
+}
+
+// SecretRef points at a secret in a SecretStore, e.g.
+// "keyring:ai-api-key". Settings keep these instead of the secrets.
+type SecretRef string
+
+// IsRef reports whether r is a reference rather than a secret itself,
+// e.g. an API key in a settings file from before secrets were stored.
+func (r SecretRef) IsRef() bool {
+	return strings.HasPrefix(string(r), "keyring:") || strings.HasPrefix(string(r), "file:")
+}
+
+// SecretStore keeps secrets, such as the AI API key and the cherries'
+// secrets, out of the settings file: in the system keyring when there is
+// one, else in a file encrypted with AES-GCM under a key derived from the
+// user's passphrase.
+type SecretStore struct {
+	mu      sync.Mutex
+	keyring keyring           // nil without one
+	path    string            // the encrypted file
+	key     []byte            // unlocks the file; nil while it is locked
+	salt    []byte            // of the key
+	rounds  int               // of PBKDF2 for the key
+	secrets map[string]string // the file's secrets, once unlocked
+}
+
+// NewSecretStore returns a store that uses the system keyring, if it
+// answers, and else the encrypted file at path.
+func NewSecretStore(path string) *SecretStore {
+	s := &SecretStore{path: path}
+	if k := systemKeyring(); k != nil {
+		if _, err := k.Get("probe"); err == nil || errors.Is(err, errSecretNotFound) {
+			s.keyring = k
+		}
+	}
+	return s
+}
+
+// UsesKeyring reports whether new secrets go to the system keyring.
+func (s *SecretStore) UsesKeyring() bool {
+	return s.keyring != nil
+}
+
+// Locked reports whether the encrypted file needs its passphrase before
+// secrets can be stored or read from it. It never does with a keyring.
+func (s *SecretStore) Locked() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keyring == nil && s.key == nil
+}
+
+// FileExists reports whether the encrypted file has been created, i.e.
+// whether Unlock checks a passphrase or sets a new one.
+func (s *SecretStore) FileExists() bool {
+	_, err := os.Stat(s.path)
+	return err == nil
+}
+
+// Put stores a secret under name and returns the reference to keep in the
+// settings.
+func (s *SecretStore) Put(name, value string) (SecretRef, error) {
+	if name == "" || strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "", fmt.Errorf("%q is not a valid secret name", name)
+	}
+	if s.keyring != nil {
+		if err := s.keyring.Set(name, value); err != nil {
+			return "", err
+		}
+		return SecretRef("keyring:" + name), nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key == nil {
+		return "", errSecretsLocked
+	}
+	s.secrets[name] = value
+	if err := s.save(); err != nil {
+		delete(s.secrets, name)
+		return "", err
+	}
+	return SecretRef("file:" + name), nil
+}
+
+// Get returns the secret ref points at.
+func (s *SecretStore) Get(ref SecretRef) (string, error) {
+	backend, name, _ := strings.Cut(string(ref), ":")
+	switch backend {
+	case "keyring":
+		if s.keyring == nil {
+			return "", fmt.Errorf("%s: %w", ref, errNoKeyring)
+		}
+		return s.keyring.Get(name)
+	case "file":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.key == nil {
+			return "", errSecretsLocked
+		}
+		value, ok := s.secrets[name]
+		if !ok {
+			return "", fmt.Errorf("%s: %w", ref, errSecretNotFound)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("%q is not a secret reference", ref)
+}
+
+// Delete removes the secret ref points at. Deleting one that is gone is
+// not an error.
+func (s *SecretStore) Delete(ref SecretRef) error {
+	backend, name, _ := strings.Cut(string(ref), ":")
+	switch backend {
+	case "keyring":
+		if s.keyring == nil {
+			return fmt.Errorf("%s: %w", ref, errNoKeyring)
+		}
+		if err := s.keyring.Delete(name); err != nil && !errors.Is(err, errSecretNotFound) {
+			return err
+		}
+		return nil
+	case "file":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.key == nil {
+			return errSecretsLocked
+		}
+		if _, ok := s.secrets[name]; !ok {
+			return nil
+		}
+		value := s.secrets[name]
+		delete(s.secrets, name)
+		if err := s.save(); err != nil {
+			s.secrets[name] = value
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("%q is not a secret reference", ref)
+}
+
+// secretsFile is the encrypted file: the secrets as a JSON object,
+// sealed with AES-256-GCM under a key derived from the passphrase with
+// PBKDF2-HMAC-SHA256.
+type secretsFile struct {
+	Version    int    `json:"version"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// secretsIterations is how many PBKDF2 rounds new files use, as
+// recommended by OWASP for HMAC-SHA256.
+const secretsIterations = 600000
+
+// Unlock opens the encrypted file with passphrase, or creates it with
+// passphrase if there is none yet. A wrong passphrase is an error.
+func (s *SecretStore) Unlock(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("the passphrase is empty")
+	}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.salt, s.rounds, s.secrets = salt, secretsIterations, map[string]string{}
+		s.key = pbkdf2.Key([]byte(passphrase), salt, secretsIterations, 32, sha256.New)
+		return s.save()
+	}
+	if err != nil {
+		return err
+	}
+	var f secretsFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("%s: %v", s.path, err)
+	}
+	if f.Version != 1 || f.Iterations < 1 {
+		return fmt.Errorf("%s: unknown format version %d", s.path, f.Version)
+	}
+	key := pbkdf2.Key([]byte(passphrase), f.Salt, f.Iterations, 32, sha256.New)
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	if len(f.Nonce) != gcm.NonceSize() {
+		return fmt.Errorf("%s: damaged, the nonce is %d bytes instead of %d", s.path, len(f.Nonce), gcm.NonceSize())
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		return errors.New("wrong passphrase")
+	}
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return fmt.Errorf("%s: %v", s.path, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.salt, s.rounds, s.key, s.secrets = f.Salt, f.Iterations, key, secrets
+	return nil
+}
+
+// save seals the secrets with a fresh nonce and replaces the file, which
+// only the user can read. The caller holds s.mu.
+func (s *SecretStore) save() error {
+	plain, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(secretsFile{
+		Version:    1,
+		Iterations: s.rounds,
+		Salt:       s.salt,
+		Nonce:      nonce,
+		Data:       gcm.Seal(nil, nonce, plain, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSecretsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	s := &SecretStore{path: path}
+	if _, err := s.Put("ai-api-key", "sk-1"); !errors.Is(err, errSecretsLocked) {
+		t.Errorf("Put before Unlock: %v", err)
+	}
+	if err := s.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	ref, err := s.Put("ai-api-key", "sk-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The secret survives a restart
+	s = &SecretStore{path: path}
+	if err := s.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := s.Get(ref); err != nil || value != "sk-1" {
+		t.Errorf("Get(%s) = %q, %v", ref, value, err)
+	}
+
+	if err := (&SecretStore{path: path}).Unlock("wrong"); err == nil {
+		t.Error("Unlock accepted a wrong passphrase")
+	}
+
+	// A damaged file is an error, not a crash
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f secretsFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	damaged := map[string]func(f *secretsFile){
+		"short nonce": func(f *secretsFile) { f.Nonce = f.Nonce[:4] },
+		"no nonce":    func(f *secretsFile) { f.Nonce = nil },
+		"data":        func(f *secretsFile) { f.Data[0] ^= 1 },
+		"version":     func(f *secretsFile) { f.Version = 2 },
+	}
+	for name, damage := range damaged {
+		g := f
+		g.Nonce = append([]byte(nil), f.Nonce...)
+		g.Data = append([]byte(nil), f.Data...)
+		damage(&g)
+		data, _ := json.Marshal(g)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := (&SecretStore{path: path}).Unlock("correct horse"); err == nil {
+			t.Errorf("Unlock accepted a file with damaged %s", name)
+		}
+	}
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&SecretStore{path: path}).Unlock("correct horse"); err == nil {
+		t.Error("Unlock accepted a file that isn't JSON")
+	}
+}
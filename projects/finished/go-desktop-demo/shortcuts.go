@@ -0,0 +1,615 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Action is something the window can do from the keyboard: it has a
+// shortcut, is listed in the command palette and can back a menu item.
+type Action struct {
+	ID       string                  // names it in the bindings file, e.g. "task.new"
+	Name     string                  // e.g. "🍒 New Task"
+	Shortcut *desktop.CustomShortcut // the default binding, or nil for none
+	Run      func()
+}
+
+// IDs of the actions every registry has
+const (
+	actionPalette   = "palette"
+	actionShortcuts = "shortcuts"
+)
+
+// Shortcuts is the registry of a window's actions and the shortcuts bound
+// to them. The actions bring their default bindings; the ones the user
+// changed are saved to a JSON file, so new defaults still reach everyone
+// else.
+type Shortcuts struct {
+	path     string
+	actions  []*Action
+	bindings map[string]*desktop.CustomShortcut // by action ID, nil when unbound
+	window   fyne.Window
+	items    map[string][]*fyne.MenuItem
+}
+
+// shortcutsFile is the bindings file: the shortcuts that differ from the
+// defaults, with "" for an action the user unbound.
+type shortcutsFile struct {
+	Bindings map[string]string `json:"bindings"`
+}
+
+// ctrl returns the shortcut of key with Ctrl, or Cmd on macOS, and mods.
+func ctrl(key fyne.KeyName, mods ...fyne.KeyModifier) *desktop.CustomShortcut {
+	s := &desktop.CustomShortcut{KeyName: key, Modifier: fyne.KeyModifierShortcutDefault}
+	for _, m := range mods {
+		s.Modifier |= m
+	}
+	return s
+}
+
+// NewShortcuts registers actions, plus the command palette (Ctrl+K) and
+// the bindings editor, and loads the bindings saved at path. An empty
+// path keeps the bindings in memory.
+func NewShortcuts(path string, actions []*Action) (*Shortcuts, error) {
+	s := &Shortcuts{path: path, bindings: map[string]*desktop.CustomShortcut{}, items: map[string][]*fyne.MenuItem{}}
+	s.actions = append(slices.Clip(actions),
+		&Action{ID: actionPalette, Name: T("🔎 Command Palette"), Shortcut: ctrl(fyne.KeyK), Run: s.ShowPalette},
+		&Action{ID: actionShortcuts, Name: T("⌨️ Keyboard Shortcuts..."), Run: s.ShowBindings},
+	)
+	for _, a := range s.actions {
+		s.bindings[a.ID] = a.Shortcut
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file shortcutsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		log.Printf("could not read %s, using the default shortcuts: %v", path, err)
+		return s, nil
+	}
+	for _, a := range s.actions {
+		text, ok := file.Bindings[a.ID]
+		if !ok {
+			continue
+		}
+		var sc *desktop.CustomShortcut
+		if text != "" {
+			if sc, err = ParseShortcut(text); err != nil {
+				log.Printf("%s: %s: %v", path, a.ID, err)
+				continue
+			}
+		}
+		s.bindings[a.ID] = sc
+	}
+	// Check once all are loaded, so swapped shortcuts don't clash
+	for _, a := range s.actions {
+		if err := s.check(a.ID, s.bindings[a.ID]); err != nil {
+			log.Printf("%s: %s: %v", path, a.ID, err)
+			s.bindings[a.ID] = nil
+		}
+	}
+	return s, nil
+}
+
+// Actions returns the registered actions, in the order they were added.
+func (s *Shortcuts) Actions() []*Action {
+	return s.actions
+}
+
+func (s *Shortcuts) action(id string) *Action {
+	for _, a := range s.actions {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+// Shortcut returns the shortcut bound to the action id, or nil.
+func (s *Shortcuts) Shortcut(id string) *desktop.CustomShortcut {
+	return s.bindings[id]
+}
+
+// Label returns the shortcut of the action id as text, e.g. "Ctrl+K", or
+// "" when it has none.
+func (s *Shortcuts) Label(id string) string {
+	if sc := s.bindings[id]; sc != nil {
+		return FormatShortcut(sc)
+	}
+	return ""
+}
+
+// Run runs the action id, if there is one.
+func (s *Shortcuts) Run(id string) {
+	if a := s.action(id); a != nil {
+		a.Run()
+	}
+}
+
+// Attach adds the shortcuts to window's canvas. Bindings changed later
+// follow, in the canvas and in the menu items.
+func (s *Shortcuts) Attach(window fyne.Window) {
+	s.window = window
+	for _, a := range s.actions {
+		s.register(a, nil)
+	}
+}
+
+// register swaps the old shortcut of a for its current one in the canvas.
+func (s *Shortcuts) register(a *Action, old *desktop.CustomShortcut) {
+	if s.window == nil {
+		return
+	}
+	c := s.window.Canvas()
+	if old != nil {
+		c.RemoveShortcut(old)
+	}
+	if sc := s.bindings[a.ID]; sc != nil {
+		run := a.Run
+		c.AddShortcut(sc, func(fyne.Shortcut) { run() })
+	}
+}
+
+// MenuItem returns a menu item that runs the action id and shows its
+// shortcut.
+func (s *Shortcuts) MenuItem(id string) *fyne.MenuItem {
+	a := s.action(id)
+	item := fyne.NewMenuItem(a.Name, a.Run)
+	if sc := s.bindings[id]; sc != nil {
+		item.Shortcut = sc
+	}
+	s.items[id] = append(s.items[id], item)
+	return item
+}
+
+// check reports why sc can't be bound to the action id.
+func (s *Shortcuts) check(id string, sc *desktop.CustomShortcut) error {
+	if s.action(id) == nil {
+		return fmt.Errorf("no action %q", id)
+	}
+	if sc == nil {
+		return nil
+	}
+	if sc.Modifier == fyne.KeyModifierShortcutDefault && slices.Contains([]fyne.KeyName{fyne.KeyA, fyne.KeyC, fyne.KeyV, fyne.KeyX}, sc.KeyName) {
+		return errors.New(T("%s is kept for copy and paste", FormatShortcut(sc)))
+	}
+	for _, a := range s.actions {
+		if b := s.bindings[a.ID]; a.ID != id && b != nil && sameShortcut(b, sc) {
+			return errors.New(T("%s is already the shortcut of %s", FormatShortcut(sc), a.Name))
+		}
+	}
+	return nil
+}
+
+// Bind binds sc to the action id, or unbinds it if sc is nil, and saves
+// the bindings.
+func (s *Shortcuts) Bind(id string, sc *desktop.CustomShortcut) error {
+	if err := s.check(id, sc); err != nil {
+		return err
+	}
+	old := s.bindings[id]
+	s.bindings[id] = sc
+	s.rebound(s.action(id), old)
+	return s.save()
+}
+
+// Reset puts back the default bindings.
+func (s *Shortcuts) Reset() error {
+	for _, a := range s.actions {
+		old := s.bindings[a.ID]
+		s.bindings[a.ID] = nil
+		s.register(a, old)
+	}
+	for _, a := range s.actions {
+		s.bindings[a.ID] = a.Shortcut
+		s.rebound(a, nil)
+	}
+	return s.save()
+}
+
+// rebound updates the canvas and the menu items after a's binding changed.
+func (s *Shortcuts) rebound(a *Action, old *desktop.CustomShortcut) {
+	s.register(a, old)
+	for _, item := range s.items[a.ID] {
+		item.Shortcut = nil
+		if sc := s.bindings[a.ID]; sc != nil {
+			item.Shortcut = sc
+		}
+	}
+	if s.window != nil && s.window.MainMenu() != nil {
+		s.window.MainMenu().Refresh()
+	}
+}
+
+func (s *Shortcuts) save() error {
+	if s.path == "" {
+		return nil
+	}
+	file := shortcutsFile{Bindings: map[string]string{}}
+	for _, a := range s.actions {
+		sc := s.bindings[a.ID]
+		switch {
+		case sc == nil && a.Shortcut == nil, sc != nil && a.Shortcut != nil && sameShortcut(sc, a.Shortcut):
+			continue
+		case sc == nil:
+			file.Bindings[a.ID] = ""
+		default:
+			file.Bindings[a.ID] = FormatShortcut(sc)
+		}
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func sameShortcut(a, b *desktop.CustomShortcut) bool {
+	return a.KeyName == b.KeyName && a.Modifier == b.Modifier
+}
+
+// Search returns the actions whose names fuzzily match query, best match
+// first: the letters of query must appear in order, and matches at the
+// start of words and runs of letters rank higher. An empty query matches
+// every action.
+func (s *Shortcuts) Search(query string) []*Action {
+	type match struct {
+		action *Action
+		score  int
+	}
+	var matches []match
+	for _, a := range s.actions {
+		if score, ok := fuzzyMatch(query, a.Name); ok {
+			matches = append(matches, match{a, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	actions := make([]*Action, len(matches))
+	for i, m := range matches {
+		actions[i] = m.action
+	}
+	return actions
+}
+
+// fuzzyMatch scores how well query matches text, ignoring case and the
+// spaces in query.
+func fuzzyMatch(query, text string) (int, bool) {
+	q := []rune(strings.ToLower(strings.Join(strings.Fields(query), "")))
+	t := []rune(strings.ToLower(text))
+	score, qi, last := 0, 0, -2
+	for ti, r := range t {
+		if qi == len(q) {
+			break
+		}
+		if r != q[qi] {
+			continue
+		}
+		score++
+		if ti == 0 || !unicode.IsLetter(t[ti-1]) && !unicode.IsDigit(t[ti-1]) {
+			score += 5 // start of a word
+		}
+		if ti == last+1 {
+			score += 3 // right after the previous letter
+		}
+		last = ti
+		qi++
+	}
+	return score, qi == len(q)
+}
+
+// Modifier names, in the order FormatShortcut writes them. ParseShortcut
+// also accepts the aliases.
+var shortcutModifiers = []struct {
+	mod     fyne.KeyModifier
+	name    string
+	aliases []string // lower case
+}{
+	{fyne.KeyModifierControl, "Ctrl", []string{"control"}},
+	{fyne.KeyModifierAlt, "Alt", []string{"option", "opt"}},
+	{fyne.KeyModifierShift, "Shift", nil},
+	{fyne.KeyModifierSuper, "Super", []string{"cmd", "command", "meta", "win"}},
+}
+
+// shortcutKeys are the keys a shortcut can use, besides letters, digits
+// and F1 to F12, by lower case name.
+var shortcutKeys = map[string]fyne.KeyName{
+	"escape": fyne.KeyEscape, "esc": fyne.KeyEscape,
+	"return": fyne.KeyReturn, "enter": fyne.KeyReturn,
+	"tab": fyne.KeyTab, "backspace": fyne.KeyBackspace, "space": fyne.KeySpace,
+	"insert": fyne.KeyInsert, "delete": fyne.KeyDelete, "del": fyne.KeyDelete,
+	"up": fyne.KeyUp, "down": fyne.KeyDown, "left": fyne.KeyLeft, "right": fyne.KeyRight,
+	"home": fyne.KeyHome, "end": fyne.KeyEnd, "pageup": fyne.KeyPageUp, "pagedown": fyne.KeyPageDown,
+	"'": fyne.KeyApostrophe, ",": fyne.KeyComma, "-": fyne.KeyMinus, ".": fyne.KeyPeriod,
+	"/": fyne.KeySlash, "\\": fyne.KeyBackslash, "[": fyne.KeyLeftBracket, "]": fyne.KeyRightBracket,
+	";": fyne.KeySemicolon, "=": fyne.KeyEqual, "*": fyne.KeyAsterisk, "+": fyne.KeyPlus, "`": fyne.KeyBackTick,
+}
+
+// shortcutKeyLabels are the names FormatShortcut writes for the keys whose
+// Fyne names aren't the usual ones.
+var shortcutKeyLabels = map[fyne.KeyName]string{
+	fyne.KeyBackspace: "Backspace",
+	fyne.KeyPageUp:    "PageUp",
+	fyne.KeyPageDown:  "PageDown",
+}
+
+// ParseShortcut parses a shortcut like "Ctrl+Shift+N" or "Alt+F4". A
+// shortcut needs Ctrl, Alt or Super (Cmd): Fyne sends keys with no
+// modifier, or only Shift, to the focused widget as typing.
+func ParseShortcut(text string) (*desktop.CustomShortcut, error) {
+	text = strings.TrimSpace(text)
+	var parts []string
+	if strings.HasSuffix(text, "++") {
+		parts = append(strings.Split(text[:len(text)-2], "+"), "+")
+	} else {
+		parts = strings.Split(text, "+")
+	}
+
+	sc := &desktop.CustomShortcut{}
+	for _, part := range parts[:len(parts)-1] {
+		name, known := strings.ToLower(strings.TrimSpace(part)), false
+		for _, m := range shortcutModifiers {
+			if strings.ToLower(m.name) == name || slices.Contains(m.aliases, name) {
+				sc.Modifier |= m.mod
+				known = true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("shortcut %q: unknown modifier %q", text, part)
+		}
+	}
+
+	key := strings.TrimSpace(parts[len(parts)-1])
+	switch lower := strings.ToLower(key); {
+	case len(key) == 1 && (key[0] >= 'a' && key[0] <= 'z' || key[0] >= 'A' && key[0] <= 'Z' || key[0] >= '0' && key[0] <= '9'):
+		sc.KeyName = fyne.KeyName(strings.ToUpper(key))
+	case len(lower) >= 2 && lower[0] == 'f' && slices.Contains(strings.Fields("1 2 3 4 5 6 7 8 9 10 11 12"), lower[1:]):
+		sc.KeyName = fyne.KeyName(strings.ToUpper(key))
+	case shortcutKeys[lower] != "":
+		sc.KeyName = shortcutKeys[lower]
+	case key == "":
+		return nil, fmt.Errorf("shortcut %q has no key", text)
+	default:
+		return nil, fmt.Errorf("shortcut %q: unknown key %q", text, key)
+	}
+	if sc.Modifier&^fyne.KeyModifierShift == 0 {
+		return nil, fmt.Errorf("shortcut %q needs Ctrl, Alt or %s", text, modifierName(fyne.KeyModifierSuper))
+	}
+	return sc, nil
+}
+
+// FormatShortcut writes sc the way ParseShortcut reads it, with the macOS
+// names of the modifiers on macOS.
+func FormatShortcut(sc *desktop.CustomShortcut) string {
+	var parts []string
+	for _, m := range shortcutModifiers {
+		if sc.Modifier&m.mod != 0 {
+			parts = append(parts, modifierName(m.mod))
+		}
+	}
+	key, ok := shortcutKeyLabels[sc.KeyName]
+	if !ok {
+		key = string(sc.KeyName)
+	}
+	return strings.Join(append(parts, key), "+")
+}
+
+func modifierName(mod fyne.KeyModifier) string {
+	if runtime.GOOS == "darwin" {
+		switch mod {
+		case fyne.KeyModifierSuper:
+			return "Cmd"
+		case fyne.KeyModifierAlt:
+			return "Option"
+		}
+	}
+	for _, m := range shortcutModifiers {
+		if m.mod == mod {
+			return m.name
+		}
+	}
+	return ""
+}
+
+// ShowPalette shows the command palette: a search over the actions that
+// runs the one picked with Enter or a tap. Up and Down move the pick.
+func (s *Shortcuts) ShowPalette() {
+	if s.window == nil {
+		return
+	}
+	matches, picked := s.Search(""), 0
+	var d dialog.Dialog
+
+	list := widget.NewList(
+		func() int { return len(matches) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, nil, widget.NewLabel(""), widget.NewLabel(""))
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			row := o.(*fyne.Container)
+			name, keys := row.Objects[0].(*widget.Label), row.Objects[1].(*widget.Label)
+			name.TextStyle.Bold = i == picked
+			name.SetText(matches[i].Name)
+			keys.SetText(s.Label(matches[i].ID))
+		},
+	)
+	run := func(i int) {
+		if i < 0 || i >= len(matches) {
+			return
+		}
+		d.Hide()
+		matches[i].Run()
+	}
+	list.OnSelected = func(i widget.ListItemID) {
+		list.Unselect(i)
+		run(i)
+	}
+	pick := func(i int) {
+		if i >= 0 && i < len(matches) {
+			picked = i
+			list.ScrollTo(i)
+			list.Refresh()
+		}
+	}
+
+	search := &paletteEntry{}
+	search.ExtendBaseWidget(search)
+	search.SetPlaceHolder(T("Type a command..."))
+	search.OnChanged = func(query string) {
+		matches, picked = s.Search(query), 0
+		list.ScrollToTop()
+		list.Refresh()
+	}
+	search.OnSubmitted = func(string) { run(picked) }
+	search.onKey = func(key fyne.KeyName) bool {
+		switch key {
+		case fyne.KeyUp:
+			pick(picked - 1)
+		case fyne.KeyDown:
+			pick(picked + 1)
+		case fyne.KeyEscape:
+			d.Hide()
+		default:
+			return false
+		}
+		return true
+	}
+
+	d = dialog.NewCustom(T("🔎 Commands"), T("Close"), container.NewBorder(search, nil, nil, nil, list), s.window)
+	d.Resize(fyne.NewSize(480, 400))
+	d.Show()
+	s.window.Canvas().Focus(search)
+}
+
+// paletteEntry is the search box of the command palette, which moves the
+// pick with the arrow keys.
+type paletteEntry struct {
+	widget.Entry
+	onKey func(fyne.KeyName) bool
+}
+
+func (e *paletteEntry) TypedKey(key *fyne.KeyEvent) {
+	if e.onKey == nil || !e.onKey(key.Name) {
+		e.Entry.TypedKey(key)
+	}
+}
+
+// ShowBindings lists the actions with their shortcuts, to change them.
+func (s *Shortcuts) ShowBindings() {
+	if s.window == nil {
+		return
+	}
+	grid := container.New(layout.NewFormLayout())
+	var fill func()
+	fill = func() {
+		grid.Objects = nil
+		for _, a := range s.actions {
+			a := a
+			label := s.Label(a.ID)
+			if label == "" {
+				label = "—"
+			}
+			grid.Add(widget.NewLabel(a.Name))
+			grid.Add(widget.NewButton(label, func() { s.editBinding(a, fill) }))
+		}
+		grid.Refresh()
+	}
+	fill()
+
+	reset := widget.NewButton(T("Reset to defaults"), func() {
+		if err := s.Reset(); err != nil {
+			dialog.ShowError(err, s.window)
+		}
+		fill()
+	})
+	d := dialog.NewCustom(T("⌨️ Keyboard Shortcuts"), T("Close"), container.NewBorder(nil, reset, nil, nil, container.NewVScroll(grid)), s.window)
+	d.Resize(fyne.NewSize(480, 500))
+	d.Show()
+}
+
+// editBinding asks for a new shortcut for a and calls done once it is
+// bound.
+func (s *Shortcuts) editBinding(a *Action, done func()) {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder(T("e.g. Ctrl+Shift+N"))
+	entry.SetText(s.Label(a.ID))
+	entry.Validator = func(text string) error {
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		_, err := ParseShortcut(text)
+		return err
+	}
+	item := widget.NewFormItem(T("Shortcut"), entry)
+	item.HintText = T("Leave empty for none")
+	dialog.ShowForm(a.Name, T("Save"), T("Cancel"), []*widget.FormItem{item}, func(save bool) {
+		if !save {
+			return
+		}
+		var sc *desktop.CustomShortcut
+		if strings.TrimSpace(entry.Text) != "" {
+			var err error
+			if sc, err = ParseShortcut(entry.Text); err != nil {
+				dialog.ShowError(err, s.window)
+				return
+			}
+		}
+		if err := s.Bind(a.ID, sc); err != nil {
+			dialog.ShowError(err, s.window)
+			return
+		}
+		done()
+	}, s.window)
+}
+
+// shortcutEntry is an Entry that passes shortcuts like Ctrl+Z on to the
+// window. Fyne gives shortcuts to the focused widget only, so without it
+// the registry's shortcuts would stop working while typing.
+type shortcutEntry struct {
+	widget.Entry
+}
+
+func newShortcutEntry() *shortcutEntry {
+	e := &shortcutEntry{}
+	e.ExtendBaseWidget(e)
+	return e
+}
+
+func (e *shortcutEntry) TypedShortcut(s fyne.Shortcut) {
+	if _, ok := s.(*desktop.CustomShortcut); ok {
+		if c, ok := fyne.CurrentApp().Driver().CanvasForObject(e).(fyne.Shortcutable); ok {
+			c.TypedShortcut(s)
+		}
+	}
+	e.Entry.TypedShortcut(s)
+}
@@ -141,18 +141,18 @@ func (r *cherryCardRenderer) Refresh() {
 	// Update status
 	if cherry.IsCompiled {
 		r.statusLabel.SetText("✅")
-		r.statusLabel.SetText("Compiled")
+		r.statusLabel.SetText(T("Compiled"))
 	} else {
 		r.statusLabel.SetText("⏳")
-		r.statusLabel.SetText("Pending")
+		r.statusLabel.SetText(T("Pending"))
 	}
 	
 	// Update time
-	r.timeLabel.SetText(formatTime(cherry.CreatedAt))
+	r.timeLabel.SetText(FormatDateTime(cherry.CreatedAt))
 	r.timeLabel.TextStyle.Italic = true
 	
 	// Update compile button
-	r.runButton.SetText("⚡ Compile")
+	r.runButton.SetText(T("⚡ Compile"))
 	r.runButton.Importance = widget.HighImportance
 	
 	// Update background color based on status
@@ -221,14 +221,14 @@ func NewCherryCardRenderer(card *CherryCard) *cherryCardRenderer {
 		statusLabel.SetText("✅")
 	}
 	
-	timeLabel := widget.NewLabel(formatTime(cherry.CreatedAt))
+	timeLabel := widget.NewLabel(FormatDateTime(cherry.CreatedAt))
 	timeLabel.TextStyle.Italic = true
 	
 	// Buttons with cherry styling
-	compileButton := widget.NewButton("⚡ Compile", card.onRun)
+	compileButton := widget.NewButton(T("⚡ Compile"), card.onRun)
 	compileButton.Importance = widget.HighImportance
 	
-	shareButton := widget.NewButton("📤 Share", card.onShare)
+	shareButton := widget.NewButton(T("📤 Share"), card.onShare)
 	shareButton.Importance = widget.MediumImportance
 	
 	deleteButton := widget.NewButton("🗑️", card.onDelete)
@@ -350,13 +350,13 @@ func (r *heroCardRenderer) Refresh() {
 	
 	// Update status
 	if cherry.IsCompiled {
-		r.statusLabel.SetText("✅ Compiled")
+		r.statusLabel.SetText(T("✅ Compiled"))
 	} else {
-		r.statusLabel.SetText("⏳ Pending")
+		r.statusLabel.SetText(T("⏳ Pending"))
 	}
 	
 	// Update compile button
-	r.runButton.SetText("⚡ Compile Cherry")
+	r.runButton.SetText(T("⚡ Compile Cherry"))
 	r.runButton.Importance = widget.HighImportance
 	
 	// Update background with gradient effect
@@ -413,13 +413,13 @@ func NewHeroCardRenderer(hero *HeroCard) *heroCardRenderer {
 	stackLabel := widget.NewLabel(fmt.Sprintf("%s • %s", cherry.Stack, cherry.Size))
 	stackLabel.Alignment = fyne.TextAlignCenter
 	
-	statusLabel := widget.NewLabel("⏳ Pending")
+	statusLabel := widget.NewLabel(T("⏳ Pending"))
 	if cherry.IsCompiled {
-		statusLabel.SetText("✅ Compiled")
+		statusLabel.SetText(T("✅ Compiled"))
 	}
 	
 	// Compile button with cherry styling
-	compileButton := widget.NewButton("⚡ Compile Cherry", func() {
+	compileButton := widget.NewButton(T("⚡ Compile Cherry"), func() {
 		// This would be connected to the cherry manager
 	})
 	compileButton.Importance = widget.HighImportance
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAutostart(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	const id = "com.filecherry.checkapp"
+	if autostartEnabled(id) {
+		t.Fatal("autostart enabled from the start")
+	}
+	if err := setAutostart(id, "checkapp", true); err != nil {
+		t.Fatal(err)
+	}
+	path, _ := autostartPath(id)
+	data, err := os.ReadFile(path)
+	if err != nil || !autostartEnabled(id) {
+		t.Fatalf("no autostart entry: %v", err)
+	}
+	for _, want := range []string{"[Desktop Entry]\n", "Type=Application\n", "Name=checkapp\n", " --hidden\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("autostart entry lacks %q:\n%s", want, data)
+		}
+	}
+	if err := setAutostart(id, "checkapp", false); err != nil || autostartEnabled(id) {
+		t.Errorf("autostart still enabled: %v", err)
+	}
+	if err := setAutostart(id, "checkapp", false); err != nil {
+		t.Errorf("disabling twice: %v", err)
+	}
+	if path, _ := autostartPath("com.filecherry.My App/2"); filepath.Base(path) != "com.filecherry.My-App-2.desktop" {
+		t.Errorf("autostart entry of an ID with spaces: %s", path)
+	}
+
+	for arg, want := range map[string]string{
+		"/usr/bin/checkapp":       "/usr/bin/checkapp",
+		"/opt/My Apps/checkapp":   `"/opt/My Apps/checkapp"`,
+		`/home/sam/$HOME "q"/app`: `"/home/sam/\\$HOME \\"q\\"/app"`,
+		`/mnt/c\apps/checkapp`:    `"/mnt/c\\\\apps/checkapp"`,
+		"/opt/100%/checkapp":      "/opt/100%%/checkapp",
+		"/opt/100% sure/checkapp": `"/opt/100%% sure/checkapp"`,
+	} {
+		if got := desktopExecQuote(arg); got != want {
+			t.Errorf("desktopExecQuote(%q) = %s, want %s", arg, got, want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// autostartSupported reports whether setAutostart works on this system.
+// Only Linux (XDG autostart) is supported so far.
+const autostartSupported = false
+
+func autostartEnabled(id string) bool {
+	return false
+}
+
+func setAutostart(id, name string, on bool) error {
+	return errors.New("starting on login is only supported on Linux")
+}
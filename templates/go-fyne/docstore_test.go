@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func openTestStore(t *testing.T) (*DocStore, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "docs.json")
+	s, err := OpenDocStore(path, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, path
+}
+
+func TestDocStorePutGetDel(t *testing.T) {
+	s, path := openTestStore(t)
+
+	id, err := s.Put(Doc{"type": "note", "title": "hello", "tags": []string{"a", "b"}, "created": 1700000000000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(id) != 36 {
+		t.Errorf("generated ID %q is not a UUID", id)
+	}
+	if _, err := s.Put(Doc{"_id": "fixed", "type": "note"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put(Doc{"_id": 42}); err == nil {
+		t.Error("accepted a numeric _id")
+	}
+
+	doc, err := s.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc["title"] != "hello" || doc["created"] != float64(1700000000000) || doc.ID() != id {
+		t.Errorf("doc = %v", doc)
+	}
+	doc["title"] = "changed"
+	if again, _ := s.Get(id); again["title"] != "hello" {
+		t.Error("Get returned the stored document instead of a copy")
+	}
+
+	if err := s.Del(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(id); !errors.Is(err, ErrDocNotFound) {
+		t.Errorf("Get after Del: %v", err)
+	}
+	if err := s.Del("never-existed"); err != nil {
+		t.Errorf("deleting a missing document: %v", err)
+	}
+
+	reopened, err := OpenDocStore(path, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docs := reopened.All(); len(docs) != 1 || docs[0].ID() != "fixed" {
+		t.Errorf("reopened store has %v", docs)
+	}
+	if reopened.Seq() != s.Seq() {
+		t.Errorf("reopened seq = %d, want %d", reopened.Seq(), s.Seq())
+	}
+}
+
+func TestDocStoreQuery(t *testing.T) {
+	s, _ := openTestStore(t)
+	s.Bulk([]Doc{
+		{"_id": "a", "type": "todo", "rank": 3},
+		{"_id": "b", "type": "note", "rank": 1},
+		{"_id": "c", "type": "todo", "rank": 2},
+		{"_id": "d", "type": "todo"},
+	})
+
+	ids := func(docs []Doc) []string {
+		var ids []string
+		for _, d := range docs {
+			ids = append(ids, d.ID())
+		}
+		return ids
+	}
+	if got := ids(s.Query("type", "todo")); !slices.Equal(got, []string{"a", "c", "d"}) {
+		t.Errorf("Query(type, todo) = %v", got)
+	}
+	if got := ids(s.Query("rank", nil)); !slices.Equal(got, []string{"b", "c", "a"}) {
+		t.Errorf("Query(rank, nil) = %v, want ordered by rank", got)
+	}
+	if got := ids(s.Query("rank", 2)); !slices.Equal(got, []string{"c"}) {
+		t.Errorf("Query(rank, 2) = %v", got)
+	}
+
+	// The index follows later writes
+	s.Put(Doc{"_id": "b", "type": "todo"})
+	s.Del("a")
+	if got := ids(s.Query("type", "todo")); !slices.Equal(got, []string{"b", "c", "d"}) {
+		t.Errorf("Query(type, todo) after writes = %v", got)
+	}
+	if got := ids(s.Query("type", "note")); len(got) != 0 {
+		t.Errorf("Query(type, note) after writes = %v", got)
+	}
+}
+
+func TestDocStoreChanges(t *testing.T) {
+	s, _ := openTestStore(t)
+	var seen []Change
+	cancel := s.Subscribe(func(c Change) { seen = append(seen, c) })
+
+	s.Put(Doc{"_id": "a", "n": 1})
+	_, since := s.Changes(0)
+	s.Put(Doc{"_id": "b", "n": 1})
+	s.Put(Doc{"_id": "a", "n": 2})
+	s.Del("b")
+
+	changes, seq := s.Changes(since)
+	if len(changes) != 2 || changes[0].ID != "a" || changes[0].Doc["n"] != float64(2) || changes[1].ID != "b" || !changes[1].Deleted {
+		t.Errorf("Changes(%d) = %+v", since, changes)
+	}
+	if seq != s.Seq() || seq != 4 {
+		t.Errorf("seq = %d, store seq = %d, want 4", seq, s.Seq())
+	}
+	if len(seen) != 4 || !seen[3].Deleted {
+		t.Errorf("subscriber saw %+v", seen)
+	}
+
+	cancel()
+	s.Put(Doc{"_id": "c"})
+	if len(seen) != 4 {
+		t.Error("subscriber called after cancel")
+	}
+}
+
+func TestDocStoreExportImport(t *testing.T) {
+	s, _ := openTestStore(t)
+	s.Bulk([]Doc{{"_id": "1", "type": "todo", "text": "a"}, {"_id": "2", "type": "todo", "text": "b"}})
+
+	var buf bytes.Buffer
+	if err := s.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+	other, _ := openTestStore(t)
+	if n, err := other.Import(&buf); err != nil || n != 2 {
+		t.Fatalf("Import = %d, %v", n, err)
+	}
+	if docs := other.Query("type", "todo"); len(docs) != 2 || docs[1]["text"] != "b" {
+		t.Errorf("imported %v", docs)
+	}
+
+	// A plain array, and the result of Fireproof's db.allDocs()
+	if n, err := other.Import(strings.NewReader(`[{"_id": "3", "type": "todo"}, {"_id": "1", "_deleted": true}]`)); err != nil || n != 2 {
+		t.Fatalf("Import(array) = %d, %v", n, err)
+	}
+	if n, err := other.Import(strings.NewReader(`{"rows": [{"key": "4", "value": {"_id": "4", "type": "todo"}}]}`)); err != nil || n != 1 {
+		t.Fatalf("Import(allDocs) = %d, %v", n, err)
+	}
+	if docs := other.Query("type", "todo"); len(docs) != 3 {
+		t.Errorf("after imports: %v", docs)
+	}
+	if _, err := other.Import(strings.NewReader(`"nope"`)); err == nil {
+		t.Error("imported a JSON string")
+	}
+}
+
+func TestDocStoreApply(t *testing.T) {
+	s, _ := openTestStore(t)
+	id, _ := s.Put(Doc{"text": "a"})
+	base := s.Version(id).Rev
+
+	edit := Change{ID: id, Doc: Doc{"text": "b"}, Rev: newDocID(), BaseRev: base}
+	if rejected, err := s.Apply([]Change{edit}); err != nil || len(rejected) != 0 {
+		t.Fatalf("Apply = %v, %v", rejected, err)
+	}
+	if v := s.Version(id); v.Rev != edit.Rev || v.Doc["text"] != "b" {
+		t.Errorf("version after Apply = %+v", v)
+	}
+	// Applying it again is a no-op; an edit of an older revision is rejected
+	if rejected, _ := s.Apply([]Change{edit}); len(rejected) != 0 || s.Seq() != 2 {
+		t.Errorf("reapplied: rejected %v, seq %d", rejected, s.Seq())
+	}
+	stale := Change{ID: id, Deleted: true, Rev: newDocID(), BaseRev: base}
+	if rejected, _ := s.Apply([]Change{stale}); len(rejected) != 1 || rejected[0].Rev != edit.Rev {
+		t.Errorf("stale edit: rejected %+v", rejected)
+	}
+	// A delete of a document never seen here is kept as a tombstone
+	s.Apply([]Change{{ID: "gone", Deleted: true, Rev: newDocID()}})
+	if v := s.Version("gone"); !v.Deleted || v.Rev == "" {
+		t.Errorf("tombstone = %+v", v)
+	}
+}
+
+func TestDocStoreUpgradesVersion1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "docs.json")
+	os.WriteFile(path, []byte(`{"version": 1, "seq": 3, "docs": [{"seq": 1, "doc": {"_id": "a"}}], "deleted": {"b": 3}}`), 0644)
+	s, err := OpenDocStore(path, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := s.Version("a"), s.Version("b"); a.Rev == "" || !b.Deleted || b.Seq != 3 || b.Rev == "" {
+		t.Errorf("upgraded versions: %+v, %+v", a, b)
+	}
+}
+
+func TestNewDocIDOrdered(t *testing.T) {
+	prev := newDocID()
+	for i := 0; i < 5000; i++ {
+		id := newDocID()
+		if id <= prev {
+			t.Fatalf("%s after %s", id, prev)
+		}
+		if id[14] != '7' {
+			t.Fatalf("%s is not a UUIDv7", id)
+		}
+		prev = id
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"sync"
+)
+
+// maxHistory is how many steps can be undone.
+const maxHistory = 100
+
+// Command is one undoable change to the tasks: the documents it wrote and
+// the versions they replaced. An operation on many tasks, like an import,
+// is a single command, so it undoes in one step.
+type Command struct {
+	Name   string // what it did, e.g. "Task deleted"
+	before []Doc  // {_id, _deleted: true} for documents that didn't exist
+	after  []Doc
+}
+
+// history holds a TaskManager's undo and redo stacks. Only changes made
+// through the manager are recorded; changes pulled by sync are not, and
+// undoing a step puts back the versions it replaced even if sync changed
+// the tasks since.
+type history struct {
+	mu         sync.Mutex
+	undo, redo []*Command
+}
+
+// do writes docs with a single save and records it as a command. Docs
+// with "_deleted": true are deleted.
+func (tm *TaskManager) do(name string, docs []Doc) error {
+	cmd := &Command{Name: name}
+	for _, doc := range docs {
+		doc = cloneDoc(doc)
+		if doc.ID() == "" {
+			doc["_id"] = newDocID()
+		}
+		before, err := tm.store.Get(doc.ID())
+		if errors.Is(err, ErrDocNotFound) {
+			before = Doc{"_id": doc.ID(), "_deleted": true}
+		} else if err != nil {
+			return err
+		}
+		cmd.before = append(cmd.before, before)
+		cmd.after = append(cmd.after, doc)
+	}
+	if _, err := tm.store.Bulk(cmd.after); err != nil {
+		return err
+	}
+
+	h := &tm.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.undo = append(h.undo, cmd)
+	if len(h.undo) > maxHistory {
+		h.undo = h.undo[1:]
+	}
+	h.redo = nil
+	return nil
+}
+
+// Undo reverts the last command and returns its name, or "" when there
+// is nothing to undo.
+func (tm *TaskManager) Undo() (string, error) {
+	return tm.step(&tm.history.undo, &tm.history.redo, func(c *Command) []Doc { return c.before })
+}
+
+// Redo repeats the last undone command and returns its name, or "" when
+// there is nothing to redo.
+func (tm *TaskManager) Redo() (string, error) {
+	return tm.step(&tm.history.redo, &tm.history.undo, func(c *Command) []Doc { return c.after })
+}
+
+// step moves the top command of from to to, writing the version of its
+// documents that docs picks.
+func (tm *TaskManager) step(from, to *[]*Command, docs func(*Command) []Doc) (string, error) {
+	h := &tm.history
+	h.mu.Lock()
+	if len(*from) == 0 {
+		h.mu.Unlock()
+		return "", nil
+	}
+	cmd := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+	h.mu.Unlock()
+
+	// Write without the lock: subscribers may ask CanUndo
+	_, err := tm.store.Bulk(docs(cmd))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil {
+		*from = append(*from, cmd)
+		return "", err
+	}
+	*to = append(*to, cmd)
+	return cmd.Name, nil
+}
+
+// CanUndo reports whether there is a command to undo.
+func (tm *TaskManager) CanUndo() bool {
+	tm.history.mu.Lock()
+	defer tm.history.mu.Unlock()
+	return len(tm.history.undo) > 0
+}
+
+// CanRedo reports whether there is an undone command to redo.
+func (tm *TaskManager) CanRedo() bool {
+	tm.history.mu.Lock()
+	defer tm.history.mu.Unlock()
+	return len(tm.history.redo) > 0
+}
+
+// clearHistory forgets all commands, e.g. after adding the welcome tasks.
+func (tm *TaskManager) clearHistory() {
+	tm.history.mu.Lock()
+	defer tm.history.mu.Unlock()
+	tm.history.undo, tm.history.redo = nil, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUndoRedo(t *testing.T) {
+	tm := newTestManager(t)
+	if tm.CanUndo() {
+		t.Error("the welcome tasks can be undone")
+	}
+	texts := func() (s []string) {
+		for _, task := range tm.GetTasks() {
+			mark := ""
+			if task.Completed {
+				mark = "✓"
+			}
+			s = append(s, task.Text+mark)
+		}
+		return s
+	}
+	check := func(step string, want ...string) {
+		t.Helper()
+		got := texts()
+		if len(got) != len(want) {
+			t.Fatalf("after %s: %q, want %q", step, got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("after %s: %q, want %q", step, got, want)
+			}
+		}
+	}
+	welcome, native := texts()[0], texts()[1]
+
+	tm.AddTask("Buy milk", "low")
+	milk := tm.GetTasks()[2].ID
+	tm.ToggleTask(milk)
+	tm.DeleteTask(milk)
+	check("delete", welcome, native)
+
+	for _, want := range []string{"Task deleted", "Task completed", "Task added"} {
+		if name, err := tm.Undo(); err != nil || name != want {
+			t.Fatalf("Undo = %q, %v; want %q", name, err, want)
+		}
+		switch want {
+		case "Task deleted":
+			check("undoing the delete", welcome, native, "Buy milk✓")
+		case "Task completed":
+			check("undoing the toggle", welcome, native, "Buy milk")
+		}
+	}
+	check("undoing everything", welcome, native)
+	if name, _ := tm.Undo(); name != "" || tm.CanUndo() {
+		t.Errorf("Undo with nothing to undo = %q", name)
+	}
+
+	tm.Redo()
+	tm.Redo()
+	check("redo", welcome, native, "Buy milk✓")
+	if !tm.CanRedo() {
+		t.Fatal("nothing to redo")
+	}
+	// A new change drops what is left to redo
+	tm.AddTask("Call Sam", "high")
+	if tm.CanRedo() {
+		t.Error("redo survived a new change")
+	}
+}
+
+func TestUndoBulk(t *testing.T) {
+	tm := newTestManager(t)
+
+	// An import is one step
+	tasks := []Task{{Text: "one"}, {Text: "two"}, {Text: "three"}}
+	for i := range tasks {
+		checkTask(&tasks[i])
+	}
+	if _, err := tm.ImportTasks(tasks, SkipDuplicates); err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := tm.Undo(); name != "3 tasks imported" || len(tm.GetTasks()) != 2 {
+		t.Fatalf("undo %q left %d tasks", name, len(tm.GetTasks()))
+	}
+	tm.Redo()
+	if len(tm.GetTasks()) != 5 {
+		t.Fatalf("redo left %d tasks", len(tm.GetTasks()))
+	}
+
+	// Completing a repeating task and adding the next one is one step too
+	due := time.Now().Add(-time.Hour)
+	tm.Add(Task{Text: "Stand-up", DueAt: &due, Repeat: "daily"})
+	var id string
+	for _, task := range tm.GetTasks() {
+		if task.Text == "Stand-up" {
+			id = task.ID
+		}
+	}
+	tm.ToggleTask(id)
+	if len(tm.GetTasks()) != 7 {
+		t.Fatalf("%d tasks after completing a repeating task", len(tm.GetTasks()))
+	}
+	tm.Undo()
+	if task, _ := tm.Task(id); len(tm.GetTasks()) != 6 || task.Completed {
+		t.Errorf("undo left %d tasks, %+v", len(tm.GetTasks()), task)
+	}
+
+	// The history is bounded
+	for i := 0; i < maxHistory+10; i++ {
+		tm.AddTask("again", "low")
+	}
+	n := 0
+	for tm.CanUndo() {
+		tm.Undo()
+		n++
+	}
+	if n != maxHistory {
+		t.Errorf("undid %d steps, want %d", n, maxHistory)
+	}
+}
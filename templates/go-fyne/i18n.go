@@ -0,0 +1,293 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// The message catalogs, one per language, e.g. locales/de.json. See
+// Catalog for the format.
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// sourceLocale is the language of the messages in the code.
+const sourceLocale = "en"
+
+// Catalog is the message catalog of a language: the translations of the
+// messages, keyed by their English text, and how dates are written.
+type Catalog struct {
+	Language string `json:"language"` // its own name, e.g. "Deutsch"
+
+	// Layouts of time.Format, and the short names of the months, January
+	// first, and weekdays, Sunday first, that replace "Jan" and "Mon"
+	DateTime string   `json:"dateTime"`
+	Date     string   `json:"date"`
+	Time     string   `json:"time"`
+	Months   []string `json:"months,omitempty"`
+	Weekdays []string `json:"weekdays,omitempty"`
+
+	Messages map[string]Message `json:"messages"`
+}
+
+// Message is a translation. A message with a count, see N, has a form for
+// each plural category of the language ("zero", "one", "two", "few",
+// "many" and "other"); a plain message is just a string, its "other" form.
+type Message map[string]string
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		*m = Message{"other": s}
+		return nil
+	}
+	var forms map[string]string
+	if err := json.Unmarshal(data, &forms); err != nil {
+		return fmt.Errorf("a message is a string or an object of plural forms: %v", err)
+	}
+	*m = forms
+	return nil
+}
+
+func (m Message) MarshalJSON() ([]byte, error) {
+	if len(m) == 1 && m["other"] != "" {
+		return json.Marshal(m["other"])
+	}
+	return json.Marshal(map[string]string(m))
+}
+
+// locale is the language in use.
+type locale struct {
+	tag     string // e.g. "de" or "pt-BR"
+	catalog *Catalog
+}
+
+var currentLocale atomic.Pointer[locale]
+
+func init() {
+	SetLocale(sourceLocale)
+}
+
+// loadCatalog reads the catalog of tag from the embedded locales.
+func loadCatalog(tag string) (*Catalog, error) {
+	data, err := localeFiles.ReadFile(path.Join("locales", tag+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("locales/%s.json: %v", tag, err)
+	}
+	return &c, nil
+}
+
+// Locales returns the tags of the languages with a catalog.
+func Locales() []string {
+	entries, _ := localeFiles.ReadDir("locales")
+	var tags []string
+	for _, e := range entries {
+		if tag, ok := strings.CutSuffix(e.Name(), ".json"); ok {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// SetLocale switches to the best catalog for tag, e.g. "de-AT" or
+// "de_AT.UTF-8": the one for the region, then for the language, then
+// English. It returns the tag of the catalog in use.
+func SetLocale(tag string) string {
+	tag = normalizeLocale(tag)
+	candidates := []string{tag}
+	if lang, _, ok := strings.Cut(tag, "-"); ok {
+		candidates = append(candidates, lang)
+	}
+	for _, t := range append(candidates, sourceLocale) {
+		if c, err := loadCatalog(t); err == nil {
+			currentLocale.Store(&locale{tag: t, catalog: c})
+			return t
+		}
+	}
+	// Without an English catalog the messages stay as they are
+	currentLocale.Store(&locale{tag: sourceLocale, catalog: &Catalog{DateTime: "Jan 2, 3:04 PM", Date: "Jan 2, 2006", Time: "3:04 PM"}})
+	return sourceLocale
+}
+
+// Locale returns the tag of the catalog in use.
+func Locale() string {
+	return currentLocale.Load().tag
+}
+
+// normalizeLocale turns a POSIX locale like "pt_BR.UTF-8@euro" into a tag
+// like "pt-BR".
+func normalizeLocale(s string) string {
+	s, _, _ = strings.Cut(s, ".")
+	s, _, _ = strings.Cut(s, "@")
+	lang, region, _ := strings.Cut(strings.ReplaceAll(s, "_", "-"), "-")
+	if region == "" {
+		return strings.ToLower(lang)
+	}
+	return strings.ToLower(lang) + "-" + strings.ToUpper(region)
+}
+
+// DetectLocale returns the user's language: from LANGUAGE, LC_ALL,
+// LC_MESSAGES or LANG, then from the system settings on Windows and macOS,
+// else English.
+func DetectLocale() string {
+	for _, env := range []string{"LANGUAGE", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		v, _, _ := strings.Cut(os.Getenv(env), ":") // LANGUAGE is a list
+		if v != "" && v != "C" && v != "POSIX" && !strings.HasPrefix(v, "C.") {
+			return normalizeLocale(v)
+		}
+	}
+	if tag := systemLocale(); tag != "" {
+		return normalizeLocale(tag)
+	}
+	return sourceLocale
+}
+
+// T translates message and, with args, formats it like fmt.Sprintf.
+// Messages without a translation stay in English.
+func T(message string, args ...any) string {
+	if m, ok := currentLocale.Load().catalog.Messages[message]; ok && m["other"] != "" {
+		message = m["other"]
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// N translates a message with a count. one and other are the English
+// forms, e.g. "%d task" and "%d tasks"; the catalog has the forms of its
+// language under the key other. The message is formatted with args, or
+// with n if there are none.
+func N(one, other string, n int, args ...any) string {
+	l := currentLocale.Load()
+	message := other
+	if pluralCategory(sourceLocale, n) == "one" {
+		message = one
+	}
+	if m, ok := l.catalog.Messages[other]; ok {
+		lang, _, _ := strings.Cut(l.tag, "-")
+		if form := m[pluralCategory(lang, n)]; form != "" {
+			message = form
+		} else if form := m["other"]; form != "" {
+			message = form
+		}
+	}
+	if len(args) == 0 {
+		args = []any{n}
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// pluralCategory returns the CLDR plural category of n in the language
+// lang, for the languages with rules beyond "one" and "other". The rest
+// follow English.
+func pluralCategory(lang string, n int) string {
+	if n < 0 {
+		n = -n
+	}
+	mod10, mod100 := n%10, n%100
+	switch lang {
+	case "ja", "ko", "zh", "vi", "th", "id", "ms":
+		return "other"
+	case "fr", "pt":
+		if n == 0 || n == 1 {
+			return "one"
+		}
+	case "ru", "uk", "be", "sr", "hr", "bs":
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	case "pl":
+		switch {
+		case n == 1:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	case "cs", "sk":
+		switch {
+		case n == 1:
+			return "one"
+		case n >= 2 && n <= 4:
+			return "few"
+		}
+	case "ar":
+		switch {
+		case n == 0:
+			return "zero"
+		case n == 1:
+			return "one"
+		case n == 2:
+			return "two"
+		case mod100 >= 3 && mod100 <= 10:
+			return "few"
+		case mod100 >= 11:
+			return "many"
+		}
+	default:
+		if n == 1 {
+			return "one"
+		}
+	}
+	return "other"
+}
+
+// FormatDateTime writes a date and time the way the language does, e.g.
+// "Mar 5, 3:04 PM" in English and "5. März, 15:04" in German.
+func FormatDateTime(t time.Time) string {
+	c := currentLocale.Load().catalog
+	return formatLocalTime(t, c.DateTime, c)
+}
+
+// FormatDate writes a date the way the language does.
+func FormatDate(t time.Time) string {
+	c := currentLocale.Load().catalog
+	return formatLocalTime(t, c.Date, c)
+}
+
+// FormatClock writes a time of day the way the language does.
+func FormatClock(t time.Time) string {
+	c := currentLocale.Load().catalog
+	return formatLocalTime(t, c.Time, c)
+}
+
+// formatLocalTime formats t with layout, putting in the catalog's names of
+// the months and weekdays. The layout's "Jan" and "Mon" become
+// placeholders Format leaves alone, so names in the catalog can't be
+// mistaken for layout elements.
+func formatLocalTime(t time.Time, layout string, c *Catalog) string {
+	const month, weekday = "\x00\x01", "\x00\x02"
+	if len(c.Months) == 12 {
+		layout = strings.Replace(layout, "Jan", month, 1)
+	}
+	if len(c.Weekdays) == 7 {
+		layout = strings.Replace(layout, "Mon", weekday, 1)
+	}
+	s := t.Format(layout)
+	if len(c.Months) == 12 {
+		s = strings.Replace(s, month, c.Months[t.Month()-1], 1)
+	}
+	if len(c.Weekdays) == 7 {
+		s = strings.Replace(s, weekday, c.Weekdays[t.Weekday()], 1)
+	}
+	return s
+}
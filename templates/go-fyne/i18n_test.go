@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+)
+
+var update = flag.Bool("update", false, "rewrite locales/en.json with the messages of the code")
+
+// sourceMessages collects the messages of the code: the literal arguments
+// of T and N, the names of the undoable changes, and the tables of names
+// that are translated when they are shown.
+func sourceMessages(t *testing.T) map[string]Message {
+	t.Helper()
+	messages := map[string]Message{}
+	add := func(other string, forms Message) {
+		if _, ok := messages[other]; !ok {
+			messages[other] = forms
+		}
+	}
+	literal := func(e ast.Expr) (string, bool) {
+		lit, ok := e.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(lit.Value)
+		return s, err == nil
+	}
+
+	files, _ := filepath.Glob("*.go")
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			var fn string
+			switch f := call.Fun.(type) {
+			case *ast.Ident:
+				fn = f.Name
+			case *ast.SelectorExpr:
+				fn = f.Sel.Name
+			}
+			switch fn {
+			case "T", "do": // tm.do names a change for undo
+				if s, ok := literal(call.Args[0]); ok {
+					add(s, Message{"other": s})
+				}
+			case "N":
+				one, ok1 := literal(call.Args[0])
+				other, ok2 := literal(call.Args[1])
+				if ok1 && ok2 {
+					messages[other] = Message{"one": one, "other": other}
+				}
+			}
+			return true
+		})
+	}
+
+	var tables []string
+	tables = append(tables, sortNames...)
+	tables = append(tables, priorities...)
+	tables = append(tables, mergeModes...)
+	tables = append(tables, duplicateChoices...)
+	for _, p := range repeatPresets {
+		tables = append(tables, p.name)
+	}
+	for _, c := range reminderChoices {
+		tables = append(tables, c.name)
+	}
+	for _, m := range themeModes {
+		tables = append(tables, m.name)
+	}
+	for _, s := range tables {
+		add(s, Message{"other": s})
+	}
+	return messages
+}
+
+// printfVerbs returns the verbs of a format, e.g. [%d %s], sorted, so a
+// translation may reorder them with explicit indexes like %[2]s.
+func printfVerbs(format string) []string {
+	verbs := regexp.MustCompile(`%(\[\d+\])?[-+# 0]*\d*(\.\d+)?[a-zA-Z%]`).FindAllString(format, -1)
+	for i, v := range verbs {
+		verbs[i] = regexp.MustCompile(`\[\d+\]`).ReplaceAllString(v, "")
+	}
+	verbs = slices.DeleteFunc(verbs, func(v string) bool { return v == "%%" })
+	slices.Sort(verbs)
+	return verbs
+}
+
+func TestSourceCatalog(t *testing.T) {
+	messages := sourceMessages(t)
+	if *update {
+		c, err := loadCatalog(sourceLocale)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Messages = messages
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(c); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join("locales", sourceLocale+".json"), buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	c, err := loadCatalog(sourceLocale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, m := range messages {
+		got, ok := c.Messages[key]
+		switch {
+		case !ok:
+			t.Errorf("locales/en.json lacks %q; run go test -run TestSourceCatalog -update", key)
+		case got["one"] != m["one"] || got["other"] != m["other"]:
+			t.Errorf("locales/en.json has %q as %v, the code %v", key, got, m)
+		}
+	}
+	for key := range c.Messages {
+		if _, ok := messages[key]; !ok {
+			t.Errorf("locales/en.json has %q, which the code no longer uses", key)
+		}
+	}
+}
+
+func TestCatalogs(t *testing.T) {
+	messages := sourceMessages(t)
+	categories := []string{"zero", "one", "two", "few", "many", "other"}
+	for _, tag := range Locales() {
+		c, err := loadCatalog(tag)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if c.Language == "" || c.DateTime == "" || c.Date == "" || c.Time == "" {
+			t.Errorf("%s: the language name or a date layout is missing", tag)
+		}
+		if len(c.Months) != 0 && len(c.Months) != 12 || len(c.Weekdays) != 0 && len(c.Weekdays) != 7 {
+			t.Errorf("%s: %d months and %d weekdays", tag, len(c.Months), len(c.Weekdays))
+		}
+		for key, m := range c.Messages {
+			if _, ok := messages[key]; !ok {
+				t.Errorf("%s: %q is not a message of the code", tag, key)
+				continue
+			}
+			for category, form := range m {
+				if !slices.Contains(categories, category) {
+					t.Errorf("%s: %q has a form for %q, which is not a plural category", tag, key, category)
+				}
+				// The one form may leave out the count, e.g. "a task"
+				want := printfVerbs(key)
+				if got := printfVerbs(form); !slices.Equal(got, want) && !(category == "one" && len(got) == len(want)-1) {
+					t.Errorf("%s: %q has the verbs %v in %q, want %v", tag, key, got, form, want)
+				}
+			}
+		}
+	}
+}
+
+func TestPluralCategory(t *testing.T) {
+	for _, tt := range []struct {
+		lang string
+		n    int
+		want string
+	}{
+		{"en", 0, "other"}, {"en", 1, "one"}, {"en", 2, "other"},
+		{"de", 1, "one"}, {"de", 21, "other"},
+		{"fr", 0, "one"}, {"fr", 1, "one"}, {"fr", 2, "other"},
+		{"ja", 1, "other"},
+		{"ru", 1, "one"}, {"ru", 21, "one"}, {"ru", 11, "many"}, {"ru", 3, "few"}, {"ru", 13, "many"}, {"ru", 5, "many"},
+		{"pl", 1, "one"}, {"pl", 21, "many"}, {"pl", 22, "few"}, {"pl", 12, "many"},
+		{"cs", 3, "few"}, {"cs", 5, "other"},
+		{"ar", 0, "zero"}, {"ar", 2, "two"}, {"ar", 103, "few"}, {"ar", 11, "many"}, {"ar", 100, "other"},
+	} {
+		if got := pluralCategory(tt.lang, tt.n); got != tt.want {
+			t.Errorf("pluralCategory(%q, %d) = %q, want %q", tt.lang, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	t.Cleanup(func() { SetLocale(sourceLocale) })
+	at := time.Date(2024, time.March, 5, 15, 4, 0, 0, time.UTC)
+
+	if got := T("🗑️ Task deleted"); got != "🗑️ Task deleted" {
+		t.Errorf("English T = %q", got)
+	}
+	if got := N("⏰ %d task due", "⏰ %d tasks due", 1); got != "⏰ 1 task due" {
+		t.Errorf("English N(1) = %q", got)
+	}
+	if got := FormatDateTime(at); got != "Mar 5, 3:04 PM" {
+		t.Errorf("English FormatDateTime = %q", got)
+	}
+
+	if got := SetLocale("de_AT.UTF-8"); got != "de" {
+		t.Fatalf("SetLocale(de_AT.UTF-8) = %q, want de", got)
+	}
+	if got := T("🗑️ Task deleted"); got != "🗑️ Aufgabe gelöscht" {
+		t.Errorf("German T = %q", got)
+	}
+	if got := T("↩️ Undone: %s", T("Task deleted")); got != "↩️ Rückgängig gemacht: Aufgabe gelöscht" {
+		t.Errorf("German T with an argument = %q", got)
+	}
+	if got := N("⏰ %d task due", "⏰ %d tasks due", 2); got != "⏰ 2 Aufgaben fällig" {
+		t.Errorf("German N(2) = %q", got)
+	}
+	if got := T("not in any catalog"); got != "not in any catalog" {
+		t.Errorf("a message without a translation became %q", got)
+	}
+	if got := FormatDateTime(at); got != "5. März, 15:04" {
+		t.Errorf("German FormatDateTime = %q", got)
+	}
+	if got := FormatDate(at); got != "Di., 5. März 2024" {
+		t.Errorf("German FormatDate = %q", got)
+	}
+
+	if got := SetLocale("xx-YY"); got != "en" || T("🗑️ Task deleted") != "🗑️ Task deleted" {
+		t.Errorf("SetLocale(xx-YY) = %q", got)
+	}
+}
+
+func TestDetectLocale(t *testing.T) {
+	for _, tt := range []struct{ language, lcAll, lang, want string }{
+		{"", "", "pt_BR.UTF-8", "pt-BR"},
+		{"fr:de", "", "pt_BR.UTF-8", "fr"},
+		{"", "C.UTF-8", "de_DE@euro", "de-DE"},
+	} {
+		t.Setenv("LANGUAGE", tt.language)
+		t.Setenv("LC_ALL", tt.lcAll)
+		t.Setenv("LC_MESSAGES", "")
+		t.Setenv("LANG", tt.lang)
+		if got := DetectLocale(); got != tt.want {
+			t.Errorf("DetectLocale with LANGUAGE=%q LC_ALL=%q LANG=%q = %q, want %q", tt.language, tt.lcAll, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestViewInGerman(t *testing.T) {
+	SetLocale("de")
+	t.Cleanup(func() { SetLocale(sourceLocale) })
+	view := newTestView(t)
+	before := len(view.manager.GetTasks())
+
+	if view.addButton.Text != "🍒 Aufgabe hinzufügen" || view.priority.Selected != "Mittel" {
+		t.Errorf("button %q, priority %q", view.addButton.Text, view.priority.Selected)
+	}
+	// The selectors show translations, the tasks keep the English values
+	test.Type(view.input, "Miete zahlen")
+	view.priority.SetSelected("Hoch")
+	view.repeat.SetSelected("Monatlich")
+	test.Type(view.due, "tomorrow 9:00")
+	test.Tap(view.addButton)
+
+	tasks := view.manager.GetTasks()
+	if len(tasks) != before+1 {
+		t.Fatalf("%d tasks, want %d", len(tasks), before+1)
+	}
+	if task := tasks[before]; task.Priority != "high" || task.Repeat != "FREQ=MONTHLY" {
+		t.Errorf("added %+v", task)
+	}
+	if view.repeat.Selected != "Nie" {
+		t.Errorf("repeat reset to %q", view.repeat.Selected)
+	}
+	view.sortBy.SetSelected("A-Z")
+	if first, _ := view.tasks.At(0); first.Text != "Miete zahlen" {
+		t.Errorf("sorting A-Z put %q first", first.Text)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// taskFileFilter limits the file dialogs to the formats of taskio.go.
+var taskFileFilter = storage.NewExtensionFileFilter([]string{".json", ".csv", ".md", ".markdown", ".ics"})
+
+// duplicateChoices labels the DuplicatePolicy values, in English; the
+// selector shows their translations.
+var duplicateChoices = []string{"Skip them", "Update the existing tasks", "Import them as new tasks"}
+
+// maxRowErrors is how many unreadable rows the import summary lists.
+const maxRowErrors = 10
+
+// taskFileMenu is the File menu with the import and export items and the
+// sync settings.
+func taskFileMenu(shortcuts *Shortcuts) *fyne.Menu {
+	return fyne.NewMenu(T("File"),
+		shortcuts.MenuItem("file.import"),
+		shortcuts.MenuItem("file.export"),
+		fyne.NewMenuItemSeparator(),
+		shortcuts.MenuItem("sync.settings"),
+	)
+}
+
+// showExport saves all tasks, in the format of the file name's extension.
+func showExport(tm *TaskManager, window fyne.Window) {
+	d := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if w == nil {
+			return // cancelled
+		}
+		tasks := tm.GetTasks()
+		format, err := FormatForName(w.URI().Name())
+		if err == nil {
+			err = WriteTasks(w, format, tasks)
+		}
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			storage.Delete(w.URI())
+			dialog.ShowError(err, window)
+			return
+		}
+		dialog.ShowInformation(T("📤 Export"), N("Exported %d task to %s", "Exported %d tasks to %s", len(tasks), len(tasks), w.URI().Name()), window)
+	}, window)
+	d.SetFileName("{{PROJECT_SLUG}}-tasks.json")
+	d.SetFilter(taskFileFilter)
+	d.Show()
+}
+
+// showImport reads a file, asks what to do with tasks that are already in
+// the list, if any, and imports the rest.
+func showImport(tm *TaskManager, window fyne.Window) {
+	d := dialog.NewFileOpen(func(r fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if r == nil {
+			return // cancelled
+		}
+		defer r.Close()
+		format, err := FormatForName(r.URI().Name())
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		tasks, rowErrs, err := ReadTasks(r, format)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("%s: %w", r.URI().Name(), err), window)
+			return
+		}
+
+		run := func(policy DuplicatePolicy) {
+			report, err := tm.ImportTasks(tasks, policy)
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			summary := widget.NewLabel(importSummary(report, rowErrs))
+			summary.Wrapping = fyne.TextWrapWord
+			scroll := container.NewVScroll(summary)
+			scroll.SetMinSize(fyne.NewSize(450, 150))
+			dialog.ShowCustom(T("📥 Import"), T("OK"), scroll, window)
+		}
+		dups := tm.CountDuplicates(tasks)
+		if dups == 0 {
+			run(SkipDuplicates)
+			return
+		}
+		choice := widget.NewSelect(translated(duplicateChoices), nil)
+		choice.SetSelectedIndex(int(SkipDuplicates))
+		items := []*widget.FormItem{widget.NewFormItem(T("Duplicates"), choice)}
+		title := N("📥 %d of %d tasks is already in the list", "📥 %d of %d tasks are already in the list", dups, dups, len(tasks))
+		dialog.ShowForm(title, T("Import"), T("Cancel"), items, func(ok bool) {
+			if ok {
+				run(DuplicatePolicy(choice.SelectedIndex()))
+			}
+		}, window)
+	}, window)
+	d.SetFilter(taskFileFilter)
+	d.Show()
+}
+
+// importSummary describes the outcome of an import, with the rows that
+// couldn't be read.
+func importSummary(report ImportReport, rowErrs []RowError) string {
+	lines := []string{T("✅ Added %d · 🔄 Updated %d · ⏭️ Skipped %d duplicates", report.Added, report.Updated, report.Skipped)}
+	if len(rowErrs) > 0 {
+		lines = append(lines, "", N("⚠️ %d row could not be imported:", "⚠️ %d rows could not be imported:", len(rowErrs)))
+		for i, err := range rowErrs {
+			if i == maxRowErrors {
+				lines = append(lines, T("...and %d more", len(rowErrs)-maxRowErrors))
+				break
+			}
+			lines = append(lines, err.Error())
+		}
+	}
+	return strings.Join(lines, "\n")
+}
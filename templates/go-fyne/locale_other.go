@@ -0,0 +1,22 @@
+//go:build !windows
+
+package main
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// systemLocale returns the language of the user's macOS settings, e.g.
+// "de_DE". Elsewhere the environment is all there is.
+func systemLocale() string {
+	if runtime.GOOS != "darwin" {
+		return ""
+	}
+	out, err := exec.Command("defaults", "read", "-g", "AppleLocale").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
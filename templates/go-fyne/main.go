@@ -1,216 +1,371 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 )
 
-// Task represents a single task item
-type Task struct {
-	ID          string
-	Text        string
-	Completed   bool
-	Priority    string
-	CreatedAt   time.Time
-	CompletedAt *time.Time
-}
+// Preferences of the look, see FileCherryTheme
+const (
+	prefTheme     = "theme"
+	prefThemeMode = "themeMode"
+)
 
-// TaskManager handles task operations
-type TaskManager struct {
-	tasks []Task
-}
+func main() {
+	// "--version" prints the build metadata, e.g. for package managers
+	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-version" || os.Args[1] == "version") {
+		fmt.Println("{{PROJECT_NAME}}", buildVersion())
+		return
+	}
 
-func NewTaskManager() *TaskManager {
-	return &TaskManager{
-		tasks: []Task{
-			{
-				ID:        "1",
-				Text:      "Welcome to your new Go desktop app! 🍒",
-				Completed: false,
-				Priority:  "high",
-				CreatedAt: time.Now(),
-			},
-			{
-				ID:        "2", 
-				Text:      "This is a native desktop application",
-				Completed: false,
-				Priority:  "medium",
-				CreatedAt: time.Now(),
-			},
-		},
+	// update, rollback, update-keygen and update-sign manage self-updates
+	if handled, err := updateCommand(os.Args[1:]); handled {
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
-}
 
-func (tm *TaskManager) AddTask(text, priority string) {
-	task := Task{
-		ID:        fmt.Sprintf("%d", len(tm.tasks)+1),
-		Text:      text,
-		Completed: false,
-		Priority:  priority,
-		CreatedAt: time.Now(),
+	noUpdate := flag.Bool("no-update", false, "never check for updates")
+	feed := flag.String("update-url", updateURL, "self-update manifest: an http(s) URL or a local file")
+	hidden := flag.Bool("hidden", false, "start hidden in the system tray, e.g. when starting on login")
+	syncURL := flag.String("sync-url", os.Getenv("{{ENV_PREFIX}}_SYNC_URL"), "sync tasks with the Go + Gin cherry at this URL (overrides the sync settings)")
+	syncToken := flag.String("sync-token", os.Getenv("{{ENV_PREFIX}}_SYNC_TOKEN"), "bearer token for a hub started with --sync-token (overrides the sync settings)")
+	flag.Parse()
+
+	// Speak the user's language, if there is a catalog for it in locales/
+	SetLocale(DetectLocale())
+
+	// Create the app, in the FileCherry look or the theme picked in the
+	// command palette; a theme file in the storage's themes folder is
+	// reloaded whenever it changes
+	myApp := app.NewWithID("com.filecherry.{{PROJECT_NAME}}")
+	prefs := myApp.Preferences()
+	themesDir := filepath.Join(myApp.Storage().RootURI().Path(), "themes")
+	appTheme := NewFileCherryTheme()
+	if err := appTheme.Use(prefs.StringWithFallback(prefTheme, themeCherry), themesDir); err != nil {
+		log.Println("theme:", err)
 	}
-	tm.tasks = append(tm.tasks, task)
-}
+	appTheme.SetMode(prefs.String(prefThemeMode))
+	myApp.Settings().SetTheme(appTheme)
+	go appTheme.Watch(context.Background(), myApp, time.Second)
 
-func (tm *TaskManager) ToggleTask(id string) {
-	for i, task := range tm.tasks {
-		if task.ID == id {
-			tm.tasks[i].Completed = !tm.tasks[i].Completed
-			if tm.tasks[i].Completed {
-				now := time.Now()
-				tm.tasks[i].CompletedAt = &now
-			} else {
-				tm.tasks[i].CompletedAt = nil
-			}
-			break
+	// Create the main window
+	myWindow := myApp.NewWindow(T("🍒 %s - Native Desktop App", "{{PROJECT_NAME}}"))
+	myWindow.Resize(fyne.NewSize(800, 600))
+	myWindow.CenterOnScreen()
+
+	// Load the tasks from the app's storage directory and build the UI
+	taskManager, err := NewTaskManager(filepath.Join(myApp.Storage().RootURI().Path(), "docs.json"))
+	if err != nil {
+		log.Fatal("Failed to load tasks: ", err)
+	}
+	view := newTaskView(taskManager)
+	view.onError = func(err error) { dialog.ShowError(err, myWindow) }
+	view.window = myWindow
+
+	// Reminders, including the ones that came due while the app was
+	// closed; the overdue and upcoming filters follow the clock
+	reminders, err := NewReminders(taskManager, filepath.Join(myApp.Storage().RootURI().Path(), "reminders.json"), myApp.SendNotification)
+	if err != nil {
+		log.Fatal("Failed to load reminders: ", err)
+	}
+	go reminders.Run(context.Background())
+
+	// Closing the window hides it to the system tray, where reminders keep
+	// coming; the tray shows what's pending, with a badge when tasks are
+	// overdue
+	tray := NewTray(myApp, myWindow, "{{PROJECT_NAME}}", func() []*fyne.MenuItem {
+		return []*fyne.MenuItem{fyne.NewMenuItem(T("🍒 Quick Add Task..."), func() { showQuickAdd(myApp, taskManager) })}
+	})
+	updateTray := func() { tray.SetStatus(trayStatus(taskManager, time.Now())) }
+	updateTray()
+	taskManager.Subscribe(func(TaskChange) { updateTray() })
+	go func() {
+		for range time.Tick(time.Minute) {
+			view.tasks.Refresh()
+			updateTray()
 		}
+	}()
+
+	// Sync with a hub, if one is set up
+	syncBar := newSyncView(taskManager.store, filepath.Join(myApp.Storage().RootURI().Path(), "docs.sync.json"), prefs, myWindow)
+	hub, token := *syncURL, *syncToken
+	if hub == "" {
+		hub = prefs.String(prefSyncURL)
+	}
+	if token == "" {
+		token = prefs.String(prefSyncToken)
+	}
+	if err := syncBar.connect(hub, token, MergeMode(prefs.Int(prefSyncMerge))); err != nil {
+		log.Println("sync disabled:", err)
+	}
+
+	// Keyboard shortcuts and the command palette (Ctrl+K), with the
+	// bindings the user changed
+	actions := append(view.actions(), syncBar.actions()...)
+	actions = append(actions, appTheme.Actions(myApp, themesDir, func(name, mode string) {
+		prefs.SetString(prefTheme, name)
+		prefs.SetString(prefThemeMode, mode)
+	})...)
+	shortcuts, err := NewShortcuts(filepath.Join(myApp.Storage().RootURI().Path(), "shortcuts.json"), actions)
+	if err != nil {
+		log.Fatal("Failed to load shortcuts: ", err)
+	}
+
+	// Set content and show window
+	myWindow.SetMainMenu(fyne.NewMainMenu(taskFileMenu(shortcuts), editMenu(shortcuts)))
+	shortcuts.Attach(myWindow)
+	myWindow.SetContent(container.NewBorder(nil, syncBar.content, nil, nil, view.content))
+
+	// Offer a newer signed release, if the manifest has one; installing it
+	// quits and restarts the app
+	var restartAfterQuit atomic.Bool
+	if *feed != "" && !*noUpdate {
+		if updater, err := NewUpdater(*feed); err != nil {
+			log.Println("self-update disabled:", err)
+		} else {
+			go checkForUpdate(updater, myApp, myWindow, func() { restartAfterQuit.Store(true) })
+		}
+	}
+
+	if *hidden && tray.desk != nil {
+		myApp.Run()
+	} else {
+		myWindow.ShowAndRun()
 	}
-}
 
-func (tm *TaskManager) DeleteTask(id string) {
-	for i, task := range tm.tasks {
-		if task.ID == id {
-			tm.tasks = append(tm.tasks[:i], tm.tasks[i+1:]...)
-			break
+	if restartAfterQuit.Load() {
+		if err := restart(); err != nil {
+			log.Fatal("restart failed, start the app again to run the update: ", err)
 		}
 	}
 }
 
-func (tm *TaskManager) GetTasks() []Task {
-	return tm.tasks
+// taskView is the main window content. Its widgets are fields so tests can
+// drive them with Fyne's test driver. The list, filters, sort order and
+// stats are derived from the task manager's change events, so a change
+// updates only what it affects, whichever device made it.
+type taskView struct {
+	manager *TaskManager
+	tasks   *TaskList
+	stats   binding.String
+
+	// onError reports tasks that couldn't be saved; window holds the
+	// dialogs, e.g. the schedule of a task
+	onError func(error)
+	window  fyne.Window
+
+	input     *shortcutEntry
+	priority  *widget.Select
+	due       *shortcutEntry
+	repeat    *widget.Select
+	addButton *widget.Button
+
+	filterAll       *widget.Button
+	filterPending   *widget.Button
+	filterCompleted *widget.Button
+	filterOverdue   *widget.Button
+	filterUpcoming  *widget.Button
+	sortBy          *widget.Select
+
+	list    *widget.List
+	content fyne.CanvasObject
+
+	// toast is the bar under the list that reports a change, e.g.
+	// "Task deleted", with a button to undo it
+	toast       *fyne.Container
+	toastText   *widget.Label
+	toastAction *widget.Button
+	toastTimer  *time.Timer
 }
 
-func (tm *TaskManager) GetStats() (total, completed, pending int) {
-	total = len(tm.tasks)
-	for _, task := range tm.tasks {
-		if task.Completed {
-			completed++
-		} else {
-			pending++
-		}
+// toastTimeout is how long a toast stays up.
+const toastTimeout = 5 * time.Second
+
+// sortNames labels the TaskSort values in the sort selector, in English;
+// the selector shows their translations.
+var sortNames = []string{"Oldest first", "Newest first", "Priority", "A-Z", "Due date"}
+
+// priorities are the task priorities, lowest first.
+var priorities = []string{"low", "medium", "high"}
+
+// translated returns the translations of names, e.g. for a selector.
+func translated(names []string) []string {
+	out := make([]string, len(names))
+	for i, name := range names {
+		out[i] = T(name)
 	}
-	return
+	return out
 }
 
-func main() {
-	// Create the app
-	myApp := app.NewWithID("com.filecherry.{{PROJECT_NAME}}")
+// newPrioritySelect is a selector of the task priorities, set to medium.
+// Its Selected is translated; selectedPriority reads the priority back.
+func newPrioritySelect() *widget.Select {
+	s := widget.NewSelect(translated(priorities), nil)
+	s.SetSelectedIndex(slices.Index(priorities, "medium"))
+	return s
+}
 
-	// Create the main window
-	myWindow := myApp.NewWindow("🍒 {{PROJECT_NAME}} - Native Desktop App")
-	myWindow.Resize(fyne.NewSize(800, 600))
-	myWindow.CenterOnScreen()
+// selectedPriority returns the priority picked in a newPrioritySelect.
+func selectedPriority(s *widget.Select) string {
+	if i := s.SelectedIndex(); i >= 0 {
+		return priorities[i]
+	}
+	return "medium"
+}
 
-	// Initialize task manager
-	taskManager := NewTaskManager()
+func newTaskView(taskManager *TaskManager) *taskView {
+	v := &taskView{manager: taskManager, onError: func(err error) { log.Println("saving tasks:", err) }}
 
 	// Create UI elements
 	title := widget.NewLabel("🍒 {{PROJECT_NAME}}")
 	title.TextStyle.Bold = true
 	title.Alignment = fyne.TextAlignCenter
 
-	subtitle := widget.NewLabel("Native Go Desktop Application")
+	subtitle := widget.NewLabel(T("Native Go Desktop Application"))
 	subtitle.Alignment = fyne.TextAlignCenter
 
-	// Stats display
-	statsBinding := binding.NewString()
+	// Stats display, kept up to date by the manager's counts
+	v.stats = binding.NewString()
 	updateStats := func() {
-		total, completed, pending := taskManager.GetStats()
-		statsBinding.Set(fmt.Sprintf("📊 Total: %d | ✅ Completed: %d | ⏳ Pending: %d", 
+		total, completed, pending := v.manager.GetStats()
+		v.stats.Set(T("📊 Total: %d | ✅ Completed: %d | ⏳ Pending: %d",
 			total, completed, pending))
 	}
 	updateStats()
+	v.manager.Subscribe(func(TaskChange) { updateStats() })
 
-	statsLabel := widget.NewLabelWithData(statsBinding)
+	statsLabel := widget.NewLabelWithData(v.stats)
 
 	// Task input
-	taskInput := widget.NewEntry()
-	taskInput.SetPlaceHolder("What needs to be done?")
+	v.input = newShortcutEntry()
+	v.input.SetPlaceHolder(T("What needs to be done?"))
 
 	// Priority selector
-	prioritySelect := widget.NewSelect([]string{"low", "medium", "high"}, nil)
-	prioritySelect.SetSelected("medium")
-
-	// Task list container
-	taskList := container.NewVBox()
-
-	// Function to refresh the task list
-	var refreshTaskList func()
-	refreshTaskList = func() {
-		taskList.RemoveAll()
-		for _, task := range taskManager.GetTasks() {
-			taskItem := createTaskItem(task, taskManager, refreshTaskList, updateStats)
-			taskList.Add(taskItem)
-		}
-	}
+	v.priority = newPrioritySelect()
+
+	// Due date and repeat rule; a task with a due date is reminded of when
+	// it comes due
+	v.due = newShortcutEntry()
+	v.due.SetPlaceHolder(T("Due, e.g. tomorrow 17:00"))
+	v.repeat = widget.NewSelect(repeatNames(), nil)
+	v.repeat.SetSelectedIndex(0)
+
+	// Task list: a virtualized list that only creates rows for the visible
+	// tasks and reuses them while scrolling
+	v.tasks = NewTaskList(v.manager, FilterAll, SortOldest)
+	v.list = widget.NewList(
+		v.tasks.Len,
+		func() fyne.CanvasObject { return newTaskRow(v) },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if task, ok := v.tasks.At(id); ok {
+				obj.(*taskRow).bind(task)
+			}
+		},
+	)
+	v.tasks.OnChange(v.list.Refresh)
 
 	// Add task button
-	addButton := widget.NewButton("🍒 Add Task", func() {
-		text := taskInput.Text
-		priority := prioritySelect.Selected
-		if text != "" {
-			taskManager.AddTask(text, priority)
-			taskInput.SetText("")
-			refreshTaskList()
-			updateStats()
+	v.addButton = widget.NewButton(T("🍒 Add Task"), func() {
+		text := v.input.Text
+		priority := selectedPriority(v.priority)
+		if text == "" {
+			return
 		}
-	})
-
-	// Initial task list
-	refreshTaskList()
-
-	// Create scrollable task list
-	scrollContainer := container.NewScroll(taskList)
-	scrollContainer.SetMinSize(fyne.NewSize(0, 300))
-
-	// Filter buttons
-	filterAll := widget.NewButton("All", func() {
-		refreshTaskList()
-	})
-	filterPending := widget.NewButton("Pending", func() {
-		taskList.RemoveAll()
-		for _, task := range taskManager.GetTasks() {
-			if !task.Completed {
-				taskItem := createTaskItem(task, taskManager, refreshTaskList, updateStats)
-				taskList.Add(taskItem)
-			}
+		task := Task{Text: text, Priority: priority, Repeat: repeatRule(v.repeat.Selected)}
+		due, err := parseDue(v.due.Text, time.Now())
+		if err != nil {
+			v.onError(err)
+			return
+		}
+		if due != nil {
+			task.DueAt, task.Reminder = due, "0s"
 		}
+		if err := v.manager.Add(task); err != nil {
+			v.onError(err)
+			return
+		}
+		v.input.SetText("")
+		v.due.SetText("")
+		v.repeat.SetSelectedIndex(0)
 	})
-	filterCompleted := widget.NewButton("Completed", func() {
-		taskList.RemoveAll()
-		for _, task := range taskManager.GetTasks() {
-			if task.Completed {
-				taskItem := createTaskItem(task, taskManager, refreshTaskList, updateStats)
-				taskList.Add(taskItem)
+
+	// Enter in the task input adds the task
+	v.input.OnSubmitted = func(string) { v.addButton.OnTapped() }
+	v.due.OnSubmitted = v.input.OnSubmitted
+
+	// Filter buttons; the active one is highlighted
+	filters := map[TaskFilter]*widget.Button{}
+	setFilter := func(filter TaskFilter) {
+		v.tasks.SetFilter(filter)
+		for f, b := range filters {
+			if f == filter {
+				b.Importance = widget.HighImportance
+			} else {
+				b.Importance = widget.MediumImportance
 			}
+			b.Refresh()
 		}
+	}
+	v.filterAll = widget.NewButton(T("All"), func() { setFilter(FilterAll) })
+	v.filterPending = widget.NewButton(T("Pending"), func() { setFilter(FilterPending) })
+	v.filterCompleted = widget.NewButton(T("Completed"), func() { setFilter(FilterCompleted) })
+	v.filterOverdue = widget.NewButton(T("Overdue"), func() { setFilter(FilterOverdue) })
+	v.filterUpcoming = widget.NewButton(T("Upcoming"), func() { setFilter(FilterUpcoming) })
+	filters[FilterAll], filters[FilterPending], filters[FilterCompleted] = v.filterAll, v.filterPending, v.filterCompleted
+	filters[FilterOverdue], filters[FilterUpcoming] = v.filterOverdue, v.filterUpcoming
+	setFilter(FilterAll)
+
+	v.sortBy = widget.NewSelect(translated(sortNames), func(string) {
+		v.tasks.SetSort(TaskSort(v.sortBy.SelectedIndex()))
 	})
+	v.sortBy.SetSelectedIndex(int(SortOldest))
 
 	filterContainer := container.NewHBox(
-		widget.NewLabel("Filters:"),
-		filterAll,
-		filterPending,
-		filterCompleted,
+		widget.NewLabel(T("Filters:")),
+		v.filterAll,
+		v.filterPending,
+		v.filterCompleted,
+		v.filterOverdue,
+		v.filterUpcoming,
+		layout.NewSpacer(),
+		widget.NewLabel(T("Sort:")),
+		v.sortBy,
 	)
 
 	// Input container
 	inputContainer := container.NewVBox(
-		taskInput,
+		v.input,
 		container.NewHBox(
-			widget.NewLabel("Priority:"),
-			prioritySelect,
-			addButton,
+			widget.NewLabel(T("Priority:")),
+			v.priority,
+			container.NewGridWrap(fyne.NewSize(200, v.due.MinSize().Height), v.due),
+			widget.NewLabel(T("Repeat:")),
+			v.repeat,
+			v.addButton,
 		),
 	)
 
-	// Main content
-	content := container.NewVBox(
+	// Main content; the list takes the remaining height
+	header := container.NewVBox(
 		title,
 		subtitle,
 		widget.NewSeparator(),
@@ -219,55 +374,327 @@ func main() {
 		inputContainer,
 		widget.NewSeparator(),
 		filterContainer,
-		scrollContainer,
 	)
+	v.toastText = widget.NewLabel("")
+	v.toastAction = widget.NewButton("", nil)
+	v.toast = container.NewHBox(v.toastText, layout.NewSpacer(), v.toastAction)
+	v.toast.Hide()
 
-	// Set content and show window
-	myWindow.SetContent(content)
-	myWindow.ShowAndRun()
+	v.content = container.NewBorder(header, v.toast, nil, nil, v.list)
+
+	return v
 }
 
-func createTaskItem(task Task, taskManager *TaskManager, refreshList func(), updateStats func()) *fyne.Container {
-	// Task text
-	taskText := widget.NewLabel(task.Text)
-	if task.Completed {
-		taskText.TextStyle.Italic = true
+// showToast shows text under the list for a few seconds, with a button
+// labelled action that calls fn, if action isn't empty.
+func (v *taskView) showToast(text, action string, fn func()) {
+	v.toastText.SetText(text)
+	v.toastAction.SetText(action)
+	v.toastAction.OnTapped = func() {
+		v.toast.Hide()
+		fn()
+	}
+	if action == "" {
+		v.toastAction.Hide()
+	} else {
+		v.toastAction.Show()
 	}
+	v.toast.Show()
+	if v.toastTimer != nil {
+		v.toastTimer.Stop()
+	}
+	v.toastTimer = time.AfterFunc(toastTimeout, v.toast.Hide)
+}
 
-	// Priority indicator
-	priorityLabel := widget.NewLabel(getPriorityIcon(task.Priority))
+// undo reverts the last change and offers to redo it.
+func (v *taskView) undo() {
+	name, err := v.manager.Undo()
+	switch {
+	case err != nil:
+		v.onError(err)
+	case name == "":
+		v.showToast(T("Nothing to undo"), "", nil)
+	default:
+		v.showToast(T("↩️ Undone: %s", T(name)), T("Redo"), v.redo)
+	}
+}
+
+// redo repeats the last undone change and offers to undo it again.
+func (v *taskView) redo() {
+	name, err := v.manager.Redo()
+	switch {
+	case err != nil:
+		v.onError(err)
+	case name == "":
+		v.showToast(T("Nothing to redo"), "", nil)
+	default:
+		v.showToast(T("↪️ Redone: %s", T(name)), T("Undo"), v.undo)
+	}
+}
+
+// Default shortcuts of undo and redo
+var (
+	shortcutUndo = ctrl(fyne.KeyZ)
+	shortcutRedo = ctrl(fyne.KeyZ, fyne.KeyModifierShift)
+)
+
+// actions are the commands of the task view, for the shortcuts, the
+// command palette and the menus.
+func (v *taskView) actions() []*Action {
+	actions := []*Action{
+		{ID: "task.new", Name: T("🍒 New Task"), Shortcut: ctrl(fyne.KeyN), Run: func() {
+			if v.window != nil {
+				v.window.Canvas().Focus(v.input)
+			}
+		}},
+		{ID: "edit.undo", Name: T("↩️ Undo"), Shortcut: shortcutUndo, Run: v.undo},
+		{ID: "edit.redo", Name: T("↪️ Redo"), Shortcut: shortcutRedo, Run: v.redo},
+		{ID: "file.import", Name: T("📥 Import Tasks..."), Shortcut: ctrl(fyne.KeyO), Run: func() { showImport(v.manager, v.window) }},
+		{ID: "file.export", Name: T("📤 Export Tasks..."), Shortcut: ctrl(fyne.KeyE), Run: func() { showExport(v.manager, v.window) }},
+	}
+	// Ctrl+1 to Ctrl+5 pick a filter
+	for i, b := range []*widget.Button{v.filterAll, v.filterPending, v.filterCompleted, v.filterOverdue, v.filterUpcoming} {
+		actions = append(actions, &Action{
+			ID:       "filter." + []string{"all", "pending", "completed", "overdue", "upcoming"}[i],
+			Name:     T("🔍 Show %s", b.Text),
+			Shortcut: ctrl(fyne.KeyName(strconv.Itoa(i + 1))),
+			Run:      b.OnTapped,
+		})
+	}
+	for i, name := range sortNames {
+		i := i
+		actions = append(actions, &Action{
+			ID:   "sort." + strings.ReplaceAll(strings.ToLower(name), " ", "-"),
+			Name: T("↕️ Sort by %s", T(name)),
+			Run:  func() { v.sortBy.SetSelectedIndex(i) },
+		})
+	}
+	return actions
+}
+
+// editMenu is the Edit menu of the main window.
+func editMenu(shortcuts *Shortcuts) *fyne.Menu {
+	return fyne.NewMenu(T("Edit"),
+		shortcuts.MenuItem("edit.undo"),
+		shortcuts.MenuItem("edit.redo"),
+		fyne.NewMenuItemSeparator(),
+		shortcuts.MenuItem(actionPalette),
+		shortcuts.MenuItem(actionShortcuts),
+	)
+}
+
+// showSchedule edits a task's due date, repeat rule and reminder.
+func (v *taskView) showSchedule(task Task) {
+	due := widget.NewEntry()
+	due.SetPlaceHolder(T("e.g. tomorrow 17:00, or empty for none"))
+	if task.DueAt != nil {
+		due.SetText(task.DueAt.Format("2006-01-02 15:04"))
+	}
+	repeat := widget.NewSelectEntry(repeatNames())
+	repeat.SetText(T("Never"))
+	if task.Repeat != "" {
+		repeat.SetText(describeRepeat(task.Repeat))
+	}
+	// A reminder imported from elsewhere may not be one of the choices
+	choices := reminderChoices
+	if !slices.ContainsFunc(choices, func(c reminderChoice) bool { return c.lead == task.Reminder }) {
+		choices = append(slices.Clip(choices), reminderChoice{T("%s before", task.Reminder), task.Reminder})
+	}
+	reminder := widget.NewSelect(nil, nil)
+	for i, c := range choices {
+		reminder.Options = append(reminder.Options, T(c.name))
+		if c.lead == task.Reminder {
+			reminder.SetSelectedIndex(i)
+		}
+	}
+
+	items := []*widget.FormItem{
+		widget.NewFormItem(T("Due"), due),
+		widget.NewFormItem(T("Repeat"), repeat),
+		widget.NewFormItem(T("Remind me"), reminder),
+	}
+	items[1].HintText = T("A preset, or an RRULE like FREQ=WEEKLY;INTERVAL=2;BYDAY=MO")
+	dialog.ShowForm("📅 "+task.Text, T("Save"), T("Cancel"), items, func(save bool) {
+		if !save {
+			return
+		}
+		at, err := parseDue(due.Text, time.Now())
+		if err == nil {
+			err = v.manager.SetSchedule(task.ID, at, repeatRule(repeat.Text), choices[reminder.SelectedIndex()].lead)
+		}
+		if err != nil {
+			v.onError(err)
+		}
+	}, v.window)
+}
+
+// showQuickAdd opens a small window to add a task without the main
+// window, e.g. from the tray.
+func showQuickAdd(a fyne.App, tm *TaskManager) fyne.Window {
+	w := a.NewWindow(T("🍒 Quick Add Task"))
+	input := widget.NewEntry()
+	input.SetPlaceHolder(T("What needs to be done?"))
+	priority := newPrioritySelect()
+
+	add := func() {
+		text := strings.TrimSpace(input.Text)
+		if text == "" {
+			return
+		}
+		if err := tm.Add(Task{Text: text, Priority: selectedPriority(priority)}); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		w.Close()
+	}
+	input.OnSubmitted = func(string) { add() }
+
+	w.SetContent(container.NewVBox(
+		input,
+		container.NewHBox(
+			widget.NewLabel(T("Priority:")),
+			priority,
+			layout.NewSpacer(),
+			widget.NewButton(T("Cancel"), w.Close),
+			widget.NewButton(T("🍒 Add Task"), add),
+		),
+	))
+	w.Resize(fyne.NewSize(420, w.Content().MinSize().Height))
+	w.CenterOnScreen()
+	w.Show()
+	w.Canvas().Focus(input)
+	return w
+}
+
+// trayStatus is the status line of the tray menu, and whether tasks are
+// overdue, which puts a badge on the tray icon.
+func trayStatus(tm *TaskManager, now time.Time) (string, bool) {
+	_, _, pending := tm.GetStats()
+	overdue := 0
+	for _, task := range tm.GetTasks() {
+		if task.overdue(now) {
+			overdue++
+		}
+	}
+	status := T("⏳ %d pending", pending)
+	if overdue > 0 {
+		status += " • " + T("⚠️ %d overdue", overdue)
+	}
+	return status, overdue > 0
+}
+
+// checkForUpdate asks whether to install a newer release and, if so,
+// installs it, calls restartNext and quits the app.
+func checkForUpdate(updater *Updater, myApp fyne.App, window fyne.Window, restartNext func()) {
+	current := buildVersion().Version
+	if current == "dev" {
+		return
+	}
+	rel, asset, err := updater.Check(context.Background())
+	if err != nil {
+		log.Println("update check failed:", err)
+		return
+	}
+	if rel == nil {
+		return
+	}
+
+	message := T("Version %s is available (you have %s).\nInstall it and restart now?", rel.Version, current)
+	dialog.ShowConfirm(T("🍒 Update available"), message, func(install bool) {
+		if !install {
+			return
+		}
+		progress := dialog.NewCustomWithoutButtons(T("Updating to %s", rel.Version), widget.NewProgressBarInfinite(), window)
+		progress.Show()
+		go func() {
+			err := updater.Apply(context.Background(), rel, asset)
+			progress.Hide()
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			restartNext()
+			myApp.Quit()
+		}()
+	}, window)
+}
+
+// taskRow is one row of the task list. The list reuses rows while
+// scrolling, so bind points a row at another task.
+type taskRow struct {
+	widget.BaseWidget
+	id       string
+	priority *widget.Label
+	text     *widget.Label
+	created  *widget.Label
+	due      *widget.Label
+	schedule *widget.Button
+	toggle   *widget.Button
+	remove   *widget.Button
+}
+
+func newTaskRow(v *taskView) *taskRow {
+	r := &taskRow{
+		priority: widget.NewLabel(""),
+		text:     widget.NewLabel(""),
+		created:  widget.NewLabel(""),
+		due:      widget.NewLabel(""),
+	}
+	r.created.TextStyle.Italic = true
+
+	// Schedule button: due date, repeat and reminder
+	r.schedule = widget.NewButton("📅", func() {
+		if task, ok := v.manager.Task(r.id); ok {
+			v.showSchedule(task)
+		}
+	})
 
 	// Toggle button
-	toggleText := "✅"
-	if !task.Completed {
-		toggleText = "⭕"
-	}
-	toggleButton := widget.NewButton(toggleText, func() {
-		taskManager.ToggleTask(task.ID)
-		refreshList()
-		updateStats()
+	r.toggle = widget.NewButton("⭕", func() {
+		if err := v.manager.ToggleTask(r.id); err != nil {
+			v.onError(err)
+		}
 	})
 
-	// Delete button
-	deleteButton := widget.NewButton("🗑️", func() {
-		taskManager.DeleteTask(task.ID)
-		refreshList()
-		updateStats()
+	// Delete button; there is no confirmation, as the toast offers undo
+	r.remove = widget.NewButton("🗑️", func() {
+		if err := v.manager.DeleteTask(r.id); err != nil {
+			v.onError(err)
+			return
+		}
+		v.showToast(T("🗑️ Task deleted"), T("Undo"), v.undo)
 	})
+	r.ExtendBaseWidget(r)
+	return r
+}
 
-	// Created time
-	timeLabel := widget.NewLabel(formatTime(task.CreatedAt))
-	timeLabel.TextStyle.Italic = true
+func (r *taskRow) bind(task Task) {
+	r.id = task.ID
+	r.priority.SetText(getPriorityIcon(task.Priority))
+	r.text.TextStyle.Italic = task.Completed
+	r.text.SetText(task.Text)
+	r.created.SetText(FormatDateTime(task.CreatedAt))
+	r.due.SetText(formatDue(task, time.Now()))
+	if task.overdue(time.Now()) {
+		r.due.Importance = widget.DangerImportance
+	} else {
+		r.due.Importance = widget.MediumImportance
+	}
+	r.due.Refresh()
+	if task.Completed {
+		r.toggle.SetText("✅")
+	} else {
+		r.toggle.SetText("⭕")
+	}
+}
 
-	// Task item container
-	taskItem := container.NewBorder(
+func (r *taskRow) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewBorder(
 		nil, nil,
-		container.NewHBox(priorityLabel, taskText),
-		container.NewHBox(toggleButton, deleteButton),
-		timeLabel,
-	)
-
-	return taskItem
+		container.NewHBox(r.priority, r.text),
+		container.NewHBox(r.due, r.schedule, r.toggle, r.remove),
+		r.created,
+	))
 }
 
 func getPriorityIcon(priority string) string {
@@ -282,7 +709,3 @@ func getPriorityIcon(priority string) string {
 		return "⚪"
 	}
 }
-
-func formatTime(t time.Time) string {
-	return t.Format("Jan 2, 3:04 PM")
-}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+)
+
+// newTestManager returns a task manager that saves to a temporary file.
+func newTestManager(t *testing.T) *TaskManager {
+	t.Helper()
+	tm, err := NewTaskManager(filepath.Join(t.TempDir(), "docs.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tm
+}
+
+func TestTaskManagerAddTask(t *testing.T) {
+	tm := newTestManager(t)
+	before := len(tm.GetTasks())
+
+	tm.AddTask("Write tests", "high")
+
+	tasks := tm.GetTasks()
+	if len(tasks) != before+1 {
+		t.Fatalf("got %d tasks, want %d", len(tasks), before+1)
+	}
+	added := tasks[len(tasks)-1]
+	if added.Text != "Write tests" || added.Priority != "high" || added.Completed {
+		t.Errorf("added task = %+v", added)
+	}
+	if added.CreatedAt.IsZero() {
+		t.Error("CreatedAt not set")
+	}
+}
+
+func TestTaskManagerToggleTask(t *testing.T) {
+	tm := newTestManager(t)
+	id := tm.GetTasks()[0].ID
+
+	tm.ToggleTask(id)
+	if task := tm.GetTasks()[0]; !task.Completed || task.CompletedAt == nil {
+		t.Fatalf("after toggle: completed=%v completedAt=%v", task.Completed, task.CompletedAt)
+	}
+
+	tm.ToggleTask(id)
+	if task := tm.GetTasks()[0]; task.Completed || task.CompletedAt != nil {
+		t.Fatalf("after second toggle: completed=%v completedAt=%v", task.Completed, task.CompletedAt)
+	}
+}
+
+func TestTaskManagerDeleteTask(t *testing.T) {
+	tm := newTestManager(t)
+	tasks := tm.GetTasks()
+	id := tasks[0].ID
+	before := len(tasks)
+
+	tm.DeleteTask(id)
+	tm.DeleteTask("no-such-task")
+
+	if got := len(tm.GetTasks()); got != before-1 {
+		t.Fatalf("got %d tasks, want %d", got, before-1)
+	}
+	for _, task := range tm.GetTasks() {
+		if task.ID == id {
+			t.Fatalf("task %s still present", id)
+		}
+	}
+}
+
+func TestTaskManagerGetStats(t *testing.T) {
+	tm := newTestManager(t)
+	tm.AddTask("one", "low")
+	tm.ToggleTask(tm.GetTasks()[0].ID)
+
+	total, completed, pending := tm.GetStats()
+	if total != len(tm.GetTasks()) || completed != 1 || pending != total-1 {
+		t.Errorf("stats = %d/%d/%d", total, completed, pending)
+	}
+}
+
+func TestTaskManagerPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "docs.json")
+	tm, err := NewTaskManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm.AddTask("Survive a restart", "low")
+	tm.ToggleTask(tm.GetTasks()[0].ID)
+	tm.DeleteTask(tm.GetTasks()[1].ID)
+
+	reopened, err := NewTaskManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, got := tm.GetTasks(), reopened.GetTasks()
+	if len(got) != len(want) {
+		t.Fatalf("reopened %d tasks, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].Text != want[i].Text || got[i].Completed != want[i].Completed {
+			t.Errorf("task %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	data, _ := os.ReadFile(path)
+	var file docFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != docStoreVersion {
+		t.Errorf("file version = %d (%v), want %d", file.Version, err, docStoreVersion)
+	}
+	for _, entry := range file.Docs {
+		if entry.Doc["type"] != "todo" || entry.Doc["created"] == nil {
+			t.Errorf("task not stored as a todo document: %v", entry.Doc)
+		}
+	}
+}
+
+func TestTaskManagerMigratesTasksFile(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `{"version": 1, "nextId": 3, "tasks": [
+		{"id": "1", "text": "old task", "completed": true, "priority": "low", "createdAt": "2025-01-02T03:04:05Z", "completedAt": "2025-01-03T00:00:00Z"},
+		{"id": "2", "text": "another", "priority": "high", "createdAt": "2025-01-02T03:04:06Z"}]}`
+	os.WriteFile(filepath.Join(dir, "tasks.json"), []byte(legacy), 0644)
+
+	tm, err := NewTaskManager(filepath.Join(dir, "docs.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks := tm.GetTasks()
+	if len(tasks) != 2 || tasks[0].Text != "old task" || !tasks[0].Completed || tasks[0].CompletedAt == nil || tasks[1].Priority != "high" {
+		t.Fatalf("migrated tasks = %+v", tasks)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tasks.json.migrated")); err != nil {
+		t.Errorf("tasks.json not renamed: %v", err)
+	}
+}
+
+func TestTaskManagerUniqueIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "docs.json")
+	tm, _ := NewTaskManager(path)
+	tm.DeleteTask(tm.GetTasks()[0].ID)
+	tm.AddTask("a", "low")
+	tm, _ = NewTaskManager(path)
+	tm.AddTask("b", "low")
+
+	seen := map[string]bool{}
+	for _, task := range tm.GetTasks() {
+		if seen[task.ID] {
+			t.Fatalf("duplicate ID %s in %+v", task.ID, tm.GetTasks())
+		}
+		seen[task.ID] = true
+	}
+
+	// Toggling the newest task must not touch any other
+	last := tm.GetTasks()[len(tm.GetTasks())-1]
+	tm.ToggleTask(last.ID)
+	if _, completed, _ := tm.GetStats(); completed != 1 {
+		t.Errorf("toggling %s completed %d tasks, want 1", last.ID, completed)
+	}
+}
+
+func TestTaskManagerNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "docs.json")
+	os.WriteFile(path, []byte(`{"version": 99, "docs": []}`), 0644)
+
+	if _, err := NewTaskManager(path); err == nil {
+		t.Fatal("loaded a file from a newer schema version")
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), `"version": 99`) {
+		t.Error("file from a newer version was overwritten")
+	}
+}
+
+func TestTaskManagerDamagedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "docs.json")
+	os.WriteFile(path, []byte(`{"docs": [`), 0644)
+
+	tm, err := NewTaskManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tm.GetTasks()) == 0 {
+		t.Error("no welcome tasks after a damaged file")
+	}
+	if aside, _ := filepath.Glob(path + ".corrupt-*"); len(aside) != 1 {
+		t.Errorf("damaged file not kept: %v", aside)
+	}
+}
+
+func TestGetPriorityIcon(t *testing.T) {
+	for priority, want := range map[string]string{"high": "🔴", "medium": "🟡", "low": "🟢", "": "⚪"} {
+		if got := getPriorityIcon(priority); got != want {
+			t.Errorf("getPriorityIcon(%q) = %q, want %q", priority, got, want)
+		}
+	}
+}
+
+// newTestView shows a task view in a window of the headless test app.
+func newTestView(t *testing.T) *taskView {
+	t.Helper()
+	a := test.NewApp()
+	t.Cleanup(a.Quit)
+
+	view := newTaskView(newTestManager(t))
+	w := test.NewWindow(view.content)
+	w.Resize(fyne.NewSize(800, 600))
+	view.window = w
+	t.Cleanup(w.Close)
+	return view
+}
+
+// findRow returns the visible list row showing text, or nil.
+func findRow(view *taskView, text string) *taskRow {
+	for _, o := range test.LaidOutObjects(view.list) {
+		if row, ok := o.(*taskRow); ok && row.Visible() && row.text.Text == text {
+			return row
+		}
+	}
+	return nil
+}
+
+func TestViewAddTask(t *testing.T) {
+	view := newTestView(t)
+	before := view.list.Length()
+
+	test.Type(view.input, "Buy cherries")
+	view.priority.SetSelected("high")
+	test.Tap(view.addButton)
+
+	if got := view.list.Length(); got != before+1 {
+		t.Fatalf("list shows %d tasks, want %d", got, before+1)
+	}
+	if findRow(view, "Buy cherries") == nil {
+		t.Error("no row for the new task")
+	}
+	if view.input.Text != "" {
+		t.Errorf("input not cleared: %q", view.input.Text)
+	}
+	if task := view.manager.GetTasks()[before]; task.Text != "Buy cherries" || task.Priority != "high" {
+		t.Errorf("added task = %+v", task)
+	}
+	stats, _ := view.stats.Get()
+	if !strings.Contains(stats, "Total: 3") {
+		t.Errorf("stats = %q", stats)
+	}
+}
+
+func TestViewIgnoresEmptyTask(t *testing.T) {
+	view := newTestView(t)
+	before := len(view.manager.GetTasks())
+
+	test.Tap(view.addButton)
+
+	if got := len(view.manager.GetTasks()); got != before {
+		t.Fatalf("empty input added a task: %d tasks, want %d", got, before)
+	}
+}
+
+func TestViewToggleAndFilter(t *testing.T) {
+	view := newTestView(t)
+	first := view.manager.GetTasks()[0]
+
+	test.Tap(findRow(view, first.Text).toggle)
+	if !view.manager.GetTasks()[0].Completed {
+		t.Fatal("tapping ⭕ did not complete the task")
+	}
+	if row := findRow(view, first.Text); row.toggle.Text != "✅" || !row.text.TextStyle.Italic {
+		t.Error("row not updated after completing the task")
+	}
+	stats, _ := view.stats.Get()
+	if !strings.Contains(stats, "Completed: 1") {
+		t.Errorf("stats = %q", stats)
+	}
+
+	test.Tap(view.filterCompleted)
+	if got := view.list.Length(); got != 1 {
+		t.Errorf("completed filter shows %d tasks, want 1", got)
+	}
+	test.Tap(view.filterPending)
+	if got := view.list.Length(); got != 1 {
+		t.Errorf("pending filter shows %d tasks, want 1", got)
+	}
+	// The filter stays while tasks change
+	view.manager.AddTask("Another one", "low")
+	if got := view.list.Length(); got != 2 {
+		t.Errorf("pending filter shows %d tasks after adding one, want 2", got)
+	}
+	test.Tap(view.filterAll)
+	if got := view.list.Length(); got != 3 {
+		t.Errorf("all filter shows %d tasks, want 3", got)
+	}
+}
+
+func TestViewDeleteTask(t *testing.T) {
+	view := newTestView(t)
+
+	test.Tap(findRow(view, view.manager.GetTasks()[0].Text).remove)
+
+	if got := view.list.Length(); got != 1 {
+		t.Fatalf("list shows %d tasks after delete, want 1", got)
+	}
+	if got := len(view.manager.GetTasks()); got != 1 {
+		t.Fatalf("manager has %d tasks after delete, want 1", got)
+	}
+}
+
+func TestViewUndoDelete(t *testing.T) {
+	view := newTestView(t)
+	shortcuts, _ := NewShortcuts("", view.actions())
+	shortcuts.Attach(view.window)
+	text := view.manager.GetTasks()[0].Text
+
+	test.Tap(findRow(view, text).remove)
+	if !view.toast.Visible() || view.toastText.Text != "🗑️ Task deleted" || view.toastAction.Text != "Undo" {
+		t.Fatalf("toast = %q %q", view.toastText.Text, view.toastAction.Text)
+	}
+	test.Tap(view.toastAction)
+	if view.list.Length() != 2 || findRow(view, text) == nil {
+		t.Fatal("Undo did not bring the task back")
+	}
+	if view.toastAction.Text != "Redo" {
+		t.Errorf("toast after undo offers %q", view.toastAction.Text)
+	}
+
+	// Ctrl+Shift+Z and Ctrl+Z, also while typing a task
+	canvas := view.window.Canvas().(fyne.Shortcutable)
+	canvas.TypedShortcut(shortcutRedo)
+	if view.list.Length() != 1 {
+		t.Errorf("Ctrl+Shift+Z left %d tasks, want 1", view.list.Length())
+	}
+	test.Type(view.input, "half typed")
+	view.input.TypedShortcut(shortcutUndo)
+	if view.list.Length() != 2 {
+		t.Errorf("Ctrl+Z in the entry left %d tasks, want 2", view.list.Length())
+	}
+}
+
+func TestViewSort(t *testing.T) {
+	view := newTestView(t)
+	view.manager.AddTask("a low one", "low")
+
+	first := func() string {
+		task, _ := view.tasks.At(0)
+		return task.Text
+	}
+	view.sortBy.SetSelected("Newest first")
+	if first() != "a low one" {
+		t.Errorf("newest first starts with %q", first())
+	}
+	view.sortBy.SetSelected("A-Z")
+	if first() != "a low one" {
+		t.Errorf("A-Z starts with %q", first())
+	}
+	view.sortBy.SetSelected("Priority")
+	if task, _ := view.tasks.At(0); task.Priority != "high" {
+		t.Errorf("priority order starts with %+v", task)
+	}
+}
+
+func TestViewDueDate(t *testing.T) {
+	view := newTestView(t)
+
+	test.Type(view.input, "Pay rent")
+	test.Type(view.due, "tomorrow 17:00")
+	view.repeat.SetSelected("Monthly")
+	test.Tap(view.addButton)
+
+	var task Task
+	for _, task = range view.manager.GetTasks() {
+		if task.Text == "Pay rent" {
+			break
+		}
+	}
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	if task.DueAt == nil || task.DueAt.Day() != tomorrow.Day() || task.DueAt.Hour() != 17 || task.Repeat != "FREQ=MONTHLY" || task.Reminder != "0s" {
+		t.Fatalf("added task = %+v", task)
+	}
+	if view.due.Text != "" || view.repeat.Selected != "Never" {
+		t.Error("due date and repeat not reset")
+	}
+	if row := findRow(view, "Pay rent"); row == nil || !strings.HasPrefix(row.due.Text, "📅") || !strings.HasSuffix(row.due.Text, "🔁") {
+		t.Errorf("row shows no due date")
+	}
+
+	test.Tap(view.filterUpcoming)
+	if got := view.list.Length(); got != 1 {
+		t.Errorf("upcoming filter shows %d tasks, want 1", got)
+	}
+	test.Tap(view.filterOverdue)
+	if got := view.list.Length(); got != 0 {
+		t.Errorf("overdue filter shows %d tasks, want 0", got)
+	}
+
+	// A due date that can't be read is reported and keeps the input
+	var reported error
+	view.onError = func(err error) { reported = err }
+	test.Type(view.input, "Later")
+	test.Type(view.due, "someday 9:00")
+	test.Tap(view.addButton)
+	if reported == nil || view.input.Text != "Later" {
+		t.Errorf("reported %v, input %q", reported, view.input.Text)
+	}
+}
+
+func TestViewSync(t *testing.T) {
+	view := newTestView(t)
+	hub := newTestHub(t)
+	hub.store.Put(taskToDoc(Task{ID: "from-hub", Text: "Added on the hub", Priority: "low", CreatedAt: time.Now()}))
+
+	bar := newSyncView(view.manager.store, filepath.Join(t.TempDir(), "docs.sync.json"), fyne.CurrentApp().Preferences(), test.NewWindow(nil))
+	if err := bar.connect(hub.server.URL, "", MergeFields); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bar.connect("", "", MergeFields) })
+
+	// Pulled tasks show up without a refresh
+	deadline := time.Now().Add(5 * time.Second)
+	for view.list.Length() != 3 || bar.replicator.Pending() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("list shows %d tasks, want 3", view.list.Length())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stats, _ := view.stats.Get()
+	if !strings.Contains(stats, "Total: 3") {
+		t.Errorf("stats = %q", stats)
+	}
+	// The welcome tasks went the other way
+	if n := len(hub.store.Query("type", taskType)); n != 3 {
+		t.Errorf("hub has %d tasks, want 3", n)
+	}
+}
+
+func TestTaskListFollowsChanges(t *testing.T) {
+	tm, err := NewTaskManager("") // in memory; saving 2000 tasks per change is slow
+	if err != nil {
+		t.Fatal(err)
+	}
+	var docs []Doc
+	for i := 0; i < 2000; i++ {
+		docs = append(docs, taskToDoc(Task{
+			ID:        fmt.Sprintf("task-%04d", i),
+			Text:      fmt.Sprintf("Task %d", (i*7919)%2000),
+			Priority:  []string{"low", "medium", "high"}[i%3],
+			Completed: i%4 == 0,
+			CreatedAt: time.UnixMilli(int64(1700000000000 + (i*104729)%2000*1000)),
+		}))
+	}
+	tm.store.Bulk(docs)
+
+	var lists []*TaskList
+	for _, filter := range []TaskFilter{FilterAll, FilterPending, FilterCompleted} {
+		for sort := range sortNames {
+			lists = append(lists, NewTaskList(tm, filter, TaskSort(sort)))
+		}
+	}
+	for i := 0; i < 2000; i += 7 {
+		id := fmt.Sprintf("task-%04d", i)
+		if i%3 == 0 {
+			tm.DeleteTask(id)
+		} else {
+			tm.ToggleTask(id)
+		}
+	}
+	tm.AddTask("One more", "high")
+
+	// Every list kept incrementally matches one built from scratch
+	for _, l := range lists {
+		fresh := NewTaskList(tm, l.filter, l.sort)
+		if l.Len() != fresh.Len() {
+			t.Fatalf("filter %d sort %d: %d tasks, want %d", l.filter, l.sort, l.Len(), fresh.Len())
+		}
+		for i := 0; i < l.Len(); i++ {
+			got, _ := l.At(i)
+			want, _ := fresh.At(i)
+			if got != want {
+				t.Fatalf("filter %d sort %d: task %d is %+v, want %+v", l.filter, l.sort, i, got, want)
+			}
+		}
+		fresh.Close()
+	}
+	total, completed, pending := tm.GetStats()
+	if all := lists[0].Len(); total != all || completed+pending != total || completed != lists[len(lists)-1].Len() {
+		t.Errorf("stats %d/%d/%d do not match the lists", total, completed, pending)
+	}
+}
+
+// findButton returns the button labelled text inside obj.
+func findButton(t *testing.T, obj fyne.CanvasObject, text string) *widget.Button {
+	t.Helper()
+	var walk func(fyne.CanvasObject) *widget.Button
+	walk = func(o fyne.CanvasObject) *widget.Button {
+		switch o := o.(type) {
+		case *widget.Button:
+			if o.Text == text {
+				return o
+			}
+		case *fyne.Container:
+			for _, child := range o.Objects {
+				if b := walk(child); b != nil {
+					return b
+				}
+			}
+		}
+		return nil
+	}
+	b := walk(obj)
+	if b == nil {
+		t.Fatalf("no %q button", text)
+	}
+	return b
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// testHub serves the replication endpoints of the Go + Gin template from
+// a DocStore; offline makes every request fail like a dropped connection.
+type testHub struct {
+	store   *DocStore
+	server  *httptest.Server
+	offline atomic.Bool
+}
+
+func newTestHub(t *testing.T) *testHub {
+	t.Helper()
+	store, _ := openTestStore(t)
+	h := &testHub{store: store}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/docs/changes", func(w http.ResponseWriter, r *http.Request) {
+		since, _ := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+		changes, seq := store.Changes(since)
+		json.NewEncoder(w).Encode(ChangesResponse{Changes: changes, Seq: seq})
+	})
+	mux.HandleFunc("/api/docs/push", func(w http.ResponseWriter, r *http.Request) {
+		var req PushRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		rejected, err := store.Apply(req.Changes)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(PushResult{Rejected: rejected})
+	})
+	h.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.offline.Load() {
+			panic(http.ErrAbortHandler)
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(h.server.Close)
+	return h
+}
+
+// newTestReplica opens an empty store replicating with hub.
+func newTestReplica(t *testing.T, hub *testHub, mode MergeMode) (*DocStore, *Replicator) {
+	t.Helper()
+	store, path := openTestStore(t)
+	r, err := NewReplicator(store, hub.server.URL, filepath.Join(filepath.Dir(path), "docs.sync.json"), mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, r
+}
+
+func syncAll(t *testing.T, replicas ...*Replicator) {
+	t.Helper()
+	for _, r := range replicas {
+		if err := r.Sync(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReplicationSync(t *testing.T) {
+	hub := newTestHub(t)
+	a, ra := newTestReplica(t, hub, MergeFields)
+	b, rb := newTestReplica(t, hub, MergeFields)
+
+	a.Bulk([]Doc{{"_id": "1", "type": "todo", "text": "one"}, {"_id": "2", "type": "todo", "text": "two"}})
+	syncAll(t, ra, rb)
+	if docs := b.Query("type", "todo"); len(docs) != 2 {
+		t.Fatalf("b has %v", docs)
+	}
+	if a.Version("1").Rev != b.Version("1").Rev {
+		t.Error("replicas have different revisions of the same version")
+	}
+
+	b.Del("1")
+	b.Put(Doc{"_id": "2", "type": "todo", "text": "two!"})
+	syncAll(t, rb, ra)
+	if _, err := a.Get("1"); err == nil {
+		t.Error("delete did not replicate")
+	}
+	if doc, _ := a.Get("2"); doc["text"] != "two!" {
+		t.Errorf("a has %v", doc)
+	}
+	if ra.Pending() != 0 || rb.Pending() != 0 || len(ra.Conflicts()) != 0 {
+		t.Errorf("pending %d/%d, conflicts %v", ra.Pending(), rb.Pending(), ra.Conflicts())
+	}
+}
+
+func TestReplicationOffline(t *testing.T) {
+	hub := newTestHub(t)
+	a, ra := newTestReplica(t, hub, MergeFields)
+
+	hub.offline.Store(true)
+	a.Put(Doc{"_id": "1", "text": "written offline"})
+	if err := ra.Sync(context.Background()); err == nil {
+		t.Fatal("sync with an unreachable hub succeeded")
+	}
+	if st := ra.Status(); st.Online || st.Pending != 1 {
+		t.Errorf("status while offline = %+v", st)
+	}
+
+	// The queue survives a restart
+	path := ra.path
+	ra, err := NewReplicator(a, hub.server.URL, path, MergeFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub.offline.Store(false)
+	syncAll(t, ra)
+	if _, err := hub.store.Get("1"); err != nil {
+		t.Errorf("queued change did not reach the hub: %v", err)
+	}
+	if st := ra.Status(); !st.Online || st.Pending != 0 || st.LastSync.IsZero() {
+		t.Errorf("status after reconnecting = %+v", st)
+	}
+}
+
+func TestReplicationMergeFields(t *testing.T) {
+	hub := newTestHub(t)
+	a, ra := newTestReplica(t, hub, MergeFields)
+	b, rb := newTestReplica(t, hub, MergeFields)
+	a.Put(Doc{"_id": "1", "text": "buy milk", "done": false, "priority": "low"})
+	syncAll(t, ra, rb)
+
+	// Different fields merge without a conflict
+	a.Put(Doc{"_id": "1", "text": "buy oat milk", "done": false, "priority": "low"})
+	b.Put(Doc{"_id": "1", "text": "buy milk", "done": true, "priority": "low"})
+	syncAll(t, ra, rb, ra)
+	for _, s := range []*DocStore{a, b, hub.store} {
+		if doc, _ := s.Get("1"); doc["text"] != "buy oat milk" || doc["done"] != true {
+			t.Errorf("merged doc = %v", doc)
+		}
+	}
+	if len(rb.Conflicts()) != 0 {
+		t.Errorf("conflicts = %+v", rb.Conflicts())
+	}
+
+	// The same field: the later edit wins and the collision is reported
+	a.Put(Doc{"_id": "1", "text": "from a", "done": true, "priority": "low"})
+	b.Put(Doc{"_id": "1", "text": "from b", "done": true, "priority": "high"})
+	syncAll(t, ra, rb, ra)
+	doc, _ := a.Get("1")
+	if doc["text"] != "from b" || doc["priority"] != "high" {
+		t.Errorf("merged doc = %v", doc)
+	}
+	conflicts := rb.Conflicts()
+	if len(conflicts) != 1 || !slices.Equal(conflicts[0].Fields, []string{"text"}) || conflicts[0].Remote["text"] != "from a" {
+		t.Fatalf("conflicts = %+v", conflicts)
+	}
+
+	// Keeping the other version replicates it
+	if err := rb.Resolve("1", conflicts[0].Remote); err != nil {
+		t.Fatal(err)
+	}
+	syncAll(t, rb, ra)
+	if doc, _ := a.Get("1"); doc["text"] != "from a" || doc["priority"] != "low" {
+		t.Errorf("resolved doc = %v", doc)
+	}
+	if len(rb.Conflicts()) != 0 {
+		t.Error("conflict still listed after Resolve")
+	}
+}
+
+func TestReplicationLWW(t *testing.T) {
+	hub := newTestHub(t)
+	a, ra := newTestReplica(t, hub, MergeLWW)
+	b, rb := newTestReplica(t, hub, MergeLWW)
+	a.Put(Doc{"_id": "1", "text": "milk", "done": false})
+	syncAll(t, ra, rb)
+
+	a.Put(Doc{"_id": "1", "text": "oat milk", "done": false})
+	b.Put(Doc{"_id": "1", "text": "milk", "done": true})
+	syncAll(t, ra, rb, ra)
+	if doc, _ := a.Get("1"); doc["text"] != "milk" || doc["done"] != true {
+		t.Errorf("doc = %v, want b's later version", doc)
+	}
+	if c := rb.Conflicts(); len(c) != 1 || !slices.Equal(c[0].Fields, []string{"done", "text"}) {
+		t.Errorf("conflicts = %+v", c)
+	}
+}
+
+func TestReplicationEditBeatsDelete(t *testing.T) {
+	hub := newTestHub(t)
+	a, ra := newTestReplica(t, hub, MergeFields)
+	b, rb := newTestReplica(t, hub, MergeFields)
+	a.Put(Doc{"_id": "1", "text": "keep me"})
+	syncAll(t, ra, rb)
+
+	a.Del("1")
+	b.Put(Doc{"_id": "1", "text": "edited"})
+	syncAll(t, ra, rb, ra)
+	for _, s := range []*DocStore{a, b} {
+		if doc, err := s.Get("1"); err != nil || doc["text"] != "edited" {
+			t.Errorf("doc = %v, %v", doc, err)
+		}
+	}
+	if c := rb.Conflicts(); len(c) != 1 || c[0].Remote != nil {
+		t.Errorf("conflicts = %+v", c)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// restart replaces the process with the freshly installed executable. The
+// PID stays the same, so service managers don't see the app exit.
+func restart() error {
+	exe, err := executablePath()
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(exe, os.Args, os.Environ())
+}
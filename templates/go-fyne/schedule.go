@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// Recurrence is a repeat rule: the subset of iCalendar's RRULE (RFC 5545)
+// with FREQ, INTERVAL, BYDAY (in weekly rules) and UNTIL.
+type Recurrence struct {
+	Freq     string // DAILY, WEEKLY, MONTHLY or YEARLY
+	Interval int
+	ByDay    []time.Weekday
+	Until    time.Time // zero for a series without an end
+}
+
+// rruleDays are the RRULE names of the weekdays, indexed by time.Weekday.
+var rruleDays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// repeatPresets are the rules offered in the UI. ParseRecurrence also
+// accepts their names, e.g. "weekly".
+var repeatPresets = []struct{ name, rule string }{
+	{"Daily", "FREQ=DAILY"},
+	{"Weekdays", "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+	{"Weekly", "FREQ=WEEKLY"},
+	{"Monthly", "FREQ=MONTHLY"},
+	{"Yearly", "FREQ=YEARLY"},
+}
+
+// ParseRecurrence reads an RRULE, with or without the "RRULE:" prefix, or
+// the name of a preset.
+func ParseRecurrence(rule string) (Recurrence, error) {
+	rule = strings.TrimSpace(rule)
+	for _, p := range repeatPresets {
+		if strings.EqualFold(rule, p.name) {
+			rule = p.rule
+		}
+	}
+	r := Recurrence{Interval: 1}
+	for _, part := range strings.Split(strings.TrimPrefix(strings.ToUpper(rule), "RRULE:"), ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Recurrence{}, fmt.Errorf("%q is not KEY=VALUE", part)
+		}
+		switch key {
+		case "FREQ":
+			if !slices.Contains([]string{"DAILY", "WEEKLY", "MONTHLY", "YEARLY"}, value) {
+				return Recurrence{}, fmt.Errorf("FREQ=%s is not supported", value)
+			}
+			r.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return Recurrence{}, fmt.Errorf("INTERVAL=%s is not a positive number", value)
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				i := slices.Index(rruleDays, day)
+				if i < 0 {
+					return Recurrence{}, fmt.Errorf("BYDAY: %q is not a weekday (MO, TU, ...)", day)
+				}
+				r.ByDay = append(r.ByDay, time.Weekday(i))
+			}
+		case "UNTIL":
+			t, err := parseICalTime(value)
+			if err != nil {
+				return Recurrence{}, fmt.Errorf("UNTIL: %w", err)
+			}
+			r.Until = t
+		default:
+			return Recurrence{}, fmt.Errorf("%s is not supported", key)
+		}
+	}
+	if r.Freq == "" {
+		return Recurrence{}, errors.New("FREQ is missing")
+	}
+	if len(r.ByDay) > 0 && r.Freq != "WEEKLY" {
+		return Recurrence{}, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	return r, nil
+}
+
+// String returns the rule as an RRULE value.
+func (r Recurrence) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = rruleDays[day]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(icalTime))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence after after of the series starting at
+// start; ok is false when the series ended. Like RFC 5545, monthly and
+// yearly series skip months without the start's day, e.g. the 31st.
+func (r Recurrence) Next(start, after time.Time) (next time.Time, ok bool) {
+	interval := max(r.Interval, 1)
+	fromMonday := (int(start.Weekday()) + 6) % 7
+	for i := 1; ; i++ {
+		switch r.Freq {
+		case "DAILY":
+			next = start.AddDate(0, 0, i*interval)
+		case "WEEKLY":
+			if len(r.ByDay) == 0 {
+				next = start.AddDate(0, 0, 7*i*interval)
+				break
+			}
+			// i counts days: the listed weekdays of every interval-th week
+			next = start.AddDate(0, 0, i)
+			if (fromMonday+i)/7%interval != 0 || !slices.Contains(r.ByDay, next.Weekday()) {
+				continue
+			}
+		case "MONTHLY", "YEARLY":
+			months := i * interval
+			if r.Freq == "YEARLY" {
+				months *= 12
+			}
+			next = start.AddDate(0, months, 0)
+			if next.Day() != start.Day() {
+				continue
+			}
+		default:
+			return time.Time{}, false
+		}
+		if !r.Until.IsZero() && next.After(r.Until) {
+			return time.Time{}, false
+		}
+		if next.After(after) {
+			return next, true
+		}
+	}
+}
+
+// describeRepeat names a rule for the UI, in the user's language.
+func describeRepeat(rule string) string {
+	for _, p := range repeatPresets {
+		if p.rule == rule {
+			return T(p.name)
+		}
+	}
+	return rule
+}
+
+// repeatNames are the choices of the repeat selectors, "Never" first.
+func repeatNames() []string {
+	names := []string{T("Never")}
+	for _, p := range repeatPresets {
+		names = append(names, T(p.name))
+	}
+	return names
+}
+
+// repeatRule is the inverse of describeRepeat; "Never" means no rule.
+func repeatRule(name string) string {
+	if name == T("Never") {
+		return ""
+	}
+	for _, p := range repeatPresets {
+		if T(p.name) == name {
+			return p.rule
+		}
+	}
+	return name
+}
+
+// reminderChoice is a reminder offered in the UI, with its lead time
+// before the due date. The name is in English; the UI shows its
+// translation.
+type reminderChoice struct{ name, lead string }
+
+var reminderChoices = []reminderChoice{
+	{"None", ""},
+	{"At due time", "0s"},
+	{"5 minutes before", "5m"},
+	{"15 minutes before", "15m"},
+	{"1 hour before", "1h"},
+	{"1 day before", "24h"},
+}
+
+// shortDuration formats d like time.Duration.String, without the zero
+// minutes and seconds: "15m", "1h", "1h30m".
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// defaultDueHour is the time of day of a due date entered without one.
+const defaultDueHour = 9
+
+// parseDue reads a due date typed by the user: "today", "tomorrow", a date
+// like 2024-05-01, each optionally followed by a time like 17:30, or just
+// a time today. An empty string means no due date.
+func parseDue(s string, now time.Time) (*time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return nil, nil
+	}
+	day, clock, _ := strings.Cut(s, " ")
+	var date time.Time
+	switch day {
+	case "today":
+		date = now
+	case "tomorrow":
+		date = now.AddDate(0, 0, 1)
+	default:
+		var err error
+		if date, err = time.ParseInLocation("2006-01-02", day, now.Location()); err != nil {
+			if clock != "" {
+				return nil, fmt.Errorf("%q is not a date like today, tomorrow or 2006-01-02", day)
+			}
+			date, clock = now, day
+		}
+	}
+	hour, minute := defaultDueHour, 0
+	if clock != "" {
+		t, err := time.Parse("15:04", clock)
+		if err != nil {
+			if t, err = time.Parse("3:04pm", clock); err != nil {
+				return nil, fmt.Errorf("%q is not a time like 17:30", clock)
+			}
+		}
+		hour, minute = t.Hour(), t.Minute()
+	}
+	due := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, now.Location())
+	return &due, nil
+}
+
+// formatDue describes a task's due date for its row.
+func formatDue(task Task, now time.Time) string {
+	if task.DueAt == nil {
+		return ""
+	}
+	due := *task.DueAt
+	s := FormatDateTime(due)
+	if y, m, d := due.Date(); y == now.Year() && m == now.Month() && d == now.Day() {
+		s = T("Today %s", FormatClock(due))
+	}
+	if task.overdue(now) {
+		s = T("⚠️ Overdue %s", s)
+	} else {
+		s = "📅 " + s
+	}
+	if task.Repeat != "" {
+		s += " 🔁"
+	}
+	return s
+}
+
+// Reminders sends a notification when a task's reminder comes due. It
+// remembers when it last checked, so reminders that came due while the
+// app was closed are sent the next time it starts.
+type Reminders struct {
+	tm     *TaskManager
+	path   string
+	notify func(*fyne.Notification)
+
+	mu    sync.Mutex
+	last  time.Time // reminders up to last were sent
+	saved time.Time // when last was saved
+}
+
+// remindersFile is the state Reminders keeps at its path.
+type remindersFile struct {
+	LastCheck time.Time `json:"lastCheck"`
+}
+
+// NewReminders watches the manager's tasks. The state is kept at path; an
+// empty path keeps it in memory only.
+func NewReminders(tm *TaskManager, path string, notify func(*fyne.Notification)) (*Reminders, error) {
+	r := &Reminders{tm: tm, path: path, notify: notify, last: time.Now()}
+	if path == "" {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, r.save()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file remindersFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		log.Printf("could not read %s, starting over: %v", path, err)
+		return r, nil
+	}
+	r.last = file.LastCheck
+	return r, nil
+}
+
+func (r *Reminders) save() error {
+	if r.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(remindersFile{LastCheck: r.last})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(r.path, data); err != nil {
+		return err
+	}
+	r.saved = time.Now()
+	return nil
+}
+
+// Run checks for reminders until ctx is done: right away, to catch up,
+// then whenever the next reminder comes due or the tasks change.
+func (r *Reminders) Run(ctx context.Context) {
+	kick := make(chan struct{}, 1)
+	cancel := r.tm.Subscribe(func(TaskChange) {
+		select {
+		case kick <- struct{}{}:
+		default:
+		}
+	})
+	defer cancel()
+	for {
+		now := time.Now()
+		if err := r.Check(now); err != nil {
+			log.Println("reminders:", err)
+		}
+		// Wake up at least every minute, in case the clock jumped
+		wait := time.Minute
+		if next, ok := r.next(now); ok && next.Sub(now) < wait {
+			wait = next.Sub(now)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-kick:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// Check sends the reminders that came due since the last check. Several
+// at once, e.g. after the app was closed, become one notification.
+func (r *Reminders) Check(now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !now.After(r.last) {
+		return nil
+	}
+	var due []Task
+	for _, task := range r.tm.GetTasks() {
+		if at, ok := task.remindAt(); ok && at.After(r.last) && !at.After(now) {
+			due = append(due, task)
+		}
+	}
+	r.last = now
+
+	switch len(due) {
+	case 0:
+		// Saving once a minute is enough to tell what was missed
+		if now.Sub(r.saved) < time.Minute {
+			return nil
+		}
+	case 1:
+		body := T("Due %s", FormatDateTime(*due[0].DueAt))
+		if due[0].overdue(now) {
+			body = T("Was due %s", FormatDateTime(*due[0].DueAt))
+		}
+		r.notify(fyne.NewNotification("⏰ "+due[0].Text, body))
+	default:
+		texts := make([]string, len(due))
+		for i, task := range due {
+			texts[i] = task.Text
+		}
+		r.notify(fyne.NewNotification(N("⏰ %d task due", "⏰ %d tasks due", len(due)), strings.Join(texts, "\n")))
+	}
+	return r.save()
+}
+
+// next returns the time of the next reminder after now.
+func (r *Reminders) next(now time.Time) (time.Time, bool) {
+	var next time.Time
+	for _, task := range r.tm.GetTasks() {
+		if at, ok := task.remindAt(); ok && at.After(now) && (next.IsZero() || at.Before(next)) {
+			next = at
+		}
+	}
+	return next, !next.IsZero()
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	for rule, want := range map[string]string{
+		"weekly":                              "FREQ=WEEKLY",
+		"Weekdays":                            "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		"RRULE:freq=daily;interval=2":         "FREQ=DAILY;INTERVAL=2",
+		"FREQ=MONTHLY;UNTIL=20250101T000000Z": "FREQ=MONTHLY;UNTIL=20250101T000000Z",
+		"FREQ=WEEKLY;INTERVAL=1;BYDAY=SA,SU":  "FREQ=WEEKLY;BYDAY=SA,SU",
+		"FREQ=YEARLY":                         "FREQ=YEARLY",
+	} {
+		r, err := ParseRecurrence(rule)
+		if err != nil || r.String() != want {
+			t.Errorf("ParseRecurrence(%q) = %q, %v; want %q", rule, r, err, want)
+		}
+	}
+	for _, rule := range []string{"", "sometimes", "FREQ=HOURLY", "FREQ=DAILY;COUNT=3", "FREQ=DAILY;INTERVAL=0", "FREQ=MONTHLY;BYDAY=MO", "FREQ=WEEKLY;BYDAY=XX", "INTERVAL=2"} {
+		if _, err := ParseRecurrence(rule); err == nil {
+			t.Errorf("ParseRecurrence(%q) succeeded", rule)
+		}
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 9, 0, 0, 0, time.UTC) } // Jan 1 2024 is a Monday
+	tests := []struct {
+		rule         string
+		start, after time.Time
+		want         time.Time
+		wantOK       bool
+	}{
+		{"daily", day(1), day(1), day(2), true},
+		{"FREQ=DAILY;INTERVAL=3", day(1), day(5), day(7), true},
+		{"weekly", day(1), day(1), day(8), true},
+		{"weekdays", day(5), day(5), day(8), true}, // Friday to Monday
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", day(1), day(1), day(3), true},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", day(3), day(3), day(15), true},
+		{"monthly", time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC), day(31), time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC), true},
+		{"yearly", time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC), day(1), time.Date(2028, 2, 29, 9, 0, 0, 0, time.UTC), true},
+		{"FREQ=DAILY;UNTIL=20240103T235959Z", day(1), day(2), day(3), true},
+		{"FREQ=DAILY;UNTIL=20240103T235959Z", day(1), day(3), time.Time{}, false},
+	}
+	for _, tt := range tests {
+		r, err := ParseRecurrence(tt.rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := r.Next(tt.start, tt.after)
+		if ok != tt.wantOK || !got.Equal(tt.want) {
+			t.Errorf("%s from %s after %s = %s, %v; want %s", tt.rule, tt.start, tt.after, got, ok, tt.want)
+		}
+	}
+}
+
+func TestParseDue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.Local)
+	for s, want := range map[string]time.Time{
+		"today":           time.Date(2024, 5, 1, defaultDueHour, 0, 0, 0, time.Local),
+		"Tomorrow 17:30":  time.Date(2024, 5, 2, 17, 30, 0, 0, time.Local),
+		"2024-06-10":      time.Date(2024, 6, 10, defaultDueHour, 0, 0, 0, time.Local),
+		"2024-06-10 8:15": time.Date(2024, 6, 10, 8, 15, 0, 0, time.Local),
+		"18:00":           time.Date(2024, 5, 1, 18, 0, 0, 0, time.Local),
+		"tomorrow 5:30pm": time.Date(2024, 5, 2, 17, 30, 0, 0, time.Local),
+	} {
+		if due, err := parseDue(s, now); err != nil || !due.Equal(want) {
+			t.Errorf("parseDue(%q) = %v, %v; want %s", s, due, err, want)
+		}
+	}
+	if due, err := parseDue(" ", now); due != nil || err != nil {
+		t.Errorf("parseDue of nothing = %v, %v", due, err)
+	}
+	for _, s := range []string{"soon", "tomorrow noon", "2024-13-01", "someday 10:00"} {
+		if _, err := parseDue(s, now); err == nil {
+			t.Errorf("parseDue(%q) succeeded", s)
+		}
+	}
+}
+
+func TestCompletingRepeatingTask(t *testing.T) {
+	tm := newTestManager(t)
+	due := time.Now().Add(-50 * time.Hour) // done two days late
+	if err := tm.Add(Task{Text: "Water the plants", Priority: "low", DueAt: &due, Repeat: "daily", Reminder: "15m"}); err != nil {
+		t.Fatal(err)
+	}
+	var task Task
+	for _, task = range tm.GetTasks() {
+		if task.Repeat != "" {
+			break
+		}
+	}
+	if task.Repeat != "FREQ=DAILY" {
+		t.Fatalf("task = %+v", task)
+	}
+	tm.ToggleTask(task.ID)
+
+	var next *Task
+	for _, tk := range tm.GetTasks() {
+		if tk.Text == "Water the plants" && !tk.Completed {
+			next = &tk
+		}
+	}
+	if next == nil {
+		t.Fatal("completing a repeating task did not add the next one")
+	}
+	if !next.DueAt.After(time.Now()) || next.DueAt.Sub(time.Now()) > 24*time.Hour || next.Reminder != "15m" || next.Repeat != "FREQ=DAILY" {
+		t.Errorf("next = %+v, due %s", next, next.DueAt)
+	}
+	if total, completed, _ := tm.GetStats(); total != 4 || completed != 1 {
+		t.Errorf("stats %d/%d", total, completed)
+	}
+
+	if err := tm.Add(Task{Text: "Without a date", Repeat: "weekly"}); err == nil {
+		t.Error("a repeating task without a due date was added")
+	}
+	if err := tm.SetSchedule(next.ID, nil, "weekly", "1h"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := tm.Task(next.ID); got.DueAt != nil || got.Repeat != "" || got.Reminder != "" {
+		t.Errorf("cleared schedule = %+v", got)
+	}
+}
+
+func TestReminders(t *testing.T) {
+	tm := newTestManager(t)
+	path := filepath.Join(t.TempDir(), "reminders.json")
+	var sent []*fyne.Notification
+	notify := func(n *fyne.Notification) { sent = append(sent, n) }
+	r, err := NewReminders(tm, path, notify)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	at := func(d time.Duration) *time.Time { t := start.Add(d); return &t }
+	tm.Add(Task{Text: "Call Sam", DueAt: at(time.Hour), Reminder: "15m"})
+	tm.Add(Task{Text: "No reminder", DueAt: at(time.Hour)})
+	tm.Add(Task{Text: "Standup", DueAt: at(2 * time.Hour), Reminder: "0s"})
+	tm.Add(Task{Text: "Report", DueAt: at(3 * time.Hour), Reminder: "1h"})
+
+	if next, ok := r.next(start); !ok || next.Sub(start.Add(45*time.Minute)).Abs() > time.Millisecond { // stored in ms
+		t.Errorf("next reminder at %s", next)
+	}
+	r.Check(start.Add(30 * time.Minute))
+	if len(sent) != 0 {
+		t.Fatalf("sent %d notifications too early", len(sent))
+	}
+	r.Check(start.Add(50 * time.Minute))
+	if len(sent) != 1 || sent[0].Title != "⏰ Call Sam" || !strings.HasPrefix(sent[0].Content, "Due ") {
+		t.Fatalf("sent %+v", sent)
+	}
+	r.Check(start.Add(55 * time.Minute))
+	if len(sent) != 1 {
+		t.Error("a reminder was sent twice")
+	}
+
+	// Reminders that came due while the app was closed are sent as one
+	r, err = NewReminders(tm, path, notify)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Check(start.Add(5 * time.Hour))
+	if len(sent) != 2 || sent[1].Title != "⏰ 2 tasks due" || sent[1].Content != "Standup\nReport" {
+		t.Fatalf("sent %+v", sent[1:])
+	}
+
+	// Completed tasks are not reminded of
+	tm.Add(Task{Text: "Done already", DueAt: at(6 * time.Hour), Reminder: "0s"})
+	for _, task := range tm.GetTasks() {
+		if task.Text == "Done already" {
+			tm.ToggleTask(task.ID)
+		}
+	}
+	r.Check(start.Add(7 * time.Hour))
+	if len(sent) != 2 {
+		t.Errorf("sent %+v", sent[2:])
+	}
+}
+
+func TestTaskListDueFilters(t *testing.T) {
+	tm := newTestManager(t)
+	at := func(d time.Duration) *time.Time { t := time.Now().Add(d); return &t }
+	tm.Add(Task{Text: "late", DueAt: at(-time.Hour)})
+	tm.Add(Task{Text: "soon", DueAt: at(time.Hour)})
+	tm.Add(Task{Text: "next week", DueAt: at(3 * 24 * time.Hour)})
+	tm.Add(Task{Text: "next month", DueAt: at(30 * 24 * time.Hour)})
+
+	texts := func(l *TaskList) string {
+		var s []string
+		for i := 0; i < l.Len(); i++ {
+			task, _ := l.At(i)
+			s = append(s, task.Text)
+		}
+		return strings.Join(s, ",")
+	}
+	overdue := NewTaskList(tm, FilterOverdue, SortOldest)
+	upcoming := NewTaskList(tm, FilterUpcoming, SortDue)
+	if got := texts(overdue); got != "late" {
+		t.Errorf("overdue = %s", got)
+	}
+	if got := texts(upcoming); got != "soon,next week" {
+		t.Errorf("upcoming = %s", got)
+	}
+	if got := texts(NewTaskList(tm, FilterAll, SortDue)); !strings.HasPrefix(got, "late,soon,next week,next month,") {
+		t.Errorf("by due date = %s", got)
+	}
+
+	// Completing an overdue task takes it off the list
+	task, _ := overdue.At(0)
+	tm.ToggleTask(task.ID)
+	if overdue.Len() != 0 {
+		t.Errorf("overdue = %s", texts(overdue))
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+)
+
+func TestParseShortcut(t *testing.T) {
+	ctrlShift := fyne.KeyModifierControl | fyne.KeyModifierShift
+	for text, want := range map[string]desktop.CustomShortcut{
+		"Ctrl+Shift+z":   {KeyName: fyne.KeyZ, Modifier: ctrlShift},
+		"shift + ctrl+Z": {KeyName: fyne.KeyZ, Modifier: ctrlShift},
+		"Alt+F4":         {KeyName: fyne.KeyF4, Modifier: fyne.KeyModifierAlt},
+		"Cmd+,":          {KeyName: fyne.KeyComma, Modifier: fyne.KeyModifierSuper},
+		"Ctrl++":         {KeyName: fyne.KeyPlus, Modifier: fyne.KeyModifierControl},
+		"control+pageup": {KeyName: fyne.KeyPageUp, Modifier: fyne.KeyModifierControl},
+		"Ctrl+Enter":     {KeyName: fyne.KeyReturn, Modifier: fyne.KeyModifierControl},
+		"Ctrl+1":         {KeyName: fyne.Key1, Modifier: fyne.KeyModifierControl},
+	} {
+		sc, err := ParseShortcut(text)
+		if err != nil || !sameShortcut(sc, &want) {
+			t.Errorf("ParseShortcut(%q) = %v, %v", text, sc, err)
+			continue
+		}
+		if again, err := ParseShortcut(FormatShortcut(sc)); err != nil || !sameShortcut(again, sc) {
+			t.Errorf("%q formats as %q, which reads back as %v, %v", text, FormatShortcut(sc), again, err)
+		}
+	}
+	for _, text := range []string{"", "Z", "Shift+Z", "Ctrl+", "Ctrl+Foo", "Hyper+Z", "Ctrl+F13"} {
+		if _, err := ParseShortcut(text); err == nil {
+			t.Errorf("ParseShortcut(%q) succeeded", text)
+		}
+	}
+}
+
+func TestShortcutsSearch(t *testing.T) {
+	view := newTestView(t)
+	s, _ := NewShortcuts("", view.actions())
+	names := func(query string) []string {
+		var names []string
+		for _, a := range s.Search(query) {
+			names = append(names, a.Name)
+		}
+		return names
+	}
+
+	if got := names(""); len(got) != len(s.Actions()) {
+		t.Errorf("empty search found %d of %d actions", len(got), len(s.Actions()))
+	}
+	for query, want := range map[string]string{
+		"undo":     "↩️ Undo",
+		"imp":      "📥 Import Tasks...",
+		"show pen": "🔍 Show Pending",
+		"sbd":      "↕️ Sort by Due date",
+		"palette":  "🔎 Command Palette",
+	} {
+		if got := names(query); len(got) == 0 || got[0] != want {
+			t.Errorf("search %q = %q, want %q first", query, got, want)
+		}
+	}
+	if got := names("xyzzy"); len(got) != 0 {
+		t.Errorf("search for nothing found %q", got)
+	}
+}
+
+func TestShortcutsBindings(t *testing.T) {
+	view := newTestView(t)
+	path := filepath.Join(t.TempDir(), "shortcuts.json")
+	s, err := NewShortcuts(path, view.actions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Attach(view.window)
+	canvas := view.window.Canvas().(fyne.Shortcutable)
+	item := s.MenuItem("task.new")
+
+	// Ctrl+N focuses the task input
+	canvas.TypedShortcut(ctrl(fyne.KeyN))
+	if view.window.Canvas().Focused() != view.input {
+		t.Fatal("Ctrl+N did not focus the task input")
+	}
+	view.window.Canvas().Unfocus()
+
+	if err := s.Bind("task.new", ctrl(fyne.KeyZ)); err == nil || !strings.Contains(err.Error(), "Undo") {
+		t.Errorf("binding Undo's shortcut: %v", err)
+	}
+	if err := s.Bind("task.new", ctrl(fyne.KeyV)); err == nil {
+		t.Error("Ctrl+V was bound")
+	}
+	if err := s.Bind("task.new", ctrl(fyne.KeyT, fyne.KeyModifierShift)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Bind("filter.pending", nil); err != nil {
+		t.Fatal(err)
+	}
+	if item.Shortcut != s.Shortcut("task.new") {
+		t.Error("the menu item kept the old shortcut")
+	}
+	canvas.TypedShortcut(ctrl(fyne.KeyN))
+	if view.window.Canvas().Focused() != nil {
+		t.Error("the old shortcut still works")
+	}
+	canvas.TypedShortcut(ctrl(fyne.KeyT, fyne.KeyModifierShift))
+	if view.window.Canvas().Focused() != view.input {
+		t.Error("the new shortcut does not work")
+	}
+
+	// Only the changes are saved, and they load in the next session
+	data, _ := os.ReadFile(path)
+	if got := strings.Join(strings.Fields(string(data)), " "); got != `{ "bindings": { "filter.pending": "", "task.new": "Ctrl+Shift+T" } }` {
+		t.Errorf("saved %s", data)
+	}
+	s, err = NewShortcuts(path, view.actions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Label("task.new") != "Ctrl+Shift+T" || s.Shortcut("filter.pending") != nil || s.Label("edit.undo") != "Ctrl+Z" {
+		t.Errorf("loaded %q %v %q", s.Label("task.new"), s.Shortcut("filter.pending"), s.Label("edit.undo"))
+	}
+
+	// Swapped shortcuts load; clashing ones are dropped
+	os.WriteFile(path, []byte(`{"bindings": {"edit.undo": "Ctrl+Shift+Z", "edit.redo": "Ctrl+Z", "task.new": "Ctrl+1", "file.export": "Hyper+E"}}`), 0644)
+	s, err = NewShortcuts(path, view.actions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Label("edit.undo") != "Ctrl+Shift+Z" || s.Label("edit.redo") != "Ctrl+Z" || s.Label("task.new") != "" || s.Label("file.export") != "Ctrl+E" {
+		t.Errorf("loaded %q %q %q %q", s.Label("edit.undo"), s.Label("edit.redo"), s.Label("task.new"), s.Label("file.export"))
+	}
+
+	if err := s.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Label("task.new") != "Ctrl+N" || s.Label("edit.undo") != "Ctrl+Z" {
+		t.Errorf("after reset %q %q", s.Label("task.new"), s.Label("edit.undo"))
+	}
+}
+
+func TestCommandPalette(t *testing.T) {
+	view := newTestView(t)
+	s, _ := NewShortcuts("", view.actions())
+	s.Attach(view.window)
+
+	view.window.Canvas().(fyne.Shortcutable).TypedShortcut(ctrl(fyne.KeyK))
+	search, ok := view.window.Canvas().Focused().(*paletteEntry)
+	if !ok {
+		t.Fatal("Ctrl+K did not open the palette")
+	}
+	test.Type(search, "show comp")
+	search.TypedKey(&fyne.KeyEvent{Name: fyne.KeyReturn})
+	if view.list.Length() != 0 || view.filterCompleted.Importance != widget.HighImportance {
+		t.Errorf("the palette did not pick the Completed filter: %d tasks", view.list.Length())
+	}
+
+	// Down picks the next match
+	s.ShowPalette()
+	search = view.window.Canvas().Focused().(*paletteEntry)
+	test.Type(search, "show")
+	search.TypedKey(&fyne.KeyEvent{Name: fyne.KeyDown})
+	search.TypedKey(&fyne.KeyEvent{Name: fyne.KeyReturn})
+	if view.filterPending.Importance != widget.HighImportance {
+		t.Error("Down and Enter did not pick the second match, the Pending filter")
+	}
+}
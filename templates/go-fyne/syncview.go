@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// syncInterval is how often the app syncs with the hub when nothing
+// changes locally.
+const syncInterval = 30 * time.Second
+
+// Preference keys of the sync settings
+const (
+	prefSyncURL   = "syncURL"
+	prefSyncToken = "syncToken"
+	prefSyncMerge = "syncMerge"
+)
+
+// mergeModes labels the MergeMode values, in English; the selector shows
+// their translations.
+var mergeModes = []string{"Merge fields", "Last writer wins"}
+
+// syncView is the status bar at the bottom of the window: the replication
+// status, the hub settings and the conflicts waiting to be resolved. The tasks sync
+// with the /api/docs endpoints of a Go + Gin cherry (see replicate.go).
+type syncView struct {
+	store     *DocStore
+	statePath string
+	prefs     fyne.Preferences
+	window    fyne.Window
+
+	status    *widget.Label
+	settings  *widget.Button
+	syncNow   *widget.Button
+	conflicts *widget.Button
+	content   fyne.CanvasObject
+
+	mu         sync.Mutex
+	replicator *Replicator
+	stop       context.CancelFunc
+}
+
+func newSyncView(store *DocStore, statePath string, prefs fyne.Preferences, window fyne.Window) *syncView {
+	v := &syncView{store: store, statePath: statePath, prefs: prefs, window: window}
+	v.status = widget.NewLabel(T("☁️ Sync off"))
+	v.settings = widget.NewButton(T("⚙️ Sync"), v.showSettings)
+	v.syncNow = widget.NewButton("🔄", func() {
+		if r := v.current(); r != nil {
+			go r.Sync(context.Background())
+		}
+	})
+	v.syncNow.Hide()
+	v.conflicts = widget.NewButton("", v.showConflicts)
+	v.conflicts.Hide()
+	v.content = container.NewHBox(v.status, v.conflicts, v.syncNow, v.settings)
+	return v
+}
+
+// actions are the sync commands, for the shortcuts and the command palette.
+func (v *syncView) actions() []*Action {
+	return []*Action{
+		{ID: "sync.settings", Name: T("☁️ Sync Settings..."), Shortcut: ctrl(fyne.KeyComma), Run: v.showSettings},
+		{ID: "sync.now", Name: T("🔄 Sync Now"), Shortcut: ctrl(fyne.KeyR), Run: v.syncNow.OnTapped},
+	}
+}
+
+// connect starts syncing with hub, replacing the running replicator. token
+// is the hub's --sync-token, if it has one. An empty hub turns sync off.
+func (v *syncView) connect(hub, token string, mode MergeMode) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.stop != nil {
+		v.stop()
+		v.replicator, v.stop = nil, nil
+	}
+	if hub == "" {
+		v.status.SetText(T("☁️ Sync off"))
+		v.syncNow.Hide()
+		v.conflicts.Hide()
+		return nil
+	}
+
+	r, err := NewReplicator(v.store, hub, v.statePath, mode)
+	if err != nil {
+		return err
+	}
+	r.SetToken(token)
+	r.OnStatus(v.showStatus)
+	ctx, stop := context.WithCancel(context.Background())
+	v.replicator, v.stop = r, stop
+	v.showStatus(r.Status())
+	v.syncNow.Show()
+	go r.Run(ctx, syncInterval)
+	return nil
+}
+
+func (v *syncView) current() *Replicator {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.replicator
+}
+
+func (v *syncView) showStatus(st SyncStatus) {
+	var text string
+	switch {
+	case st.Syncing:
+		text = T("🔄 Syncing...")
+	case st.Err != nil && !st.Online:
+		text = T("📴 Offline")
+	case st.Err != nil:
+		text = T("⚠️ Sync failed: %v", st.Err)
+	case st.LastSync.IsZero():
+		text = T("☁️ Not synced yet")
+	default:
+		text = T("☁️ Synced %s", FormatDateTime(st.LastSync))
+	}
+	if st.Pending > 0 && !st.Syncing {
+		text += " · " + T("%d waiting", st.Pending)
+	}
+	v.status.SetText(text)
+
+	if st.Conflicts > 0 {
+		v.conflicts.SetText(N("⚠️ %d conflict", "⚠️ %d conflicts", st.Conflicts))
+		v.conflicts.Show()
+	} else {
+		v.conflicts.Hide()
+	}
+}
+
+// showSettings asks for the hub URL, its token and the merge mode and
+// reconnects.
+func (v *syncView) showSettings() {
+	hub := widget.NewEntry()
+	hub.SetPlaceHolder("http://localhost:3000")
+	hub.SetText(v.prefs.String(prefSyncURL))
+	token := widget.NewPasswordEntry()
+	token.SetPlaceHolder(T("The hub's --sync-token, if it has one"))
+	token.SetText(v.prefs.String(prefSyncToken))
+	merge := widget.NewSelect(translated(mergeModes), nil)
+	merge.SetSelectedIndex(v.prefs.Int(prefSyncMerge))
+
+	items := []*widget.FormItem{
+		widget.NewFormItem(T("Hub URL"), hub),
+		widget.NewFormItem(T("Token"), token),
+		widget.NewFormItem(T("Conflicts"), merge),
+	}
+	dialog.ShowForm(T("☁️ Sync with a Go + Gin cherry"), T("Save"), T("Cancel"), items, func(save bool) {
+		if !save {
+			return
+		}
+		if err := v.connect(hub.Text, token.Text, MergeMode(merge.SelectedIndex())); err != nil {
+			dialog.ShowError(err, v.window)
+			return
+		}
+		v.prefs.SetString(prefSyncURL, hub.Text)
+		v.prefs.SetString(prefSyncToken, token.Text)
+		v.prefs.SetInt(prefSyncMerge, merge.SelectedIndex())
+	}, v.window)
+}
+
+// showConflicts lists the conflicts with a choice of version for each.
+func (v *syncView) showConflicts() {
+	r := v.current()
+	if r == nil {
+		return
+	}
+	list := container.NewVBox()
+	var d dialog.Dialog
+	var fill func()
+	fill = func() {
+		list.RemoveAll()
+		conflicts := r.Conflicts()
+		if len(conflicts) == 0 {
+			d.Hide()
+			return
+		}
+		for _, c := range conflicts {
+			c := c
+			resolve := func(version Doc) func() {
+				return func() {
+					if err := r.Resolve(c.ID, version); err != nil {
+						dialog.ShowError(err, v.window)
+					}
+					fill()
+				}
+			}
+			title := widget.NewLabel(conflictTitle(c))
+			title.TextStyle.Bold = true
+			list.Add(title)
+			list.Add(widget.NewLabel(conflictDetails(c)))
+			list.Add(container.NewHBox(
+				widget.NewButton(T("Keep mine"), resolve(c.Local)),
+				widget.NewButton(T("Keep theirs"), resolve(c.Remote)),
+				widget.NewButton(T("Keep merged"), func() { r.Dismiss(c.ID); fill() }),
+			))
+			list.Add(widget.NewSeparator())
+		}
+	}
+	scroll := container.NewVScroll(list)
+	scroll.SetMinSize(fyne.NewSize(500, 300))
+	d = dialog.NewCustom(T("⚠️ Edited on both sides"), T("Close"), scroll, v.window)
+	fill()
+	d.Show()
+}
+
+// conflictTitle names a conflict by its task text, if it has one.
+func conflictTitle(c Conflict) string {
+	for _, doc := range []Doc{c.Local, c.Remote} {
+		if text, ok := doc["text"].(string); ok && text != "" {
+			return text
+		}
+	}
+	return c.ID
+}
+
+// conflictDetails describes how the two versions differ.
+func conflictDetails(c Conflict) string {
+	switch {
+	case c.Local == nil:
+		return T("🗑️ Deleted here, edited on the hub")
+	case c.Remote == nil:
+		return T("🗑️ Deleted on the hub, edited here")
+	}
+	var lines []string
+	for _, field := range c.Fields {
+		mine, _ := json.Marshal(c.Local[field])
+		theirs, _ := json.Marshal(c.Remote[field])
+		lines = append(lines, T("%s: %s here, %s on the hub", field, mine, theirs))
+	}
+	return strings.Join(lines, "\n")
+}
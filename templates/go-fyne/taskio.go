@@ -0,0 +1,691 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaskFormat is a file format tasks can be imported from and exported to.
+type TaskFormat int
+
+const (
+	FormatJSON     TaskFormat = iota // {"tasks": [...]}, like the old tasks.json
+	FormatCSV                        // one task per row, with a header row
+	FormatMarkdown                   // a "- [ ] task" checklist
+	FormatICal                       // iCalendar VTODOs, for calendar and to-do apps
+)
+
+// taskFormatExtensions maps file extensions to formats; the first one of
+// each format is used for new files.
+var taskFormatExtensions = []struct {
+	ext    string
+	format TaskFormat
+}{
+	{".json", FormatJSON},
+	{".csv", FormatCSV},
+	{".md", FormatMarkdown},
+	{".markdown", FormatMarkdown},
+	{".ics", FormatICal},
+}
+
+// FormatForName picks the format from a file name's extension.
+func FormatForName(name string) (TaskFormat, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range taskFormatExtensions {
+		if e.ext == ext {
+			return e.format, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported file type %q: use .json, .csv, .md or .ics", ext)
+}
+
+// RowError is a row of an imported file that couldn't be read. Row is the
+// line number, or for JSON the position in the list, counting from 1.
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+// WriteTasks writes tasks in format.
+func WriteTasks(w io.Writer, format TaskFormat, tasks []Task) error {
+	switch format {
+	case FormatCSV:
+		return writeTasksCSV(w, tasks)
+	case FormatMarkdown:
+		return writeTasksMarkdown(w, tasks)
+	case FormatICal:
+		return writeTasksICal(w, tasks)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Tasks []Task `json:"tasks"`
+	}{tasks})
+}
+
+// ReadTasks reads the tasks of a file in format. Rows that can't be read
+// are returned as RowErrors, and the rest still imports; err is only set
+// when the file as a whole can't be read.
+func ReadTasks(r io.Reader, format TaskFormat) (tasks []Task, rowErrs []RowError, err error) {
+	switch format {
+	case FormatCSV:
+		return readTasksCSV(r)
+	case FormatMarkdown:
+		return readTasksMarkdown(r)
+	case FormatICal:
+		return readTasksICal(r)
+	}
+	return readTasksJSON(r)
+}
+
+// readTasksJSON reads {"tasks": [...]}, a plain array of tasks, or todo
+// documents: a document export of any cherry or Fireproof's allDocs().
+func readTasksJSON(r io.Reader) ([]Task, []RowError, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		var file struct {
+			Tasks []json.RawMessage `json:"tasks"`
+			Docs  []json.RawMessage `json:"docs"`
+			Rows  []struct {
+				Value json.RawMessage `json:"value"`
+			} `json:"rows"`
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, nil, fmt.Errorf("not a task list: %w", err)
+		}
+		items = append(file.Tasks, file.Docs...)
+		for _, row := range file.Rows {
+			items = append(items, row.Value)
+		}
+	}
+
+	var tasks []Task
+	var rowErrs []RowError
+	for i, item := range items {
+		var fields map[string]any
+		if err := json.Unmarshal(item, &fields); err != nil {
+			rowErrs = append(rowErrs, RowError{i + 1, errors.New("not a JSON object")})
+			continue
+		}
+		var task Task
+		if _, isDoc := fields["_id"]; isDoc || fields["type"] != nil {
+			if fields["type"] != taskType {
+				continue // another kind of document
+			}
+			task = docToTask(Doc(fields))
+		} else if err := json.Unmarshal(item, &task); err != nil {
+			rowErrs = append(rowErrs, RowError{i + 1, err})
+			continue
+		}
+		if err := checkTask(&task); err != nil {
+			rowErrs = append(rowErrs, RowError{i + 1, err})
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rowErrs, nil
+}
+
+var csvHeader = []string{"id", "text", "priority", "completed", "created", "completed_at", "due", "repeat", "reminder"}
+
+func writeTasksCSV(w io.Writer, tasks []Task) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, task := range tasks {
+		completedAt, due := "", ""
+		if task.CompletedAt != nil {
+			completedAt = task.CompletedAt.Format(time.RFC3339)
+		}
+		if task.DueAt != nil {
+			due = task.DueAt.Format(time.RFC3339)
+		}
+		cw.Write([]string{task.ID, task.Text, task.Priority, strconv.FormatBool(task.Completed), task.CreatedAt.Format(time.RFC3339), completedAt, due, task.Repeat, task.Reminder})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// readTasksCSV reads a CSV file with a header row. Only the text column is
+// required; columns are matched by name in any order, unknown ones are
+// ignored.
+func readTasksCSV(r io.Reader) ([]Task, []RowError, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading the header row: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))
+		columns[strings.NewReplacer(" ", "_", "-", "_").Replace(name)] = i
+	}
+	if _, ok := columns["text"]; !ok {
+		return nil, nil, errors.New(`the header row has no "text" column`)
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var tasks []Task
+	var rowErrs []RowError
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrs = append(rowErrs, RowError{parseErr.Line, parseErr.Err})
+				continue
+			}
+			return tasks, rowErrs, err
+		}
+		line, _ := cr.FieldPos(0)
+		task := Task{
+			ID:       field(record, "id"),
+			Text:     field(record, "text"),
+			Priority: strings.ToLower(field(record, "priority")),
+			Repeat:   field(record, "repeat"),
+			Reminder: field(record, "reminder"),
+		}
+		var rowErr error
+		if s := field(record, "completed"); s != "" {
+			if task.Completed, rowErr = parseBool(s); rowErr != nil {
+				rowErr = fmt.Errorf("completed: %w", rowErr)
+			}
+		}
+		if s := field(record, "created"); s != "" && rowErr == nil {
+			if task.CreatedAt, rowErr = parseTime(s); rowErr != nil {
+				rowErr = fmt.Errorf("created: %w", rowErr)
+			}
+		}
+		if s := field(record, "completed_at"); s != "" && rowErr == nil {
+			var t time.Time
+			if t, rowErr = parseTime(s); rowErr != nil {
+				rowErr = fmt.Errorf("completed_at: %w", rowErr)
+			}
+			task.CompletedAt = &t
+		}
+		if s := field(record, "due"); s != "" && rowErr == nil {
+			var t time.Time
+			if t, rowErr = parseTime(s); rowErr != nil {
+				rowErr = fmt.Errorf("due: %w", rowErr)
+			}
+			task.DueAt = &t
+		}
+		if rowErr == nil {
+			rowErr = checkTask(&task)
+		}
+		if rowErr != nil {
+			rowErrs = append(rowErrs, RowError{line, rowErr})
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rowErrs, nil
+}
+
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "true", "yes", "y", "1", "x", "done":
+		return true, nil
+	case "false", "no", "n", "0", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not true or false", s)
+}
+
+// parseTime accepts RFC 3339 times and plain dates.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date (use 2006-01-02 or RFC 3339)", s)
+}
+
+func writeTasksMarkdown(w io.Writer, tasks []Task) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# {{PROJECT_NAME}} tasks\n\n")
+	for _, task := range tasks {
+		box := " "
+		if task.Completed {
+			box = "x"
+		}
+		// Dates go in an HTML comment, which Markdown viewers hide
+		meta := "created " + task.CreatedAt.Format(time.RFC3339)
+		if task.CompletedAt != nil {
+			meta += ", completed " + task.CompletedAt.Format(time.RFC3339)
+		}
+		if task.DueAt != nil {
+			meta += ", due " + task.DueAt.Format(time.RFC3339)
+		}
+		if task.Repeat != "" {
+			meta += ", repeat " + task.Repeat
+		}
+		if task.Reminder != "" {
+			meta += ", reminder " + task.Reminder
+		}
+		text := strings.Join(strings.Fields(task.Text), " ")
+		fmt.Fprintf(bw, "- [%s] %s %s <!-- %s -->\n", box, getPriorityIcon(task.Priority), text, meta)
+	}
+	return bw.Flush()
+}
+
+var (
+	markdownTask = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s*(.*)$`)
+	markdownMeta = regexp.MustCompile(`\s*<!--(.*?)-->\s*$`)
+)
+
+// readTasksMarkdown reads the checklist items of a Markdown file; other
+// lines are ignored. A leading 🔴, 🟡 or 🟢 sets the priority.
+func readTasksMarkdown(r io.Reader) ([]Task, []RowError, error) {
+	var tasks []Task
+	var rowErrs []RowError
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		m := markdownTask.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		task := Task{Completed: m[1] != " "}
+		text := m[2]
+		var rowErr error
+		if meta := markdownMeta.FindStringSubmatch(text); meta != nil {
+			text = text[:len(text)-len(meta[0])]
+			// Repeat rules contain commas, but no spaces
+			for _, part := range strings.Split(meta[1], ", ") {
+				key, value, _ := strings.Cut(strings.TrimSpace(part), " ")
+				value = strings.TrimSpace(value)
+				switch key {
+				case "repeat":
+					task.Repeat = value
+					continue
+				case "reminder":
+					task.Reminder = value
+					continue
+				case "created", "completed", "due":
+				default:
+					continue
+				}
+				t, err := parseTime(value)
+				switch {
+				case err != nil:
+					rowErr = fmt.Errorf("%s: %w", key, err)
+				case key == "created":
+					task.CreatedAt = t
+				case key == "completed":
+					task.CompletedAt = &t
+				default:
+					task.DueAt = &t
+				}
+			}
+		}
+		for _, priority := range []string{"high", "medium", "low"} {
+			if rest, ok := strings.CutPrefix(text, getPriorityIcon(priority)); ok {
+				task.Priority, text = priority, rest
+				break
+			}
+		}
+		task.Text = text
+		if rowErr == nil {
+			rowErr = checkTask(&task)
+		}
+		if rowErr != nil {
+			rowErrs = append(rowErrs, RowError{line, rowErr})
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rowErrs, scanner.Err()
+}
+
+const icalTime = "20060102T150405Z"
+
+func writeTasksICal(w io.Writer, tasks []Task) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		// Lines are folded at 75 bytes, counting the space that starts a
+		// continuation line, without splitting a character
+		s, limit := name+":"+value, 75
+		for len(s) > limit {
+			cut := limit
+			for cut > 0 && s[cut]&0xC0 == 0x80 {
+				cut--
+			}
+			bw.WriteString(s[:cut] + "\r\n ")
+			s, limit = s[cut:], 74
+		}
+		bw.WriteString(s + "\r\n")
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//FileCherry//{{PROJECT_NAME}}//EN")
+	now := time.Now().UTC().Format(icalTime)
+	for _, task := range tasks {
+		line("BEGIN", "VTODO")
+		line("UID", task.ID)
+		line("DTSTAMP", now)
+		line("CREATED", task.CreatedAt.UTC().Format(icalTime))
+		line("SUMMARY", icalEscape(task.Text))
+		line("PRIORITY", strconv.Itoa(map[string]int{"high": 1, "medium": 5, "low": 9}[task.Priority]))
+		if task.DueAt != nil {
+			line("DUE", task.DueAt.UTC().Format(icalTime))
+		}
+		if task.Repeat != "" {
+			line("RRULE", task.Repeat)
+		}
+		if task.Completed {
+			line("STATUS", "COMPLETED")
+			if task.CompletedAt != nil {
+				line("COMPLETED", task.CompletedAt.UTC().Format(icalTime))
+			}
+		} else {
+			line("STATUS", "NEEDS-ACTION")
+		}
+		if lead, err := time.ParseDuration(task.Reminder); err == nil && task.Reminder != "" {
+			line("BEGIN", "VALARM")
+			line("ACTION", "DISPLAY")
+			line("DESCRIPTION", icalEscape(task.Text))
+			line("TRIGGER", formatICalTrigger(lead))
+			line("END", "VALARM")
+		}
+		line("END", "VTODO")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func icalEscape(s string) string {
+	return icalEscaper.Replace(s)
+}
+
+func icalUnescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// readTasksICal reads the VTODOs of an iCalendar file; events and other
+// components are ignored. Errors are reported at the VTODO's BEGIN line.
+func readTasksICal(r io.Reader) ([]Task, []RowError, error) {
+	type icalLine struct {
+		n    int
+		text string
+	}
+	// Unfold continuation lines, which start with a space or tab
+	var lines []icalLine
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1].text += text[1:]
+			continue
+		}
+		lines = append(lines, icalLine{n: n, text: text})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(lines[0].text, "BEGIN:VCALENDAR") {
+		return nil, nil, errors.New("not an iCalendar file")
+	}
+
+	var tasks []Task
+	var rowErrs []RowError
+	var task *Task
+	var start int
+	var rowErr error
+	var dtstart *time.Time
+	inAlarm := false
+	for _, l := range lines {
+		nameParams, value, _ := strings.Cut(l.text, ":")
+		name, params, _ := strings.Cut(strings.ToUpper(nameParams), ";")
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VTODO"):
+			task, start, rowErr, dtstart = &Task{}, l.n, nil, nil
+		case task == nil:
+		case name == "BEGIN" && strings.EqualFold(value, "VALARM"):
+			inAlarm = true
+		case name == "END" && strings.EqualFold(value, "VALARM"):
+			inAlarm = false
+		case inAlarm:
+			// Only relative triggers before the due date become reminders
+			if lead, ok := parseICalTrigger(value); ok && name == "TRIGGER" && !strings.Contains(params, "VALUE=DATE-TIME") && task.Reminder == "" {
+				task.Reminder = shortDuration(lead)
+			}
+		case name == "END" && strings.EqualFold(value, "VTODO"):
+			if task.DueAt == nil {
+				task.DueAt = dtstart
+			}
+			if rowErr == nil {
+				rowErr = checkTask(task)
+			}
+			if rowErr != nil {
+				rowErrs = append(rowErrs, RowError{start, rowErr})
+			} else {
+				tasks = append(tasks, *task)
+			}
+			task = nil
+		case name == "UID":
+			task.ID = value
+		case name == "SUMMARY":
+			task.Text = icalUnescape(value)
+		case name == "PRIORITY":
+			switch p, err := strconv.Atoi(value); {
+			case err != nil:
+				rowErr = fmt.Errorf("PRIORITY %q is not a number", value)
+			case p >= 1 && p <= 4:
+				task.Priority = "high"
+			case p >= 6 && p <= 9:
+				task.Priority = "low"
+			default:
+				task.Priority = "medium"
+			}
+		case name == "STATUS":
+			task.Completed = strings.EqualFold(value, "COMPLETED")
+		case name == "CREATED" || name == "COMPLETED":
+			t, err := parseICalTime(value)
+			if err != nil {
+				rowErr = fmt.Errorf("%s: %w", name, err)
+			} else if name == "CREATED" {
+				task.CreatedAt = t
+			} else {
+				task.Completed, task.CompletedAt = true, &t
+			}
+		case name == "DUE" || name == "DTSTART":
+			t, err := parseICalTime(value)
+			if err != nil {
+				rowErr = fmt.Errorf("%s: %w", name, err)
+			} else if name == "DUE" {
+				task.DueAt = &t
+			} else {
+				dtstart = &t
+			}
+		case name == "RRULE":
+			task.Repeat = value
+		}
+	}
+	if task != nil {
+		rowErrs = append(rowErrs, RowError{start, errors.New("VTODO has no END")})
+	}
+	return tasks, rowErrs, nil
+}
+
+// formatICalTrigger writes a reminder's lead time as a TRIGGER before
+// the due date, e.g. -PT15M.
+func formatICalTrigger(lead time.Duration) string {
+	if lead == 0 {
+		return "PT0S"
+	}
+	s := "-P"
+	if days := lead / (24 * time.Hour); days > 0 {
+		s += strconv.Itoa(int(days)) + "D"
+		lead -= days * 24 * time.Hour
+	}
+	if lead > 0 {
+		s += "T"
+		for _, unit := range []struct {
+			d    time.Duration
+			name string
+		}{{time.Hour, "H"}, {time.Minute, "M"}, {time.Second, "S"}} {
+			if n := lead / unit.d; n > 0 {
+				s += strconv.Itoa(int(n)) + unit.name
+				lead -= n * unit.d
+			}
+		}
+	}
+	return s
+}
+
+// parseICalTrigger reads a TRIGGER at or before the due date, e.g. -PT15M
+// or -P1D, and returns how long before.
+func parseICalTrigger(s string) (time.Duration, bool) {
+	rest, ok := strings.CutPrefix(s, "-P")
+	if !ok {
+		if rest, ok = strings.CutPrefix(s, "PT0"); !ok || strings.Trim(rest, "HMS") != "" {
+			return 0, false
+		}
+		return 0, true
+	}
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour, 'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+	var lead time.Duration
+	n := -1
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c >= '0' && c <= '9':
+			n = max(n, 0)*10 + int(c-'0')
+		case c == 'T' && n < 0:
+		case units[c] > 0 && n >= 0:
+			lead += time.Duration(n) * units[c]
+			n = -1
+		default:
+			return 0, false
+		}
+	}
+	return lead, n < 0
+}
+
+// parseICalTime reads UTC, floating (local) and date-only values.
+func parseICalTime(s string) (time.Time, error) {
+	if t, err := time.Parse(icalTime, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"20060102T150405", "20060102"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not an iCalendar date", s)
+}
+
+// DuplicatePolicy decides what importing does with a task that is already
+// in the list: one with the same ID, or with the same text.
+type DuplicatePolicy int
+
+const (
+	SkipDuplicates   DuplicatePolicy = iota
+	UpdateDuplicates                 // overwrite the existing task
+	KeepDuplicates                   // import it as a new task
+)
+
+// ImportReport sums up an import.
+type ImportReport struct {
+	Added, Updated, Skipped int
+}
+
+// ImportTasks adds tasks read by ReadTasks, with a single save. Tasks
+// repeated within the imported list count as duplicates too.
+func (tm *TaskManager) ImportTasks(tasks []Task, policy DuplicatePolicy) (ImportReport, error) {
+	docs, report := tm.planImport(tasks, policy)
+	if len(docs) == 0 {
+		return report, nil
+	}
+	name := "Task imported"
+	if len(docs) > 1 {
+		name = fmt.Sprintf("%d tasks imported", len(docs))
+	}
+	if err := tm.do(name, docs); err != nil {
+		return ImportReport{}, err
+	}
+	return report, nil
+}
+
+// CountDuplicates returns how many of tasks are already in the list.
+func (tm *TaskManager) CountDuplicates(tasks []Task) int {
+	_, report := tm.planImport(tasks, SkipDuplicates)
+	return report.Skipped
+}
+
+// planImport returns the documents ImportTasks writes.
+func (tm *TaskManager) planImport(tasks []Task, policy DuplicatePolicy) ([]Doc, ImportReport) {
+	var report ImportReport
+	byID := map[string]bool{}
+	byText := map[string]string{} // normalized text -> ID
+	normalize := func(text string) string { return strings.ToLower(strings.Join(strings.Fields(text), " ")) }
+	for _, task := range tm.GetTasks() {
+		byID[task.ID] = true
+		byText[normalize(task.Text)] = task.ID
+	}
+
+	var docs []Doc
+	for _, task := range tasks {
+		existing, dup := task.ID, task.ID != "" && byID[task.ID]
+		if !dup {
+			existing, dup = byText[normalize(task.Text)]
+		}
+		switch {
+		case dup && policy == SkipDuplicates:
+			report.Skipped++
+			continue
+		case dup && policy == UpdateDuplicates:
+			task.ID = existing
+			report.Updated++
+		default:
+			// A new ID unless the file's is free, so tasks exported from
+			// another device keep theirs
+			if dup || task.ID == "" || tm.store.Version(task.ID).Rev != "" {
+				task.ID = newDocID()
+			}
+			report.Added++
+		}
+		doc := taskToDoc(task)
+		if current, err := tm.store.Get(task.ID); err == nil {
+			// Keep fields other apps added to the existing document
+			delete(current, "completedAt")
+			for k, v := range doc {
+				current[k] = v
+			}
+			doc = current
+		}
+		docs = append(docs, doc)
+		byID[task.ID] = true
+		byText[normalize(task.Text)] = task.ID
+	}
+	return docs, report
+}
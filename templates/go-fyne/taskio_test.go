@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testTasks() []Task {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	done := created.Add(time.Hour)
+	due := created.Add(48 * time.Hour)
+	return []Task{
+		{ID: "a", Text: "Buy milk, eggs; bread", Priority: "high", CreatedAt: created},
+		{ID: "b", Text: "Call \"Sam\" \\ back", Priority: "low", Completed: true, CreatedAt: created, CompletedAt: &done},
+		{ID: "c", Text: strings.Repeat("A long task ✍️ ", 10), Priority: "medium", CreatedAt: created.Add(time.Minute),
+			DueAt: &due, Repeat: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", Reminder: "1h30m"},
+	}
+}
+
+func TestTaskFormatsRoundTrip(t *testing.T) {
+	for _, name := range []string{"tasks.json", "tasks.csv", "tasks.md", "tasks.ics"} {
+		format, err := FormatForName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := WriteTasks(&buf, format, testTasks()); err != nil {
+			t.Fatal(err)
+		}
+		// RFC 5545 limits lines to 75 bytes, continuations included
+		for _, line := range strings.Split(buf.String(), "\r\n") {
+			if format == FormatICal && len(line) > 75 {
+				t.Errorf("%s: line of %d bytes: %q", name, len(line), line)
+			}
+		}
+		tasks, rowErrs, err := ReadTasks(&buf, format)
+		if err != nil || len(rowErrs) > 0 {
+			t.Fatalf("%s: %v %v", name, err, rowErrs)
+		}
+		want := testTasks()
+		if len(tasks) != len(want) {
+			t.Fatalf("%s: read %d tasks, want %d", name, len(tasks), len(want))
+		}
+		for i, got := range tasks {
+			w := want[i]
+			w.Text = strings.TrimSpace(w.Text)
+			if name == "tasks.md" {
+				w.ID = "" // checklists have no IDs
+			}
+			if got.ID != w.ID || got.Text != w.Text || got.Priority != w.Priority || got.Completed != w.Completed ||
+				!got.CreatedAt.Equal(w.CreatedAt) || (got.CompletedAt == nil) != (w.CompletedAt == nil) ||
+				(got.CompletedAt != nil && !got.CompletedAt.Equal(*w.CompletedAt)) ||
+				(got.DueAt == nil) != (w.DueAt == nil) || (got.DueAt != nil && !got.DueAt.Equal(*w.DueAt)) ||
+				got.Repeat != w.Repeat || got.Reminder != w.Reminder {
+				t.Errorf("%s: task %d = %+v, want %+v", name, i, got, w)
+			}
+		}
+	}
+	if _, err := FormatForName("tasks.txt"); err == nil {
+		t.Error("FormatForName accepted .txt")
+	}
+}
+
+func TestReadTasksRowErrors(t *testing.T) {
+	tests := []struct {
+		name, data string
+		format     TaskFormat
+		texts      []string
+		rows       []int
+	}{
+		{"json", `{"tasks": [{"text": "ok"}, {"text": ""}, 3, {"text": "bad", "priority": "urgent"}]}`, FormatJSON, []string{"ok"}, []int{2, 3, 4}},
+		{"docs", `{"docs": [{"_id": "1", "type": "todo", "text": "todo", "done": true}, {"_id": "2", "type": "note", "text": "skipped"}]}`, FormatJSON, []string{"todo"}, nil},
+		{"csv", "Text,Completed,Created\nok,yes,2024-03-01\n,no,\nbad,maybe,\nlate,no,yesterday\n", FormatCSV, []string{"ok"}, []int{3, 4, 5}},
+		{"csv quote", "text,priority\nok,low\na\"b,high\nfine,high\n", FormatCSV, []string{"ok", "fine"}, []int{3}},
+		{"markdown", "# List\n\n- [ ] ok\n- [x] 🔴 done\n- [ ]   \ntext\n- [ ] bad <!-- created soon -->\n", FormatMarkdown, []string{"ok", "done"}, []int{5, 7}},
+		{"ical", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:event\r\nEND:VEVENT\r\nBEGIN:VTODO\r\nSUMMARY:o\r\n k\r\nEND:VTODO\r\nBEGIN:VTODO\r\nPRIORITY:high\r\nSUMMARY:bad\r\nEND:VTODO\r\nBEGIN:VTODO\r\nEND:VTODO\r\nEND:VCALENDAR\r\n", FormatICal, []string{"ok"}, []int{9, 13}},
+	}
+	for _, tt := range tests {
+		tasks, rowErrs, err := ReadTasks(strings.NewReader(tt.data), tt.format)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var texts []string
+		for _, task := range tasks {
+			texts = append(texts, task.Text)
+		}
+		var rows []int
+		for _, e := range rowErrs {
+			rows = append(rows, e.Row)
+		}
+		if strings.Join(texts, "|") != strings.Join(tt.texts, "|") || len(rows) != len(tt.rows) {
+			t.Errorf("%s: tasks %q, row errors %v", tt.name, texts, rowErrs)
+			continue
+		}
+		for i := range rows {
+			if rows[i] != tt.rows[i] {
+				t.Errorf("%s: row errors %v, want rows %v", tt.name, rowErrs, tt.rows)
+				break
+			}
+		}
+	}
+
+	for _, data := range []string{"not json", "[1, 2"} {
+		if _, _, err := ReadTasks(strings.NewReader(data), FormatJSON); err == nil {
+			t.Errorf("ReadTasks(%q) succeeded", data)
+		}
+	}
+	if _, _, err := ReadTasks(strings.NewReader("name,priority\nx,low\n"), FormatCSV); err == nil {
+		t.Error("CSV without a text column was accepted")
+	}
+}
+
+func TestImportTasksDuplicates(t *testing.T) {
+	import1 := []Task{
+		{Text: "  welcome to your NEW go desktop app! 🍒", Priority: "low", CreatedAt: time.Now()},
+		{Text: "Fresh task", Priority: "high", CreatedAt: time.Now()},
+		{Text: "fresh task", Priority: "low", CreatedAt: time.Now()},
+	}
+	for _, tt := range []struct {
+		policy DuplicatePolicy
+		want   ImportReport
+		total  int
+	}{
+		{SkipDuplicates, ImportReport{Added: 1, Skipped: 2}, 3},
+		{UpdateDuplicates, ImportReport{Added: 1, Updated: 2}, 3},
+		{KeepDuplicates, ImportReport{Added: 3}, 5},
+	} {
+		tm, err := NewTaskManager("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := tm.CountDuplicates(import1); n != 2 {
+			t.Errorf("CountDuplicates = %d", n)
+		}
+		report, err := tm.ImportTasks(import1, tt.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total, _, _ := tm.GetStats(); report != tt.want || total != tt.total {
+			t.Errorf("policy %d: report %+v with %d tasks", tt.policy, report, total)
+		}
+		if tt.policy == UpdateDuplicates {
+			for _, task := range tm.GetTasks() {
+				if strings.HasPrefix(task.Text, "welcome") && task.Priority != "low" {
+					t.Errorf("updated task = %+v", task)
+				}
+			}
+		}
+	}
+
+	// Tasks exported from another device keep their IDs, and are
+	// duplicates the next time
+	tm, _ := NewTaskManager("")
+	tasks := []Task{{ID: "remote-1", Text: "From the laptop", Priority: "medium", CreatedAt: time.Now()}}
+	if report, _ := tm.ImportTasks(tasks, SkipDuplicates); report.Added != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	tasks[0].Text = "Renamed on the laptop"
+	if report, _ := tm.ImportTasks(tasks, UpdateDuplicates); report.Updated != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	if doc, err := tm.store.Get("remote-1"); err != nil || doc["text"] != "Renamed on the laptop" {
+		t.Errorf("doc = %v, %v", doc, err)
+	}
+}
+
+func TestImportSummary(t *testing.T) {
+	rowErrs := make([]RowError, 12)
+	for i := range rowErrs {
+		rowErrs[i] = RowError{i + 2, ErrDocNotFound}
+	}
+	summary := importSummary(ImportReport{Added: 3, Skipped: 1}, rowErrs)
+	for _, want := range []string{"Added 3", "Skipped 1", "12 rows", "row 2: ", "...and 2 more"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary %q lacks %q", summary, want)
+		}
+	}
+}
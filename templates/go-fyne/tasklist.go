@@ -0,0 +1,228 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TaskFilter selects the tasks a TaskList shows.
+type TaskFilter int
+
+const (
+	FilterAll TaskFilter = iota
+	FilterPending
+	FilterCompleted
+	FilterOverdue  // open tasks past their due date
+	FilterUpcoming // open tasks due within upcomingDays
+)
+
+// upcomingDays is how far ahead FilterUpcoming looks.
+const upcomingDays = 7
+
+func (f TaskFilter) match(task Task, now time.Time) bool {
+	switch f {
+	case FilterPending:
+		return !task.Completed
+	case FilterCompleted:
+		return task.Completed
+	case FilterOverdue:
+		return task.overdue(now)
+	case FilterUpcoming:
+		return !task.Completed && task.DueAt != nil && !task.DueAt.Before(now) && task.DueAt.Before(now.AddDate(0, 0, upcomingDays))
+	}
+	return true
+}
+
+// TaskSort orders a TaskList.
+type TaskSort int
+
+const (
+	SortOldest TaskSort = iota
+	SortNewest
+	SortPriority // high first, then oldest first
+	SortText
+	SortDue // soonest due first, then tasks without a due date
+)
+
+// compare orders two tasks; ties are broken by ID, so every task has
+// exactly one place in a list.
+func (s TaskSort) compare(a, b Task) int {
+	var c int
+	switch s {
+	case SortNewest:
+		c = -compareCreated(a, b)
+	case SortPriority:
+		if c = cmp.Compare(priorityRank(b.Priority), priorityRank(a.Priority)); c == 0 {
+			c = compareCreated(a, b)
+		}
+	case SortText:
+		c = strings.Compare(strings.ToLower(a.Text), strings.ToLower(b.Text))
+	case SortDue:
+		switch {
+		case a.DueAt == nil && b.DueAt == nil:
+			c = compareCreated(a, b)
+		case a.DueAt == nil:
+			c = 1
+		case b.DueAt == nil:
+			c = -1
+		default:
+			c = cmp.Compare(a.DueAt.UnixMilli(), b.DueAt.UnixMilli())
+		}
+	default:
+		c = compareCreated(a, b)
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+func compareCreated(a, b Task) int {
+	return cmp.Compare(a.CreatedAt.UnixMilli(), b.CreatedAt.UnixMilli())
+}
+
+func priorityRank(priority string) int {
+	switch priority {
+	case "high":
+		return 3
+	case "medium":
+		return 2
+	case "low":
+		return 1
+	}
+	return 0
+}
+
+// TaskList is a live view of the tasks that match a filter, in a sort
+// order. It follows the manager's changes one task at a time, moving only
+// the task that changed, so a list of thousands of tasks stays cheap to
+// keep up to date. It backs the virtualized widget.List of the window.
+type TaskList struct {
+	mu        sync.Mutex
+	filter    TaskFilter
+	sort      TaskSort
+	items     []Task
+	listeners []func()
+	cancel    func()
+	manager   *TaskManager
+}
+
+// NewTaskList returns a view of the manager's tasks. Close it when it is
+// no longer shown.
+func NewTaskList(tm *TaskManager, filter TaskFilter, sort TaskSort) *TaskList {
+	l := &TaskList{manager: tm, filter: filter, sort: sort}
+	l.cancel = tm.Subscribe(l.apply)
+	l.rebuild()
+	return l
+}
+
+// Close stops following the manager.
+func (l *TaskList) Close() {
+	l.cancel()
+}
+
+// OnChange calls fn after the list changed, from the writing goroutine.
+func (l *TaskList) OnChange(fn func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.listeners = append(l.listeners, fn)
+}
+
+// Len returns the number of tasks in the list.
+func (l *TaskList) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.items)
+}
+
+// At returns the task at index i; ok is false if the list got shorter.
+func (l *TaskList) At(i int) (task Task, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if i < 0 || i >= len(l.items) {
+		return Task{}, false
+	}
+	return l.items[i], true
+}
+
+// Filter returns the current filter.
+func (l *TaskList) Filter() TaskFilter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.filter
+}
+
+// SetFilter shows the tasks that match filter.
+func (l *TaskList) SetFilter(filter TaskFilter) {
+	l.mu.Lock()
+	l.filter = filter
+	l.mu.Unlock()
+	l.rebuild()
+}
+
+// SetSort changes the sort order.
+func (l *TaskList) SetSort(sort TaskSort) {
+	l.mu.Lock()
+	l.sort = sort
+	l.mu.Unlock()
+	l.rebuild()
+}
+
+// Refresh rebuilds the list. The overdue and upcoming filters depend on
+// the time, so call it as time passes.
+func (l *TaskList) Refresh() {
+	l.rebuild()
+}
+
+// rebuild fills the list from scratch, after the filter or sort changed.
+func (l *TaskList) rebuild() {
+	l.mu.Lock()
+	now := time.Now()
+	tasks := l.manager.GetTasks()
+	items := tasks[:0]
+	for _, task := range tasks {
+		if l.filter.match(task, now) {
+			items = append(items, task)
+		}
+	}
+	slices.SortFunc(items, l.sort.compare)
+	l.items = items
+	l.mu.Unlock()
+	l.notify()
+}
+
+// apply moves the one task that changed. A change that arrived while the
+// list was rebuilt may already be in it. The old version is looked for
+// whether or not it matches the filter now, as time filters change.
+func (l *TaskList) apply(c TaskChange) {
+	l.mu.Lock()
+	changed := false
+	if c.Old != nil {
+		if i, found := slices.BinarySearchFunc(l.items, *c.Old, l.sort.compare); found {
+			l.items = slices.Delete(l.items, i, i+1)
+			changed = true
+		}
+	}
+	if c.New != nil && l.filter.match(*c.New, time.Now()) {
+		if i, found := slices.BinarySearchFunc(l.items, *c.New, l.sort.compare); !found {
+			l.items = slices.Insert(l.items, i, *c.New)
+			changed = true
+		}
+	}
+	l.mu.Unlock()
+	if changed {
+		l.notify()
+	}
+}
+
+func (l *TaskList) notify() {
+	l.mu.Lock()
+	listeners := slices.Clone(l.listeners)
+	l.mu.Unlock()
+	for _, fn := range listeners {
+		fn()
+	}
+}
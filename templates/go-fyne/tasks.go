@@ -0,0 +1,418 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Task represents a single task item
+type Task struct {
+	ID          string     `json:"id"`
+	Text        string     `json:"text"`
+	Completed   bool       `json:"completed"`
+	Priority    string     `json:"priority"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	DueAt       *time.Time `json:"dueAt,omitempty"`
+	Repeat      string     `json:"repeat,omitempty"`   // an RRULE, see Recurrence
+	Reminder    string     `json:"reminder,omitempty"` // how long before DueAt to remind, e.g. "15m"
+}
+
+// remindAt returns when to send the task's reminder, if it has one.
+func (t Task) remindAt() (time.Time, bool) {
+	if t.Completed || t.DueAt == nil || t.Reminder == "" {
+		return time.Time{}, false
+	}
+	lead, err := time.ParseDuration(t.Reminder)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.DueAt.Add(-lead), true
+}
+
+// overdue reports whether the task is still open after its due date.
+func (t Task) overdue(now time.Time) bool {
+	return !t.Completed && t.DueAt != nil && t.DueAt.Before(now)
+}
+
+// checkTask validates a task before it is stored and fills in defaults.
+func checkTask(task *Task) error {
+	task.Text = strings.TrimSpace(task.Text)
+	if task.Text == "" {
+		return errors.New("task has no text")
+	}
+	switch task.Priority {
+	case "low", "medium", "high":
+	case "":
+		task.Priority = "medium"
+	default:
+		return fmt.Errorf("unknown priority %q", task.Priority)
+	}
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now()
+	}
+	if !task.Completed {
+		task.CompletedAt = nil
+	}
+	if task.Repeat != "" {
+		r, err := ParseRecurrence(task.Repeat)
+		if err != nil {
+			return fmt.Errorf("repeat: %w", err)
+		}
+		if task.DueAt == nil {
+			return errors.New("a repeating task needs a due date")
+		}
+		task.Repeat = r.String()
+	}
+	if task.Reminder != "" {
+		lead, err := time.ParseDuration(task.Reminder)
+		if err != nil || lead < 0 {
+			return fmt.Errorf("reminder %q is not a time like 15m or 1h", task.Reminder)
+		}
+		if task.DueAt == nil {
+			return errors.New("a reminder needs a due date")
+		}
+		task.Reminder = shortDuration(lead)
+	}
+	return nil
+}
+
+// taskType is the document type of tasks. Tasks are stored like the todos
+// of the web templates ({_id, type: "todo", text, done, created}), so a
+// list exported from one imports into the other.
+const taskType = "todo"
+
+// taskToDoc converts a task to its document.
+func taskToDoc(task Task) Doc {
+	doc := Doc{
+		"_id":      task.ID,
+		"type":     taskType,
+		"text":     task.Text,
+		"done":     task.Completed,
+		"priority": task.Priority,
+		"created":  task.CreatedAt.UnixMilli(),
+	}
+	if task.CompletedAt != nil {
+		doc["completedAt"] = task.CompletedAt.UnixMilli()
+	}
+	if task.DueAt != nil {
+		doc["due"] = task.DueAt.UnixMilli()
+	}
+	if task.Repeat != "" {
+		doc["repeat"] = task.Repeat
+	}
+	if task.Reminder != "" {
+		doc["reminder"] = task.Reminder
+	}
+	return doc
+}
+
+// docToTask converts a document to a task; missing fields stay empty.
+func docToTask(doc Doc) Task {
+	task := Task{ID: doc.ID()}
+	task.Text, _ = doc["text"].(string)
+	task.Completed, _ = doc["done"].(bool)
+	task.Priority, _ = doc["priority"].(string)
+	if ms, ok := doc["created"].(float64); ok {
+		task.CreatedAt = time.UnixMilli(int64(ms))
+	}
+	if ms, ok := doc["completedAt"].(float64); ok {
+		completedAt := time.UnixMilli(int64(ms))
+		task.CompletedAt = &completedAt
+	}
+	if ms, ok := doc["due"].(float64); ok {
+		due := time.UnixMilli(int64(ms))
+		task.DueAt = &due
+	}
+	task.Repeat, _ = doc["repeat"].(string)
+	task.Reminder, _ = doc["reminder"].(string)
+	return task
+}
+
+// TaskManager handles task operations. Tasks are documents in a DocStore,
+// which saves them after every change. The manager follows the store's
+// change feed, so it also sees tasks changed by sync, and keeps the tasks
+// and their counts in memory for the views. Every change it makes is a
+// Command that can be undone (see history.go).
+type TaskManager struct {
+	store   *DocStore
+	history history
+
+	mu        sync.Mutex
+	tasks     map[string]Task
+	completed int
+	subs      map[int]func(TaskChange)
+	nextSub   int
+}
+
+// TaskChange is one change of a task. Old is nil for a new task, New is
+// nil for a deleted one.
+type TaskChange struct {
+	Old, New *Task
+}
+
+// NewTaskManager opens the tasks stored at path, or starts with the
+// welcome tasks the first time. An empty path keeps the tasks in memory
+// only. Tasks saved by earlier versions of the template in tasks.json next
+// to path are moved over.
+func NewTaskManager(path string) (*TaskManager, error) {
+	store, err := OpenDocStore(path, "{{PROJECT_SLUG}}")
+	if err != nil {
+		return nil, err
+	}
+	tm := &TaskManager{store: store, tasks: map[string]Task{}, subs: map[int]func(TaskChange){}}
+	for _, doc := range store.Query("type", taskType) {
+		task := docToTask(doc)
+		tm.tasks[task.ID] = task
+		if task.Completed {
+			tm.completed++
+		}
+	}
+	store.Subscribe(tm.apply)
+	if store.Seq() > 0 {
+		return tm, nil
+	}
+
+	if path != "" {
+		legacy := filepath.Join(filepath.Dir(path), "tasks.json")
+		if migrated, err := tm.migrateTasksFile(legacy); err != nil {
+			return nil, err
+		} else if migrated {
+			return tm, nil
+		}
+	}
+
+	for _, task := range []Task{
+		{Text: "Welcome to your new Go desktop app! 🍒", Priority: "high"},
+		{Text: "This is a native desktop application", Priority: "medium"},
+	} {
+		if err := tm.AddTask(task.Text, task.Priority); err != nil {
+			return nil, err
+		}
+	}
+	tm.clearHistory()
+	return tm, nil
+}
+
+// migrateTasksFile imports a tasks.json written before tasks were
+// documents and renames it to tasks.json.migrated.
+func (tm *TaskManager) migrateTasksFile(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var file struct {
+		Tasks []Task `json:"tasks"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		log.Printf("could not read %s, leaving it alone: %v", path, err)
+		return false, nil
+	}
+
+	docs := make([]Doc, len(file.Tasks))
+	for i, task := range file.Tasks {
+		task.ID = "" // the old IDs were only unique within the file
+		docs[i] = taskToDoc(task)
+	}
+	if _, err := tm.store.Bulk(docs); err != nil {
+		return false, err
+	}
+	return true, os.Rename(path, path+".migrated")
+}
+
+func (tm *TaskManager) AddTask(text, priority string) error {
+	return tm.Add(Task{Text: text, Priority: priority})
+}
+
+// Add stores a new task, e.g. one with a due date.
+func (tm *TaskManager) Add(task Task) error {
+	task.ID = ""
+	if err := checkTask(&task); err != nil {
+		return err
+	}
+	return tm.do("Task added", []Doc{taskToDoc(task)})
+}
+
+// ToggleTask flips a task between done and not done. Fields the app
+// doesn't know, e.g. added by a web cherry, are kept. Completing a
+// repeating task adds its next occurrence.
+func (tm *TaskManager) ToggleTask(id string) error {
+	doc, err := tm.store.Get(id)
+	if errors.Is(err, ErrDocNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	done, _ := doc["done"].(bool)
+	doc["done"] = !done
+	if !done {
+		doc["completedAt"] = time.Now().UnixMilli()
+	} else {
+		delete(doc, "completedAt")
+	}
+	if done {
+		return tm.do("Task reopened", []Doc{doc})
+	}
+	docs := []Doc{doc}
+	if next, ok := nextOccurrence(docToTask(doc), time.Now()); ok {
+		docs = append(docs, taskToDoc(next))
+	}
+	return tm.do("Task completed", docs)
+}
+
+// nextOccurrence returns the task that follows a repeating task: due at
+// the first occurrence after both its due date and now, so a task done
+// late doesn't come back overdue.
+func nextOccurrence(task Task, now time.Time) (Task, bool) {
+	if task.Repeat == "" || task.DueAt == nil {
+		return Task{}, false
+	}
+	r, err := ParseRecurrence(task.Repeat)
+	if err != nil {
+		return Task{}, false
+	}
+	due, ok := r.Next(*task.DueAt, maxTime(*task.DueAt, now))
+	if !ok {
+		return Task{}, false
+	}
+	return Task{
+		ID:        newDocID(),
+		Text:      task.Text,
+		Priority:  task.Priority,
+		CreatedAt: now,
+		DueAt:     &due,
+		Repeat:    task.Repeat,
+		Reminder:  task.Reminder,
+	}, true
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// SetSchedule sets a task's due date, repeat rule and reminder; a nil due
+// clears all three.
+func (tm *TaskManager) SetSchedule(id string, due *time.Time, repeat, reminder string) error {
+	doc, err := tm.store.Get(id)
+	if err != nil {
+		return err
+	}
+	task := docToTask(doc)
+	task.DueAt, task.Repeat, task.Reminder = due, repeat, reminder
+	if due == nil {
+		task.Repeat, task.Reminder = "", ""
+	}
+	if err := checkTask(&task); err != nil {
+		return err
+	}
+	delete(doc, "due")
+	delete(doc, "repeat")
+	delete(doc, "reminder")
+	for k, v := range taskToDoc(task) {
+		doc[k] = v
+	}
+	return tm.do("Schedule changed", []Doc{doc})
+}
+
+func (tm *TaskManager) DeleteTask(id string) error {
+	if _, ok := tm.Task(id); !ok {
+		return nil
+	}
+	return tm.do("Task deleted", []Doc{{"_id": id, "_deleted": true}})
+}
+
+// GetTasks returns the tasks, oldest first.
+func (tm *TaskManager) GetTasks() []Task {
+	tm.mu.Lock()
+	tasks := make([]Task, 0, len(tm.tasks))
+	for _, task := range tm.tasks {
+		tasks = append(tasks, task)
+	}
+	tm.mu.Unlock()
+	slices.SortFunc(tasks, SortOldest.compare)
+	return tasks
+}
+
+// Task returns the task with the given ID.
+func (tm *TaskManager) Task(id string) (Task, bool) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	task, ok := tm.tasks[id]
+	return task, ok
+}
+
+func (tm *TaskManager) GetStats() (total, completed, pending int) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return len(tm.tasks), tm.completed, len(tm.tasks) - tm.completed
+}
+
+// Subscribe calls fn after every task change until cancel is called. Like
+// DocStore.Subscribe, fn runs on the writing goroutine.
+func (tm *TaskManager) Subscribe(fn func(TaskChange)) (cancel func()) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	id := tm.nextSub
+	tm.nextSub++
+	tm.subs[id] = fn
+	return func() {
+		tm.mu.Lock()
+		defer tm.mu.Unlock()
+		delete(tm.subs, id)
+	}
+}
+
+// apply updates the tasks in memory after a store change and passes it on
+// to subscribers.
+func (tm *TaskManager) apply(c Change) {
+	var next *Task
+	if !c.Deleted && c.Doc["type"] == taskType {
+		task := docToTask(c.Doc)
+		next = &task
+	}
+
+	tm.mu.Lock()
+	old, had := tm.tasks[c.ID]
+	if !had && next == nil {
+		tm.mu.Unlock()
+		return
+	}
+	change := TaskChange{New: next}
+	if had {
+		change.Old = &old
+		if old.Completed {
+			tm.completed--
+		}
+		delete(tm.tasks, c.ID)
+	}
+	if next != nil {
+		tm.tasks[c.ID] = *next
+		if next.Completed {
+			tm.completed++
+		}
+	}
+	subs := make([]func(TaskChange), 0, len(tm.subs))
+	for _, fn := range tm.subs {
+		subs = append(subs, fn)
+	}
+	tm.mu.Unlock()
+
+	for _, fn := range subs {
+		fn(change)
+	}
+}
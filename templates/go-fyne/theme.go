@@ -0,0 +1,461 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// ThemeFile is a theme as JSON: colors, sizes and fonts, by their Fyne
+// names, e.g.
+//
+//	{
+//	  "colors": {"primary": "#8e24aa"},
+//	  "light": {"background": "#fbf5fc"},
+//	  "dark": {"background": "#1a0f1c", "inputBackground": "#2a1a2d"},
+//	  "sizes": {"padding": 8, "inputRadius": 12},
+//	  "fonts": {"regular": "fonts/Inter-Regular.ttf"}
+//	}
+//
+// colors apply to both variants, light and dark to one of them. Colors are
+// written #rgb, #rrggbb or #rrggbbaa. Font paths are relative to the file.
+// Whatever the file leaves out comes from Fyne's default theme.
+type ThemeFile struct {
+	Variant string             `json:"variant,omitempty"` // "light" or "dark" if the theme has only one
+	Colors  map[string]string  `json:"colors,omitempty"`
+	Light   map[string]string  `json:"light,omitempty"`
+	Dark    map[string]string  `json:"dark,omitempty"`
+	Sizes   map[string]float32 `json:"sizes,omitempty"`
+	Fonts   map[string]string  `json:"fonts,omitempty"` // regular, bold, italic, boldItalic, monospace, symbol
+}
+
+// Names of the built-in themes
+const (
+	themeCherry       = "cherry"
+	themeHighContrast = "high-contrast"
+)
+
+// builtinThemes are the themes that need no file: the FileCherry look, and
+// a high contrast one.
+var builtinThemes = map[string]*ThemeFile{
+	themeCherry: {
+		Dark: map[string]string{
+			"primary":           "#ff1744",
+			"background":        "#1a0a0a",
+			"overlayBackground": "#000000c8",
+			"success":           "#4caf50",
+			"warning":           "#ff9800",
+			"error":             "#f44336",
+			"disabled":          "#646464",
+			"hover":             "#ff174432",
+			"focus":             "#ff174464",
+			"selection":         "#ff174496",
+			"separator":         "#3c3c3c",
+			"inputBackground":   "#2d1515",
+			"inputBorder":       "#505050",
+			"foreground":        "#ffffff",
+		},
+		Light: map[string]string{
+			"primary":           "#d50032",
+			"background":        "#fff8f9",
+			"overlayBackground": "#ffffff",
+			"success":           "#2e7d32",
+			"warning":           "#ef6c00",
+			"error":             "#c62828",
+			"disabled":          "#9e9e9e",
+			"hover":             "#d5003220",
+			"focus":             "#d5003240",
+			"selection":         "#d5003260",
+			"separator":         "#eadadd",
+			"inputBackground":   "#fcecef",
+			"inputBorder":       "#d9c3c7",
+			"foreground":        "#1a0a0a",
+		},
+		Sizes: map[string]float32{
+			"padding":         12,
+			"scrollBar":       8,
+			"scrollBarSmall":  4,
+			"separator":       1,
+			"inputBorder":     2,
+			"inputRadius":     8,
+			"selectionRadius": 6,
+		},
+	},
+	themeHighContrast: {
+		Dark: map[string]string{
+			"primary":           "#ffd600",
+			"background":        "#000000",
+			"overlayBackground": "#000000",
+			"inputBackground":   "#000000",
+			"inputBorder":       "#ffffff",
+			"separator":         "#ffffff",
+			"foreground":        "#ffffff",
+			"placeholder":       "#e0e0e0",
+			"disabled":          "#bdbdbd",
+			"hover":             "#ffd60040",
+			"focus":             "#ffd600",
+			"selection":         "#ffd60080",
+			"success":           "#69f0ae",
+			"warning":           "#ffab40",
+			"error":             "#ff5252",
+		},
+		Light: map[string]string{
+			"primary":           "#0000c8",
+			"background":        "#ffffff",
+			"overlayBackground": "#ffffff",
+			"inputBackground":   "#ffffff",
+			"inputBorder":       "#000000",
+			"separator":         "#000000",
+			"foreground":        "#000000",
+			"placeholder":       "#333333",
+			"disabled":          "#555555",
+			"hover":             "#0000c830",
+			"focus":             "#0000c8",
+			"selection":         "#0000c860",
+			"success":           "#006400",
+			"warning":           "#8a4b00",
+			"error":             "#b00020",
+		},
+		Sizes: map[string]float32{
+			"inputBorder": 3,
+			"separator":   2,
+		},
+	},
+}
+
+// themeFonts are the keys of ThemeFile.Fonts.
+var themeFonts = []string{"regular", "bold", "italic", "boldItalic", "monospace", "symbol"}
+
+// ThemeNames returns the built-in themes and the theme files in dir,
+// e.g. "plum" for dir/plum.json.
+func ThemeNames(dir string) []string {
+	names := []string{themeCherry, themeHighContrast}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	sort.Strings(files)
+	for _, f := range files {
+		if name := strings.TrimSuffix(filepath.Base(f), ".json"); builtinThemes[name] == nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ReadThemeFile reads the theme file at path.
+func ReadThemeFile(path string) (*ThemeFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f ThemeFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &f, nil
+}
+
+// parseColor reads a color written #rgb, #rrggbb or #rrggbbaa.
+func parseColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || err != nil {
+		return color.NRGBA{}, fmt.Errorf("%q is not a color like #ff1744", s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// compiledTheme is a ThemeFile ready to draw with.
+type compiledTheme struct {
+	variant string
+	colors  map[fyne.ThemeVariant]map[fyne.ThemeColorName]color.Color
+	sizes   map[fyne.ThemeSizeName]float32
+	fonts   map[string]fyne.Resource
+}
+
+// compileTheme checks f and loads its fonts, relative to dir.
+func compileTheme(f *ThemeFile, dir string) (*compiledTheme, error) {
+	c := &compiledTheme{
+		variant: f.Variant,
+		colors: map[fyne.ThemeVariant]map[fyne.ThemeColorName]color.Color{
+			theme.VariantLight: {},
+			theme.VariantDark:  {},
+		},
+		sizes: map[fyne.ThemeSizeName]float32{},
+		fonts: map[string]fyne.Resource{},
+	}
+	if f.Variant != "" && f.Variant != "light" && f.Variant != "dark" {
+		return nil, fmt.Errorf("variant %q is not light or dark", f.Variant)
+	}
+	for _, set := range []struct {
+		colors   map[string]string
+		variants []fyne.ThemeVariant
+	}{
+		{f.Colors, []fyne.ThemeVariant{theme.VariantLight, theme.VariantDark}},
+		{f.Light, []fyne.ThemeVariant{theme.VariantLight}},
+		{f.Dark, []fyne.ThemeVariant{theme.VariantDark}},
+	} {
+		for name, s := range set.colors {
+			col, err := parseColor(s)
+			if err != nil {
+				return nil, fmt.Errorf("color %s: %v", name, err)
+			}
+			for _, v := range set.variants {
+				c.colors[v][fyne.ThemeColorName(name)] = col
+			}
+		}
+	}
+	for name, size := range f.Sizes {
+		if size < 0 {
+			return nil, fmt.Errorf("size %s is negative", name)
+		}
+		c.sizes[fyne.ThemeSizeName(name)] = size
+	}
+	for key, path := range f.Fonts {
+		if !slices.Contains(themeFonts, key) {
+			return nil, fmt.Errorf("font %q is not one of %s", key, strings.Join(themeFonts, ", "))
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("font %s: %v", key, err)
+		}
+		c.fonts[key] = fyne.NewStaticResource(filepath.Base(path), data)
+	}
+	return c, nil
+}
+
+// FileCherryTheme is the FileCherry look: a built-in theme or a theme
+// file, in light or dark as the system setting says, unless the user or
+// the theme picks one. A theme file is reloaded when it changes, see Watch.
+type FileCherryTheme struct {
+	mu      sync.RWMutex
+	name    string // as passed to Use
+	current *compiledTheme
+	mode    string    // "light" or "dark", or empty to follow the system
+	path    string    // the theme file, or empty for a built-in theme
+	modTime time.Time // of the theme file when it was loaded
+}
+
+// NewFileCherryTheme returns the built-in cherry theme, following the
+// system's light or dark setting.
+func NewFileCherryTheme() *FileCherryTheme {
+	t := &FileCherryTheme{name: themeCherry}
+	t.current, _ = compileTheme(builtinThemes[themeCherry], "")
+	return t
+}
+
+// Use switches to the theme called name: a built-in one or dir/name.json.
+func (t *FileCherryTheme) Use(name, dir string) error {
+	if f := builtinThemes[name]; f != nil {
+		c, err := compileTheme(f, "")
+		if err != nil {
+			return err
+		}
+		t.mu.Lock()
+		t.name, t.current, t.path, t.modTime = name, c, "", time.Time{}
+		t.mu.Unlock()
+		return nil
+	}
+	return t.LoadFile(filepath.Join(dir, name+".json"))
+}
+
+// LoadFile switches to the theme file at path. If it can't be read, the
+// theme stays as it was.
+func (t *FileCherryTheme) LoadFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	f, err := ReadThemeFile(path)
+	if err != nil {
+		return err
+	}
+	c, err := compileTheme(f, filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	t.mu.Lock()
+	t.name = strings.TrimSuffix(filepath.Base(path), ".json")
+	t.current, t.path, t.modTime = c, path, info.ModTime()
+	t.mu.Unlock()
+	return nil
+}
+
+// Name returns the name of the theme in use, e.g. "cherry" or "plum" for
+// plum.json.
+func (t *FileCherryTheme) Name() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.name
+}
+
+// SetMode picks "light" or "dark"; "system" or empty follows the system
+// setting. A theme with only one variant keeps it.
+func (t *FileCherryTheme) SetMode(mode string) {
+	if mode == "system" {
+		mode = ""
+	}
+	t.mu.Lock()
+	t.mode = mode
+	t.mu.Unlock()
+}
+
+// Mode returns "light", "dark" or "system".
+func (t *FileCherryTheme) Mode() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.mode == "" {
+		return "system"
+	}
+	return t.mode
+}
+
+// Watch reloads the theme file whenever it changes, every interval, and
+// redraws the app with it. It returns when ctx is done.
+func (t *FileCherryTheme) Watch(ctx context.Context, a fyne.App, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		t.mu.RLock()
+		path, loaded := t.path, t.modTime
+		t.mu.RUnlock()
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || info.ModTime().Equal(loaded) {
+			continue
+		}
+		if err := t.LoadFile(path); err != nil {
+			log.Println("theme not reloaded:", err)
+			continue
+		}
+		a.Settings().SetTheme(t)
+	}
+}
+
+// variant resolves the variant to draw: the theme's own, the user's
+// choice, or the system's.
+func (t *FileCherryTheme) variant(system fyne.ThemeVariant) fyne.ThemeVariant {
+	mode := t.mode
+	if t.current.variant != "" {
+		mode = t.current.variant
+	}
+	switch mode {
+	case "light":
+		return theme.VariantLight
+	case "dark":
+		return theme.VariantDark
+	}
+	return system
+}
+
+// Color returns the color for the given theme color name
+func (t *FileCherryTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	variant = t.variant(variant)
+	if c, ok := t.current.colors[variant][name]; ok {
+		return c
+	}
+	return theme.DefaultTheme().Color(name, variant)
+}
+
+// Size returns the size for the given theme size name
+func (t *FileCherryTheme) Size(name fyne.ThemeSizeName) float32 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if s, ok := t.current.sizes[name]; ok {
+		return s
+	}
+	return theme.DefaultTheme().Size(name)
+}
+
+// Font returns the font for the given text style
+func (t *FileCherryTheme) Font(style fyne.TextStyle) fyne.Resource {
+	key := "regular"
+	switch {
+	case style.Monospace:
+		key = "monospace"
+	case style.Symbol:
+		key = "symbol"
+	case style.Bold && style.Italic:
+		key = "boldItalic"
+	case style.Bold:
+		key = "bold"
+	case style.Italic:
+		key = "italic"
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if f, ok := t.current.fonts[key]; ok {
+		return f
+	}
+	return theme.DefaultTheme().Font(style)
+}
+
+// Icon returns the icon for the given theme icon name
+func (t *FileCherryTheme) Icon(name fyne.ThemeIconName) fyne.Resource {
+	return theme.DefaultTheme().Icon(name)
+}
+
+// themeModes labels the modes of SetMode in the UI, in English.
+var themeModes = []struct{ mode, name string }{
+	{"system", "🌓 Follow System Theme"},
+	{"light", "☀️ Light Theme"},
+	{"dark", "🌙 Dark Theme"},
+}
+
+// Actions are the commands that switch the mode and the theme, one per
+// theme in ThemeNames(dir), for the shortcuts and the command palette.
+// save is called with the theme and mode after every switch, to remember
+// them.
+func (t *FileCherryTheme) Actions(a fyne.App, dir string, save func(name, mode string)) []*Action {
+	apply := func() {
+		a.Settings().SetTheme(t)
+		save(t.Name(), t.Mode())
+	}
+	var actions []*Action
+	for _, m := range themeModes {
+		m := m
+		actions = append(actions, &Action{ID: "theme." + m.mode, Name: T(m.name), Run: func() {
+			t.SetMode(m.mode)
+			apply()
+		}})
+	}
+	for _, n := range ThemeNames(dir) {
+		n := n
+		actions = append(actions, &Action{ID: "theme.use." + n, Name: T("🎨 Use the %s Theme", n), Run: func() {
+			if err := t.Use(n, dir); err != nil {
+				log.Println("theme:", err)
+				return
+			}
+			apply()
+		}})
+	}
+	return actions
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"image/color"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/theme"
+)
+
+func writeTheme(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	for s, want := range map[string]color.NRGBA{
+		"#ff1744":   {R: 0xff, G: 0x17, B: 0x44, A: 0xff},
+		"#ff174432": {R: 0xff, G: 0x17, B: 0x44, A: 0x32},
+		"#fff":      {R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+		" 1a0a0a ":  {R: 0x1a, G: 0x0a, B: 0x0a, A: 0xff},
+	} {
+		if got, err := parseColor(s); err != nil || got != want {
+			t.Errorf("parseColor(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "#ff17", "red", "#gg1744"} {
+		if _, err := parseColor(s); err == nil {
+			t.Errorf("parseColor(%q) succeeded", s)
+		}
+	}
+}
+
+func TestThemeVariants(t *testing.T) {
+	th := NewFileCherryTheme()
+	bg := func(v fyne.ThemeVariant) color.Color { return th.Color(theme.ColorNameBackground, v) }
+	dark, light := color.NRGBA{R: 0x1a, G: 0x0a, B: 0x0a, A: 0xff}, color.NRGBA{R: 0xff, G: 0xf8, B: 0xf9, A: 0xff}
+
+	// Following the system
+	if th.Name() != "cherry" || th.Mode() != "system" {
+		t.Errorf("theme %q, mode %q", th.Name(), th.Mode())
+	}
+	if bg(theme.VariantDark) != dark || bg(theme.VariantLight) != light {
+		t.Errorf("background dark %v, light %v", bg(theme.VariantDark), bg(theme.VariantLight))
+	}
+	// Colors the theme leaves out come from Fyne's theme, in the same variant
+	if got, want := th.Color(theme.ColorNameShadow, theme.VariantLight), theme.DefaultTheme().Color(theme.ColorNameShadow, theme.VariantLight); got != want {
+		t.Errorf("shadow %v, want %v", got, want)
+	}
+	if th.Size(theme.SizeNamePadding) != 12 || th.Size(theme.SizeNameText) != theme.DefaultTheme().Size(theme.SizeNameText) {
+		t.Errorf("padding %v, text %v", th.Size(theme.SizeNamePadding), th.Size(theme.SizeNameText))
+	}
+
+	// The user's choice wins over the system
+	th.SetMode("light")
+	if bg(theme.VariantDark) != light {
+		t.Errorf("light mode draws %v", bg(theme.VariantDark))
+	}
+	th.SetMode("system")
+	if th.Mode() != "system" || bg(theme.VariantDark) != dark {
+		t.Errorf("mode %q draws %v", th.Mode(), bg(theme.VariantDark))
+	}
+
+	if err := th.Use(themeHighContrast, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if got := th.Color(theme.ColorNameForeground, theme.VariantDark); got != (color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Errorf("high contrast foreground %v", got)
+	}
+	if err := th.Use("missing", t.TempDir()); err == nil || th.Name() != themeHighContrast {
+		t.Errorf("using a missing theme: %v, now %q", err, th.Name())
+	}
+}
+
+func TestThemeFile(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "fonts"), 0755)
+	writeTheme(t, filepath.Join(dir, "fonts", "Mono.ttf"), "not really a font")
+	writeTheme(t, filepath.Join(dir, "plum.json"), `{
+		"variant": "dark",
+		"colors": {"primary": "#8e24aa", "background": "#fbf5fc"},
+		"dark": {"background": "#1a0f1c"},
+		"sizes": {"padding": 6},
+		"fonts": {"monospace": "fonts/Mono.ttf"}
+	}`)
+
+	th := NewFileCherryTheme()
+	th.SetMode("light")
+	if err := th.Use("plum", dir); err != nil {
+		t.Fatal(err)
+	}
+	// The theme has only a dark variant, the user's light mode can't change it
+	if got := th.Color(theme.ColorNameBackground, theme.VariantLight); got != (color.NRGBA{R: 0x1a, G: 0x0f, B: 0x1c, A: 0xff}) {
+		t.Errorf("background %v", got)
+	}
+	if got := th.Color(theme.ColorNamePrimary, theme.VariantLight); got != (color.NRGBA{R: 0x8e, G: 0x24, B: 0xaa, A: 0xff}) {
+		t.Errorf("primary %v", got)
+	}
+	if th.Size(theme.SizeNamePadding) != 6 || th.Size(theme.SizeNameInputRadius) != theme.DefaultTheme().Size(theme.SizeNameInputRadius) {
+		t.Errorf("padding %v, input radius %v", th.Size(theme.SizeNamePadding), th.Size(theme.SizeNameInputRadius))
+	}
+	if font := th.Font(fyne.TextStyle{Monospace: true}); string(font.Content()) != "not really a font" {
+		t.Errorf("monospace font %q", font.Name())
+	}
+	if th.Font(fyne.TextStyle{Bold: true}) != theme.DefaultTheme().Font(fyne.TextStyle{Bold: true}) {
+		t.Error("the bold font is not Fyne's")
+	}
+	if names := ThemeNames(dir); !slices.Equal(names, []string{"cherry", "high-contrast", "plum"}) {
+		t.Errorf("ThemeNames = %v", names)
+	}
+
+	for data, want := range map[string]string{
+		`{"colors": {"primary": "purple"}}`:  "color primary",
+		`{"sizes": {"padding": -1}}`:         "size padding",
+		`{"fonts": {"heading": "a.ttf"}}`:    `font "heading"`,
+		`{"fonts": {"bold": "missing.ttf"}}`: "font bold",
+		`{"variant": "sepia"}`:               "variant",
+		`{"colors": `:                        "unexpected end",
+	} {
+		writeTheme(t, filepath.Join(dir, "bad.json"), data)
+		if err := th.Use("bad", dir); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("loading %s: %v, want an error about %s", data, err, want)
+		}
+	}
+	if th.Name() != "plum" {
+		t.Errorf("a bad theme replaced plum with %q", th.Name())
+	}
+}
+
+func TestThemeReload(t *testing.T) {
+	a := test.NewApp()
+	t.Cleanup(a.Quit)
+	path := filepath.Join(t.TempDir(), "live.json")
+	writeTheme(t, path, `{"colors": {"primary": "#111111"}}`)
+	th := NewFileCherryTheme()
+	if err := th.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	a.Settings().SetTheme(th)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go th.Watch(ctx, a, 10*time.Millisecond)
+
+	primary := func() color.Color { return th.Color(theme.ColorNamePrimary, theme.VariantDark) }
+	edit := func(data string, at time.Time) {
+		writeTheme(t, path, data)
+		os.Chtimes(path, at, at)
+	}
+	waitFor := func(want color.Color) {
+		t.Helper()
+		for deadline := time.Now().Add(2 * time.Second); primary() != want; time.Sleep(5 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("primary %v, want %v", primary(), want)
+			}
+		}
+	}
+
+	edit(`{"colors": {"primary": "#222222"}}`, time.Now().Add(time.Minute))
+	waitFor(color.NRGBA{R: 0x22, G: 0x22, B: 0x22, A: 0xff})
+
+	// A broken edit keeps the theme as it was, until it is fixed
+	edit(`{"colors": {"primary": "#22"}}`, time.Now().Add(2*time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if primary() != (color.NRGBA{R: 0x22, G: 0x22, B: 0x22, A: 0xff}) {
+		t.Errorf("a broken edit changed primary to %v", primary())
+	}
+	edit(`{"colors": {"primary": "#333333"}}`, time.Now().Add(3*time.Minute))
+	waitFor(color.NRGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff})
+}
+
+func TestThemeActions(t *testing.T) {
+	a := test.NewApp()
+	t.Cleanup(a.Quit)
+	th := NewFileCherryTheme()
+	var saved []string
+	actions := th.Actions(a, t.TempDir(), func(name, mode string) { saved = append(saved, name+" "+mode) })
+
+	var ids []string
+	for _, action := range actions {
+		ids = append(ids, action.ID)
+	}
+	want := []string{"theme.system", "theme.light", "theme.dark", "theme.use.cherry", "theme.use.high-contrast"}
+	if !slices.Equal(ids, want) {
+		t.Fatalf("actions %v, want %v", ids, want)
+	}
+	actions[2].Run()
+	actions[4].Run()
+	if !slices.Equal(saved, []string{"cherry dark", "high-contrast dark"}) {
+		t.Errorf("saved %q", saved)
+	}
+	if a.Settings().Theme() != th {
+		t.Error("the app does not draw with the theme")
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// Tray keeps the app running in the system tray: closing the window hides
+// it, and the tray menu shows it again, runs quick actions and reports a
+// status, with a badge on the icon when something needs attention. Where
+// there is no tray, closing the window quits as before.
+type Tray struct {
+	app    fyne.App
+	window fyne.Window
+	name   string
+	desk   desktop.App // nil without a tray
+
+	actions func() []*fyne.MenuItem
+
+	mu     sync.Mutex
+	status string
+	badge  bool
+	told   bool // whether the user was told the app keeps running
+}
+
+// NewTray puts the app, called name, in the tray. actions returns the
+// quick actions, e.g. "Quick Add Task"; it is called again on every
+// Refresh.
+func NewTray(a fyne.App, window fyne.Window, name string, actions func() []*fyne.MenuItem) *Tray {
+	t := &Tray{app: a, window: window, name: name, actions: actions}
+	if desk, ok := a.(desktop.App); ok {
+		t.desk = desk
+		window.SetCloseIntercept(t.Hide)
+	}
+	t.Refresh()
+	return t
+}
+
+// Show brings the window back.
+func (t *Tray) Show() {
+	t.window.Show()
+	t.window.RequestFocus()
+}
+
+// Hide hides the window to the tray. The first time, a notification says
+// the app is still running.
+func (t *Tray) Hide() {
+	t.window.Hide()
+	t.mu.Lock()
+	told := t.told
+	t.told = true
+	t.mu.Unlock()
+	if !told {
+		t.app.SendNotification(fyne.NewNotification(t.name, T("Still running in the tray. Quit from the tray menu.")))
+	}
+}
+
+// SetStatus sets the first line of the tray menu, and whether the icon
+// shows a badge.
+func (t *Tray) SetStatus(status string, badge bool) {
+	t.mu.Lock()
+	changed := status != t.status || badge != t.badge
+	t.status, t.badge = status, badge
+	t.mu.Unlock()
+	if changed {
+		t.Refresh()
+	}
+}
+
+// Refresh rebuilds the tray menu and icon.
+func (t *Tray) Refresh() {
+	if t.desk == nil {
+		return
+	}
+	menu := t.menu()
+	t.mu.Lock()
+	badge := t.badge
+	t.mu.Unlock()
+	t.desk.SetSystemTrayMenu(menu)
+	t.desk.SetSystemTrayIcon(trayIcon(badge))
+}
+
+// menu is the tray menu: the status, the quick actions, showing the window
+// and starting on login. Fyne adds Quit.
+func (t *Tray) menu() *fyne.Menu {
+	t.mu.Lock()
+	text := t.status
+	t.mu.Unlock()
+
+	var items []*fyne.MenuItem
+	if text != "" {
+		status := fyne.NewMenuItem(text, nil)
+		status.Disabled = true
+		items = append(items, status, fyne.NewMenuItemSeparator())
+	}
+	items = append(items, t.actions()...)
+	items = append(items, fyne.NewMenuItemSeparator(), fyne.NewMenuItem(T("🪟 Show Window"), t.Show))
+
+	if autostartSupported {
+		id := t.app.UniqueID()
+		login := fyne.NewMenuItem(T("🚀 Start on Login"), nil)
+		login.Checked = autostartEnabled(id)
+		login.Action = func() {
+			if err := setAutostart(id, t.name, !login.Checked); err != nil {
+				log.Println("start on login:", err)
+				t.app.SendNotification(fyne.NewNotification(T("Start on login"), err.Error()))
+			}
+			t.Refresh()
+		}
+		items = append(items, login)
+	}
+	return fyne.NewMenu(t.name, items...)
+}
+
+// Colours of the tray icon
+var (
+	trayCherry = color.NRGBA{R: 0xd2, G: 0x0a, B: 0x2e, A: 0xff}
+	trayStem   = color.NRGBA{R: 0x2e, G: 0x8b, B: 0x57, A: 0xff}
+	trayBadge  = color.NRGBA{R: 0xff, G: 0xb3, B: 0x00, A: 0xff}
+)
+
+// trayIcon draws the tray icon, a cherry, with an amber dot in the top
+// right corner if badge is set. Trays are small, so the count goes in the
+// status line instead.
+func trayIcon(badge bool) fyne.Resource {
+	const size = 64
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	in := func(x, y, cx, cy, r int) bool { return (x-cx)*(x-cx)+(y-cy)*(y-cy) <= r*r }
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			switch {
+			case badge && in(x, y, 50, 13, 11):
+				img.SetNRGBA(x, y, trayBadge)
+			case in(x, y, 28, 40, 20):
+				img.SetNRGBA(x, y, trayCherry)
+			case x >= 27 && x <= 31 && y >= 4 && y < 22:
+				img.SetNRGBA(x, y, trayStem)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	name := "tray.png"
+	if badge {
+		name = "tray-badge.png"
+	}
+	return fyne.NewStaticResource(name, buf.Bytes())
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+)
+
+func TestTrayMenu(t *testing.T) {
+	a := test.NewApp()
+	t.Cleanup(a.Quit)
+	w := a.NewWindow("main")
+	quick := fyne.NewMenuItem("🍒 Quick Add Task...", nil)
+	tray := NewTray(a, w, "checkapp", func() []*fyne.MenuItem { return []*fyne.MenuItem{quick} })
+	tray.SetStatus("⏳ 2 pending", false)
+
+	var labels []string
+	for _, item := range tray.menu().Items {
+		labels = append(labels, item.Label)
+	}
+	want := []string{"⏳ 2 pending", "", "🍒 Quick Add Task...", "", "🪟 Show Window"}
+	if autostartSupported {
+		want = append(want, "🚀 Start on Login")
+	}
+	if strings.Join(labels, "|") != strings.Join(want, "|") {
+		t.Errorf("tray menu = %q, want %q", labels, want)
+	}
+	if !tray.menu().Items[0].Disabled {
+		t.Error("the status line can be clicked")
+	}
+
+	// Hiding tells the user once that the app keeps running
+	test.AssertNotificationSent(t, fyne.NewNotification("checkapp", "Still running in the tray. Quit from the tray menu."), tray.Hide)
+	test.AssertNotificationSent(t, nil, tray.Hide)
+}
+
+func TestTrayIcon(t *testing.T) {
+	for _, badge := range []bool{false, true} {
+		img, err := png.Decode(bytes.NewReader(trayIcon(badge).Content()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, _, cherry := img.At(28, 40).RGBA()
+		r, g, _, dot := img.At(50, 13).RGBA()
+		if cherry == 0 || (dot != 0) != badge || badge && (r>>8 != 0xff || g>>8 != 0xb3) {
+			t.Errorf("badge %v: cherry alpha %d, badge pixel %d %d alpha %d", badge, cherry, r, g, dot)
+		}
+	}
+}
+
+func TestTrayStatus(t *testing.T) {
+	tm := newTestManager(t)
+	if status, badge := trayStatus(tm, time.Now()); status != "⏳ 2 pending" || badge {
+		t.Errorf("status = %q, %v", status, badge)
+	}
+	due := time.Now().Add(-time.Hour)
+	tm.Add(Task{Text: "Late", DueAt: &due})
+	if status, badge := trayStatus(tm, time.Now()); status != "⏳ 3 pending • ⚠️ 1 overdue" || !badge {
+		t.Errorf("status = %q, %v", status, badge)
+	}
+}
+
+func TestQuickAdd(t *testing.T) {
+	a := test.NewApp()
+	t.Cleanup(a.Quit)
+	tm := newTestManager(t)
+	before := len(tm.GetTasks())
+
+	w := showQuickAdd(a, tm)
+	input, ok := w.Canvas().Focused().(fyne.Focusable)
+	if !ok {
+		t.Fatal("the input is not focused")
+	}
+	test.Type(input, "  Call the plumber ")
+	input.TypedKey(&fyne.KeyEvent{Name: fyne.KeyReturn})
+
+	tasks := tm.GetTasks()
+	if len(tasks) != before+1 || tasks[len(tasks)-1].Text != "Call the plumber" || tasks[len(tasks)-1].Priority != "medium" {
+		t.Errorf("tasks = %+v", tasks)
+	}
+}
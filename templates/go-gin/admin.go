@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth guards the /api/admin routes. With a token configured, requests
+// need an "Authorization: Bearer <token>" header; without one, only clients
+// on the same machine are let in, and not on behalf of another site's page.
+func AdminAuth(token string) gin.HandlerFunc {
+	return bearerAuth(token, "admin")
+}
+
+// SyncAuth guards the document store, whose routes and "docs" realtime
+// topic let a client read and overwrite every document, like AdminAuth
+// does with sync-token.
+func SyncAuth(token string) gin.HandlerFunc {
+	return bearerAuth(token, "sync")
+}
+
+// bearerAuth checks requests with checkBearer.
+func bearerAuth(token, realm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := checkBearer(c.Request, token, realm); err != nil {
+			if err.Status == http.StatusUnauthorized {
+				c.Header("WWW-Authenticate", `Bearer realm="{{PROJECT_SLUG}} `+realm+`"`)
+			}
+			c.AbortWithStatusJSON(err.Status, ErrorResponse{Error: err.Message})
+			return
+		}
+		c.Next()
+	}
+}
+
+// checkBearer checks the bearer token of realm ("admin" or "sync") on r,
+// or when there is no token, that r comes from this machine and not from
+// another site's page.
+func checkBearer(r *http.Request, token, realm string) *APIError {
+	if token == "" {
+		if crossSite(r) {
+			return &APIError{Status: http.StatusForbidden, Message: "cross-site " + realm + " requests are not allowed"}
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+			return &APIError{Status: http.StatusForbidden, Message: realm + " requests are only accepted from localhost unless " + realm + "-token is set"}
+		}
+		return nil
+	}
+
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		return &APIError{Status: http.StatusUnauthorized, Message: "missing or invalid " + realm + " token"}
+	}
+	return nil
+}
+
+// crossSite reports whether a browser sent r for a page of another site,
+// e.g. a form that a website posts to localhost. Such requests come from
+// the same machine, but not from the user. Pages served on localhost, like
+// the frontend's dev server, are not another site.
+func crossSite(r *http.Request) bool {
+	if r.Header.Get("Sec-Fetch-Site") == "cross-site" {
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false // not a browser, or a same-origin GET
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return true // "null", from sandboxed frames and local files
+	}
+	if strings.EqualFold(u.Host, r.Host) || u.Hostname() == "localhost" {
+		return false
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip == nil || !ip.IsLoopback()
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// API registers typed /api routes and keeps a description of each one, from
+// which the OpenAPI document and the TypeScript client are generated.
+type API struct {
+	group  *gin.RouterGroup
+	routes []route
+	root   *API // set for groups, which record their routes in the root
+}
+
+type route struct {
+	method      string
+	path        string // gin syntax, relative to the group, e.g. /items/:id
+	operation   string // operationId and TypeScript client method name
+	summary     string
+	contentType string       // response content type
+	request     reflect.Type // nil when the route takes no body
+	response    reflect.Type // nil for non-JSON responses
+}
+
+// Empty is the response type of endpoints that return no data.
+type Empty struct{}
+
+// ErrorResponse is the body of every error returned by the API.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// APIError is an error with an HTTP status. Handlers return it to control
+// the status code; any other error becomes a 500.
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string { return e.Message }
+
+// Errorf creates an APIError with the given status.
+func Errorf(status int, format string, args ...any) error {
+	return &APIError{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewAPI creates a registry for routes in group.
+func NewAPI(group *gin.RouterGroup) *API {
+	return &API{group: group}
+}
+
+// Group returns a registry for routes under prefix that run handlers, such
+// as authentication, first. Its routes are documented along with a's.
+func (a *API) Group(prefix string, handlers ...gin.HandlerFunc) *API {
+	root := a
+	if a.root != nil {
+		root = a.root
+	}
+	return &API{group: a.group.Group(prefix, handlers...), root: root}
+}
+
+// Get registers a GET endpoint returning Resp as JSON.
+func Get[Resp any](a *API, path, operation, summary string, fn func(c *gin.Context) (Resp, error)) {
+	a.add(route{
+		method:    http.MethodGet,
+		path:      path,
+		operation: operation,
+		summary:   summary,
+		response:  typeOf[Resp](),
+	}, func(c *gin.Context) {
+		resp, err := fn(c)
+		respond(c, resp, err)
+	})
+}
+
+// Delete registers a DELETE endpoint returning Resp as JSON.
+func Delete[Resp any](a *API, path, operation, summary string, fn func(c *gin.Context) (Resp, error)) {
+	a.add(route{
+		method:    http.MethodDelete,
+		path:      path,
+		operation: operation,
+		summary:   summary,
+		response:  typeOf[Resp](),
+	}, func(c *gin.Context) {
+		resp, err := fn(c)
+		respond(c, resp, err)
+	})
+}
+
+// Post registers a POST endpoint taking a Req JSON body and returning Resp.
+func Post[Req, Resp any](a *API, path, operation, summary string, fn func(c *gin.Context, req Req) (Resp, error)) {
+	withBody(a, http.MethodPost, path, operation, summary, fn)
+}
+
+// Put registers a PUT endpoint taking a Req JSON body and returning Resp.
+func Put[Req, Resp any](a *API, path, operation, summary string, fn func(c *gin.Context, req Req) (Resp, error)) {
+	withBody(a, http.MethodPut, path, operation, summary, fn)
+}
+
+func withBody[Req, Resp any](a *API, method, path, operation, summary string, fn func(c *gin.Context, req Req) (Resp, error)) {
+	// Endpoints taking Empty, such as actions, don't need a body at all
+	request := typeOf[Req]()
+	if request == typeOf[Empty]() {
+		request = nil
+	}
+	a.add(route{
+		method:    method,
+		path:      path,
+		operation: operation,
+		summary:   summary,
+		request:   request,
+		response:  typeOf[Resp](),
+	}, func(c *gin.Context) {
+		var req Req
+		if request != nil {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+				return
+			}
+		}
+		resp, err := fn(c, req)
+		respond(c, resp, err)
+	})
+}
+
+// Raw registers an endpoint that writes its own response, such as plain
+// text or a file. It is documented with the given content type. POST and
+// PUT endpoints read the request body themselves, e.g. an uploaded file.
+func (a *API) Raw(method, path, operation, summary, contentType string, h gin.HandlerFunc) {
+	a.add(route{
+		method:      method,
+		path:        path,
+		operation:   operation,
+		summary:     summary,
+		contentType: contentType,
+	}, h)
+}
+
+// rawBody reports whether the route takes a request body that isn't JSON.
+func (r route) rawBody() bool {
+	return r.request == nil && r.response == nil && (r.method == http.MethodPost || r.method == http.MethodPut)
+}
+
+func (a *API) add(r route, h gin.HandlerFunc) {
+	if r.contentType == "" {
+		r.contentType = "application/json"
+	}
+	a.group.Handle(r.method, r.path, h)
+	if a.root != nil {
+		r.path = strings.TrimPrefix(a.group.BasePath(), a.root.group.BasePath()) + r.path
+		a.root.routes = append(a.root.routes, r)
+		return
+	}
+	a.routes = append(a.routes, r)
+}
+
+func respond(c *gin.Context, resp any, err error) {
+	if err == nil {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		c.JSON(apiErr.Status, ErrorResponse{Error: apiErr.Message})
+		return
+	}
+
+	RequestLogger(c).Error("request failed", "error", err)
+	c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
+}
+
+// RecoverJSON is gin's panic recovery, answering with an ErrorResponse
+// instead of an empty 500.
+func RecoverJSON() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, err any) {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
+	})
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+var pathParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// fullPath returns the route path with the group prefix, in OpenAPI syntax
+// ({id} instead of :id).
+func (a *API) fullPath(r route) string {
+	return pathParam.ReplaceAllString(a.group.BasePath()+r.path, "{$1}")
+}
+
+func pathParams(r route) []string {
+	var params []string
+	for _, m := range pathParam.FindAllStringSubmatch(r.path, -1) {
+		params = append(params, m[1])
+	}
+	return params
+}
+
+// OpenAPI returns the OpenAPI 3 document describing the registered routes.
+func (a *API) OpenAPI() map[string]any {
+	schemas := newSchemaSet()
+	paths := map[string]map[string]any{}
+
+	for _, r := range a.routes {
+		op := map[string]any{
+			"operationId": r.operation,
+			"summary":     r.summary,
+		}
+
+		var params []map[string]any
+		for _, name := range pathParams(r) {
+			params = append(params, map[string]any{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+
+		if r.request != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": schemas.schema(r.request)},
+				},
+			}
+		} else if r.rawBody() {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/octet-stream": map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}},
+				},
+			}
+		}
+
+		responseSchema := map[string]any{"type": "string"}
+		if r.response != nil {
+			responseSchema = schemas.schema(r.response)
+		} else if r.contentType == "application/json" {
+			responseSchema = map[string]any{}
+		} else if !strings.HasPrefix(r.contentType, "text/") {
+			responseSchema["format"] = "binary"
+		}
+		op["responses"] = map[string]any{
+			"200": map[string]any{
+				"description": "OK",
+				"content":     map[string]any{r.contentType: map[string]any{"schema": responseSchema}},
+			},
+			"default": map[string]any{
+				"description": "Error",
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": schemas.schema(typeOf[ErrorResponse]())},
+				},
+			},
+		}
+
+		path := a.fullPath(r)
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(r.method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "{{PROJECT_NAME}} API",
+			"version": "1.0.0",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas.defs},
+	}
+}
+
+// schemaSet converts Go types to JSON schemas, collecting named structs as
+// reusable components.
+type schemaSet struct {
+	defs map[string]any
+}
+
+func newSchemaSet() *schemaSet {
+	return &schemaSet{defs: map[string]any{}}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+func (s *schemaSet) schema(t reflect.Type) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]any{} // any JSON value
+	case t.Kind() == reflect.Pointer:
+		schema := s.schema(t.Elem())
+		if _, isRef := schema["$ref"]; isRef {
+			return map[string]any{"allOf": []any{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		if _, seen := s.defs[t.Name()]; !seen {
+			s.defs[t.Name()] = map[string]any{} // placeholder for recursive types
+			s.defs[t.Name()] = s.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]any{}
+	}
+}
+
+func (s *schemaSet) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	for _, f := range jsonFields(t) {
+		properties[f.name] = s.schema(f.typ)
+		if !f.optional {
+			required = append(required, f.name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if required != nil {
+		schema["required"] = required
+	}
+	return schema
+}
+
+type jsonField struct {
+	name     string
+	typ      reflect.Type
+	optional bool
+}
+
+// jsonFields lists the fields of struct t as encoding/json sees them.
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(f.Type)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		fields = append(fields, jsonField{
+			name:     name,
+			typ:      f.Type,
+			optional: strings.Contains(opts, "omitempty"),
+		})
+	}
+	return fields
+}
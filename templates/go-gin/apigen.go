@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// defaultClientPath is where gen-client writes the TypeScript client.
+const defaultClientPath = "frontend/src/lib/api.ts"
+
+// genClient implements the "gen-client" command: it writes a TypeScript
+// client for every /api route so the frontend build fails when the two
+// sides drift apart.
+func genClient(api *API, args []string) error {
+	path := defaultClientPath
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	var buf bytes.Buffer
+	if err := api.WriteTSClient(&buf); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s (%d routes)\n", path, len(api.routes))
+	return nil
+}
+
+// WriteTSClient writes TypeScript interfaces for the request and response
+// types and an `api` object with one method per route.
+func (a *API) WriteTSClient(w io.Writer) error {
+	ts := &tsTypes{defs: map[string]string{}}
+
+	var methods strings.Builder
+	for _, r := range a.routes {
+		var params []string
+		for _, name := range pathParams(r) {
+			params = append(params, name+": string")
+		}
+		body := "undefined"
+		switch {
+		case r.request != nil:
+			params = append(params, "body: "+ts.typeName(r.request))
+			body = "body"
+		case r.rawBody():
+			params = append(params, "body: Blob")
+			body = "body"
+		}
+
+		result := "Blob"
+		mode := "'blob'"
+		switch {
+		case r.response != nil:
+			result, mode = ts.typeName(r.response), "'json'"
+		case r.contentType == "application/json":
+			result, mode = "unknown", "'json'"
+		case strings.HasPrefix(r.contentType, "text/"):
+			result, mode = "string", "'text'"
+		}
+
+		path := pathParam.ReplaceAllString(a.group.BasePath()+r.path, "${encodeURIComponent($1)}")
+
+		fmt.Fprintf(&methods, "  /** %s */\n", r.summary)
+		fmt.Fprintf(&methods, "  %s: (%s) =>\n    request<%s>('%s', `%s`, %s, %s),\n",
+			r.operation, strings.Join(params, ", "), result, r.method, path, body, mode)
+	}
+
+	var out strings.Builder
+	out.WriteString("// Code generated by `go run . gen-client`. DO NOT EDIT.\n")
+	out.WriteString("// Regenerate after changing routes in the Go server.\n\n")
+	for _, name := range ts.order {
+		out.WriteString(ts.defs[name])
+		out.WriteString("\n")
+	}
+	out.WriteString(tsRuntime)
+	out.WriteString("\nexport const api = {\n")
+	out.WriteString(methods.String())
+	out.WriteString("}\n")
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// tsTypes converts Go types to TypeScript, emitting an interface for every
+// named struct it meets.
+type tsTypes struct {
+	defs  map[string]string
+	order []string
+}
+
+func (ts *tsTypes) typeName(t reflect.Type) string {
+	switch {
+	case t == timeType:
+		return "string"
+	case t == rawMessageType:
+		return "unknown"
+	case t.Kind() == reflect.Pointer:
+		return ts.typeName(t.Elem()) + " | null"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		elem := ts.typeName(t.Elem())
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case reflect.Map:
+		return "Record<string, " + ts.typeName(t.Elem()) + ">"
+	case reflect.Struct:
+		if t.Name() == "" {
+			return ts.object(t, "")
+		}
+		if _, seen := ts.defs[t.Name()]; !seen {
+			ts.defs[t.Name()] = "" // placeholder for recursive types
+			ts.order = append(ts.order, t.Name())
+			if len(jsonFields(t)) == 0 {
+				ts.defs[t.Name()] = "export type " + t.Name() + " = Record<string, never>\n"
+			} else {
+				ts.defs[t.Name()] = "export interface " + t.Name() + " " + ts.object(t, "") + "\n"
+			}
+		}
+		return t.Name()
+	default:
+		return "unknown"
+	}
+}
+
+func (ts *tsTypes) object(t reflect.Type, indent string) string {
+	fields := jsonFields(t)
+	if len(fields) == 0 {
+		return "Record<string, never>"
+	}
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, f := range fields {
+		optional := ""
+		if f.optional {
+			optional = "?"
+		}
+		fmt.Fprintf(&b, "%s  %s%s: %s\n", indent, f.name, optional, ts.typeName(f.typ))
+	}
+	b.WriteString(indent + "}")
+	return b.String()
+}
+
+// tsRuntime is the fetch wrapper shared by all generated methods.
+const tsRuntime = `export class ApiError extends Error {
+  status: number
+
+  constructor(status: number, message: string) {
+    super(message)
+    this.status = status
+  }
+}
+
+async function request<T>(method: string, path: string, body: unknown, mode: 'json' | 'text' | 'blob'): Promise<T> {
+  const raw = body instanceof Blob
+  const response = await fetch(path, {
+    method,
+    headers: body === undefined || raw ? undefined : { 'Content-Type': 'application/json' },
+    body: body === undefined ? undefined : raw ? body : JSON.stringify(body),
+  })
+
+  if (!response.ok) {
+    let message = response.statusText
+    try {
+      message = (await response.json()).error ?? message
+    } catch {
+      // not a JSON error body
+    }
+    throw new ApiError(response.status, message)
+  }
+
+  if (mode === 'json') return (await response.json()) as T
+  if (mode === 'blob') return (await response.blob()) as T
+  return (await response.text()) as T
+}
+`
@@ -0,0 +1,525 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// backupFormat is the version of the archive layout: data files under
+// data/, followed by a backup.json manifest.
+const backupFormat = 1
+
+// BackupManifest describes the contents of a backup archive.
+type BackupManifest struct {
+	Format  int               `json:"format"`
+	App     string            `json:"app"`
+	Version string            `json:"version"` // version of the app that took it
+	Created time.Time         `json:"created"`
+	Files   map[string]string `json:"files"` // path relative to the data directory -> SHA-256
+}
+
+// BackupInfo describes a snapshot stored in the backup directory.
+type BackupInfo struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+}
+
+// RestoreResult reports a completed restore.
+type RestoreResult struct {
+	Files    int       `json:"files"`
+	Created  time.Time `json:"created"`  // when the restored backup was taken
+	Previous string    `json:"previous"` // snapshot of the data the restore replaced
+}
+
+// Backups takes snapshots of the data directory and restores them. Code
+// that writes to the data directory should do so inside Modify so that
+// snapshots never capture a half-written state.
+type Backups struct {
+	dataDir    string
+	dir        string
+	keep       int
+	maxRestore int64 // bytes a restored backup may unpack to
+
+	mu sync.RWMutex // write-locked while a snapshot is taken or data is swapped
+
+	hooksMu   sync.Mutex
+	onRestore []func()
+}
+
+// NewBackups creates the backup subsystem for the data and backup
+// directories in cfg. Scheduled snapshots run as the "backup" job.
+func NewBackups(cfg *Config) *Backups {
+	return &Backups{dataDir: cfg.DataDir, dir: cfg.BackupDir, keep: cfg.BackupKeep, maxRestore: int64(cfg.RestoreMaxMB) << 20}
+}
+
+// Modify runs fn, which changes files in the data directory, so that it
+// doesn't overlap a snapshot or a restore. Calls may run concurrently.
+func (b *Backups) Modify(fn func() error) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return fn()
+}
+
+// OnRestore registers fn to run after restored data has been swapped in,
+// e.g. to reopen a database.
+func (b *Backups) OnRestore(fn func()) {
+	b.hooksMu.Lock()
+	defer b.hooksMu.Unlock()
+	b.onRestore = append(b.onRestore, fn)
+}
+
+// Save stores a snapshot in the backup directory and prunes the oldest
+// ones beyond the retention count. The label, if any, is added to the name.
+func (b *Backups) Save(label string) (BackupInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.save(label)
+}
+
+func (b *Backups) save(label string) (BackupInfo, error) {
+	name := "{{PROJECT_SLUG}}-" + time.Now().UTC().Format("20060102-150405")
+	if label != "" {
+		name += "-" + label
+	}
+	name += ".tar.gz"
+
+	// The directory is made on first use, so servers that never take a
+	// snapshot don't need a writable one
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return BackupInfo{}, err
+	}
+	path := filepath.Join(b.dir, name)
+	if err := b.snapshotFile(path); err != nil {
+		return BackupInfo{}, err
+	}
+	b.prune()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return BackupInfo{}, err
+	}
+	return BackupInfo{Name: name, Size: fi.Size(), Created: fi.ModTime()}, nil
+}
+
+// snapshotFile writes a snapshot to path via a temporary file, so path
+// only ever holds a complete archive. The caller holds the write lock.
+func (b *Backups) snapshotFile(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".partial-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = b.snapshot(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// snapshot writes a gzipped tar archive of the data directory to w.
+func (b *Backups) snapshot(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifest := BackupManifest{
+		Format:  backupFormat,
+		App:     "{{PROJECT_SLUG}}",
+		Version: buildVersion().Version,
+		Created: time.Now().UTC(),
+		Files:   map[string]string{},
+	}
+
+	err := filepath.WalkDir(b.dataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(b.dataDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "data/" + rel + "/", Mode: int64(info.Mode().Perm()), ModTime: info.ModTime()})
+		case !d.Type().IsRegular():
+			slog.Warn("backup: skipping non-regular file", "path", path)
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		hdr := &tar.Header{Typeflag: tar.TypeReg, Name: "data/" + rel, Mode: int64(info.Mode().Perm()), Size: info.Size(), ModTime: info.ModTime()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		h := sha256.New()
+		if _, err := io.Copy(tw, io.TeeReader(f, h)); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		manifest.Files[rel] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "backup.json", Mode: 0644, Size: int64(len(data)), ModTime: manifest.Created}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Restore verifies the backup archive read from r and replaces the data
+// directory with its contents. The current data is saved as a
+// "pre-restore" snapshot first. Archives that unpack to more than
+// restore-max-mb are rejected before they fill the disk.
+func (b *Backups) Restore(r io.Reader) (RestoreResult, error) {
+	// Unpack next to the data directory, so the swap is a rename
+	dataDir := filepath.Clean(b.dataDir)
+	staging, err := os.MkdirTemp(filepath.Dir(dataDir), "."+filepath.Base(dataDir)+"-restore-")
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, err := extractBackup(r, staging, b.maxRestore)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous, err := b.save("pre-restore")
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("save current data before restoring: %w", err)
+	}
+	if err := swapDir(b.dataDir, staging); err != nil {
+		return RestoreResult{}, err
+	}
+
+	b.hooksMu.Lock()
+	hooks := slices.Clone(b.onRestore)
+	b.hooksMu.Unlock()
+	for _, fn := range hooks {
+		fn()
+	}
+
+	slog.Info("backup restored", "files", len(manifest.Files), "created", manifest.Created, "previous", previous.Name)
+	return RestoreResult{Files: len(manifest.Files), Created: manifest.Created, Previous: previous.Name}, nil
+}
+
+// maxBackupEntries limits the files and directories of a restored backup.
+const maxBackupEntries = 100000
+
+// extractBackup unpacks a backup archive of at most limit bytes into dir
+// and checks it against its manifest. Problems with the archive itself are
+// 400 APIErrors, archives over the limit 413.
+func extractBackup(r io.Reader, dir string, limit int64) (*BackupManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, Errorf(http.StatusBadRequest, "not a backup archive: %v", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var manifest *BackupManifest
+	sums := map[string]string{}
+	var size int64
+	for entries := 1; ; entries++ {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, Errorf(http.StatusBadRequest, "corrupt backup archive: %v", err)
+		}
+		if entries > maxBackupEntries {
+			return nil, Errorf(http.StatusRequestEntityTooLarge, "backup has more than %d entries", maxBackupEntries)
+		}
+
+		if hdr.Name == "backup.json" {
+			manifest = &BackupManifest{}
+			if err := json.NewDecoder(io.LimitReader(tr, 64<<20)).Decode(manifest); err != nil {
+				return nil, Errorf(http.StatusBadRequest, "invalid backup manifest: %v", err)
+			}
+			continue
+		}
+
+		rel, ok := strings.CutPrefix(hdr.Name, "data/")
+		rel = strings.TrimSuffix(rel, "/")
+		if !ok || rel == "" {
+			continue
+		}
+		if !filepath.IsLocal(rel) {
+			return nil, Errorf(http.StatusBadRequest, "backup contains an unsafe path: %s", hdr.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if size += hdr.Size; size > limit {
+				return nil, Errorf(http.StatusRequestEntityTooLarge, "backup is larger than %d MB unpacked, see restore-max-mb", limit>>20)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return nil, err
+			}
+			sum, err := writeFile(target, tr, hdr.FileInfo().Mode().Perm()|0600)
+			if err != nil {
+				return nil, err
+			}
+			os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+			sums[rel] = sum
+		default:
+			return nil, Errorf(http.StatusBadRequest, "backup contains an unsupported entry: %s", hdr.Name)
+		}
+	}
+
+	switch {
+	case manifest == nil:
+		return nil, Errorf(http.StatusBadRequest, "not a backup archive: backup.json is missing")
+	case manifest.Format != backupFormat:
+		return nil, Errorf(http.StatusBadRequest, "unsupported backup format %d", manifest.Format)
+	case manifest.App != "{{PROJECT_SLUG}}":
+		return nil, Errorf(http.StatusBadRequest, "backup belongs to %q, not {{PROJECT_SLUG}}", manifest.App)
+	case len(sums) != len(manifest.Files):
+		return nil, Errorf(http.StatusBadRequest, "backup has %d files, its manifest lists %d", len(sums), len(manifest.Files))
+	}
+	for path, sum := range manifest.Files {
+		if sums[path] != sum {
+			return nil, Errorf(http.StatusBadRequest, "backup file %s is missing or damaged", path)
+		}
+	}
+	return manifest, nil
+}
+
+// writeFile copies r to a new file at path and returns its SHA-256.
+func writeFile(path string, r io.Reader, perm fs.FileMode) (string, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return hex.EncodeToString(h.Sum(nil)), err
+}
+
+// swapDir replaces dir with replacement. Directories that can't be renamed,
+// such as mount points, get their contents replaced instead.
+func swapDir(dir, replacement string) error {
+	old := replacement + ".old"
+	if err := os.Rename(dir, old); err == nil {
+		if err := os.Rename(replacement, dir); err != nil {
+			os.Rename(old, dir)
+			return err
+		}
+		return os.RemoveAll(old)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	entries, err = os.ReadDir(replacement)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := os.Rename(filepath.Join(replacement, e.Name()), filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the stored snapshots, newest first.
+func (b *Backups) List() ([]BackupInfo, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	backups := []BackupInfo{}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.HasPrefix(e.Name(), "{{PROJECT_SLUG}}-") || !strings.HasSuffix(e.Name(), ".tar.gz") {
+			continue
+		}
+		if fi, err := e.Info(); err == nil {
+			backups = append(backups, BackupInfo{Name: e.Name(), Size: fi.Size(), Created: fi.ModTime()})
+		}
+	}
+	slices.SortFunc(backups, func(a, b BackupInfo) int { return b.Created.Compare(a.Created) })
+	return backups, nil
+}
+
+// prune deletes the oldest snapshots beyond the retention count.
+func (b *Backups) prune() {
+	backups, err := b.List()
+	if err != nil || b.keep <= 0 || len(backups) <= b.keep {
+		return
+	}
+	for _, old := range backups[b.keep:] {
+		if err := os.Remove(filepath.Join(b.dir, old.Name)); err != nil {
+			slog.Warn("could not prune backup", "name", old.Name, "error", err)
+		}
+	}
+}
+
+// open opens a stored snapshot by name.
+func (b *Backups) open(name string) (*os.File, error) {
+	backups, err := b.List()
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(backups, func(info BackupInfo) bool { return info.Name == name }) {
+		return nil, Errorf(http.StatusNotFound, "backup %s not found", name)
+	}
+	return os.Open(filepath.Join(b.dir, name))
+}
+
+// Register adds the backup endpoints to the admin API.
+func (b *Backups) Register(admin *API) {
+	Get(admin, "/backups", "listBackups", "List stored snapshots of the data directory", func(c *gin.Context) ([]BackupInfo, error) {
+		return b.List()
+	})
+
+	admin.Raw(http.MethodGet, "/backup", "downloadBackup", "Download a snapshot of the data directory (?name= for a stored one)", "application/gzip", func(c *gin.Context) {
+		var f *os.File
+		var err error
+		name := c.Query("name")
+		if name != "" {
+			f, err = b.open(name)
+		} else {
+			name = "{{PROJECT_SLUG}}-" + time.Now().UTC().Format("20060102-150405") + ".tar.gz"
+			if f, err = b.snapshotTemp(); err == nil {
+				defer os.Remove(f.Name())
+			}
+		}
+		if err != nil {
+			respond(c, nil, err)
+			return
+		}
+		defer f.Close()
+
+		fi, err := f.Stat()
+		if err != nil {
+			respond(c, nil, err)
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+		c.Header("Content-Length", strconv.FormatInt(fi.Size(), 10))
+		c.Header("Content-Type", "application/gzip")
+		c.Status(http.StatusOK)
+		io.Copy(c.Writer, f)
+	})
+
+	admin.Raw(http.MethodPost, "/restore", "restoreBackup", "Replace the data directory with the backup archive in the request body (?name= for a stored one)", "application/json", func(c *gin.Context) {
+		var src io.Reader = http.MaxBytesReader(c.Writer, c.Request.Body, b.maxRestore)
+		if name := c.Query("name"); name != "" {
+			f, err := b.open(name)
+			if err != nil {
+				respond(c, nil, err)
+				return
+			}
+			defer f.Close()
+			src = f
+		} else if !archiveType(c.ContentType()) {
+			// Forms can post any body cross-site, but only as text or
+			// form data.
+			respond(c, nil, Errorf(http.StatusUnsupportedMediaType, "restore needs Content-Type: application/gzip, not %q", c.ContentType()))
+			return
+		}
+		result, err := b.Restore(src)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = Errorf(http.StatusRequestEntityTooLarge, "backup archive too large")
+		}
+		respond(c, result, err)
+	})
+}
+
+// archiveType reports whether contentType is one that backup archives
+// are uploaded with.
+func archiveType(contentType string) bool {
+	switch contentType {
+	case "application/gzip", "application/x-gzip", "application/octet-stream":
+		return true
+	}
+	return false
+}
+
+// snapshotTemp takes a snapshot into a temporary file, positioned at the
+// start. Downloads are streamed from it so a slow client doesn't hold up
+// writers. The caller removes the file.
+func (b *Backups) snapshotTemp() (*os.File, error) {
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(b.dir, ".download-*")
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	err = b.snapshot(f)
+	b.mu.Unlock()
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,566 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BlobInfo is the metadata of a stored file.
+type BlobInfo struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`
+	Size    int64     `json:"size"`
+	SHA256  string    `json:"sha256"`
+	Created time.Time `json:"created"`
+}
+
+// UploadRequest starts a resumable upload.
+type UploadRequest struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+	Size int64  `json:"size"`
+}
+
+// UploadStatus reports the progress of a resumable upload. Blob is set
+// once the last chunk has arrived.
+type UploadStatus struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Type    string    `json:"type,omitempty"`
+	Size    int64     `json:"size"`
+	Offset  int64     `json:"offset"`
+	Created time.Time `json:"created"`
+	Blob    *BlobInfo `json:"blob,omitempty"`
+}
+
+// uploadTTL is how long an unfinished upload is kept without new chunks.
+const uploadTTL = 24 * time.Hour
+
+// Blobs stores uploaded files under <data-dir>/blobs. Contents are stored
+// once per SHA-256 in objects/, so uploading the same file twice takes no
+// extra space; index.json maps blob IDs to names, types and contents.
+type Blobs struct {
+	dir      string
+	maxSize  int64
+	types    []string
+	modify   func(func() error) error
+	mu       sync.Mutex
+	index    map[string]BlobInfo
+	uploadMu sync.Map // upload ID -> *sync.Mutex
+}
+
+// NewBlobs opens the blob store in the data directory of cfg. Writes go
+// through backups.Modify so snapshots stay consistent.
+func NewBlobs(cfg *Config, backups *Backups) *Blobs {
+	b := &Blobs{
+		dir:     filepath.Join(cfg.DataDir, "blobs"),
+		maxSize: int64(cfg.BlobMaxMB) << 20,
+		types:   splitList(cfg.BlobTypes),
+		modify:  backups.Modify,
+	}
+	b.load()
+	backups.OnRestore(b.load)
+	return b
+}
+
+// load reads the metadata index from disk.
+func (b *Blobs) load() {
+	index := map[string]BlobInfo{}
+	data, err := os.ReadFile(filepath.Join(b.dir, "index.json"))
+	if err == nil {
+		err = json.Unmarshal(data, &index)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Error("could not read blob index", "error", err)
+	}
+
+	b.mu.Lock()
+	b.index = index
+	b.mu.Unlock()
+}
+
+// saveIndex writes the index atomically. The caller holds b.mu.
+func (b *Blobs) saveIndex() error {
+	data, err := json.MarshalIndent(b.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(b.dir, "index.json.tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(b.dir, "index.json"))
+}
+
+func (b *Blobs) objectPath(sum string) string {
+	return filepath.Join(b.dir, "objects", sum[:2], sum)
+}
+
+// List returns all blobs, newest first.
+func (b *Blobs) List() []BlobInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	blobs := make([]BlobInfo, 0, len(b.index))
+	for _, info := range b.index {
+		blobs = append(blobs, info)
+	}
+	slices.SortFunc(blobs, func(x, y BlobInfo) int { return y.Created.Compare(x.Created) })
+	return blobs
+}
+
+// Get returns the metadata of a blob.
+func (b *Blobs) Get(id string) (BlobInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	info, ok := b.index[id]
+	if !ok {
+		return BlobInfo{}, Errorf(http.StatusNotFound, "blob %s not found", id)
+	}
+	return info, nil
+}
+
+// Open returns a blob's metadata and contents.
+func (b *Blobs) Open(id string) (BlobInfo, *os.File, error) {
+	info, err := b.Get(id)
+	if err != nil {
+		return info, nil, err
+	}
+	f, err := os.Open(b.objectPath(info.SHA256))
+	return info, f, err
+}
+
+// Put stores the contents of r as a new blob.
+func (b *Blobs) Put(name, declaredType string, r io.Reader) (BlobInfo, error) {
+	if err := os.MkdirAll(filepath.Join(b.dir, "uploads"), 0755); err != nil {
+		return BlobInfo{}, err
+	}
+	f, err := os.CreateTemp(filepath.Join(b.dir, "uploads"), ".put-*")
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = io.Copy(f, io.LimitReader(r, b.maxSize+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	return b.store(f.Name(), name, declaredType)
+}
+
+// store checks the file at path against the limits and moves it into the
+// object store, or drops it if the same contents are already stored.
+func (b *Blobs) store(path, name, declaredType string) (BlobInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	f.Seek(0, io.SeekStart)
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	f.Close()
+	if err != nil {
+		return BlobInfo{}, err
+	}
+
+	if size > b.maxSize {
+		return BlobInfo{}, Errorf(http.StatusRequestEntityTooLarge, "file is larger than %d MB", b.maxSize>>20)
+	}
+	contentType := detectType(declaredType, head[:n])
+	if !typeAllowed(contentType, b.types) {
+		return BlobInfo{}, Errorf(http.StatusUnsupportedMediaType, "files of type %s are not accepted", contentType)
+	}
+
+	info := BlobInfo{
+		ID:      newID(),
+		Name:    cleanName(name),
+		Type:    contentType,
+		Size:    size,
+		SHA256:  hex.EncodeToString(h.Sum(nil)),
+		Created: time.Now().UTC(),
+	}
+
+	err = b.modify(func() error {
+		// Held across the object check so Delete can't remove the contents
+		// in between
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		object := b.objectPath(info.SHA256)
+		if _, err := os.Stat(object); err != nil {
+			if err := os.MkdirAll(filepath.Dir(object), 0755); err != nil {
+				return err
+			}
+			if err := os.Rename(path, object); err != nil {
+				return err
+			}
+		}
+		b.index[info.ID] = info
+		return b.saveIndex()
+	})
+	return info, err
+}
+
+// Delete removes a blob, and its contents once no other blob shares them.
+func (b *Blobs) Delete(id string) error {
+	return b.modify(func() error {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		info, ok := b.index[id]
+		if !ok {
+			return Errorf(http.StatusNotFound, "blob %s not found", id)
+		}
+		delete(b.index, id)
+		if err := b.saveIndex(); err != nil {
+			return err
+		}
+
+		for _, other := range b.index {
+			if other.SHA256 == info.SHA256 {
+				return nil
+			}
+		}
+		return os.Remove(b.objectPath(info.SHA256))
+	})
+}
+
+// CreateUpload starts a resumable upload of req.Size bytes.
+func (b *Blobs) CreateUpload(req UploadRequest) (UploadStatus, error) {
+	if req.Size < 0 {
+		return UploadStatus{}, Errorf(http.StatusBadRequest, "size must not be negative")
+	}
+	if req.Size > b.maxSize {
+		return UploadStatus{}, Errorf(http.StatusRequestEntityTooLarge, "file is larger than %d MB", b.maxSize>>20)
+	}
+	if req.Type != "" && !typeAllowed(req.Type, b.types) {
+		return UploadStatus{}, Errorf(http.StatusUnsupportedMediaType, "files of type %s are not accepted", req.Type)
+	}
+
+	status := UploadStatus{ID: newID(), Name: cleanName(req.Name), Type: req.Type, Size: req.Size, Created: time.Now().UTC()}
+	data, err := json.Marshal(status)
+	if err != nil {
+		return UploadStatus{}, err
+	}
+	dir := filepath.Join(b.dir, "uploads")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return UploadStatus{}, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, status.ID), nil, 0644); err != nil {
+		return UploadStatus{}, err
+	}
+	return status, os.WriteFile(filepath.Join(dir, status.ID+".json"), data, 0644)
+}
+
+// Upload returns the progress of a resumable upload.
+func (b *Blobs) Upload(id string) (UploadStatus, error) {
+	var status UploadStatus
+	if len(id) != 32 || strings.Trim(id, "0123456789abcdef") != "" {
+		return status, Errorf(http.StatusNotFound, "upload %s not found", id)
+	}
+	data, err := os.ReadFile(filepath.Join(b.dir, "uploads", id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return status, Errorf(http.StatusNotFound, "upload %s not found", id)
+	}
+	if err != nil {
+		return status, err
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return status, err
+	}
+	fi, err := os.Stat(filepath.Join(b.dir, "uploads", id))
+	if err != nil {
+		return status, err
+	}
+	status.Offset = fi.Size()
+	return status, nil
+}
+
+// AppendChunk writes a chunk at offset, which must be the number of bytes
+// received so far. A chunk cut short by a dropped connection keeps what
+// arrived; the client resumes from the new offset. The blob is stored once
+// the last byte is in.
+func (b *Blobs) AppendChunk(id string, offset int64, r io.Reader) (UploadStatus, error) {
+	// Only uploads that exist get a lock, so made-up IDs can't fill uploadMu
+	if status, err := b.Upload(id); err != nil {
+		return status, err
+	}
+	lock, _ := b.uploadMu.LoadOrStore(id, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	status, err := b.Upload(id)
+	if err != nil {
+		// Finished or cancelled while we waited for the lock
+		b.uploadMu.CompareAndDelete(id, lock)
+		return status, err
+	}
+	if offset != status.Offset {
+		return status, Errorf(http.StatusConflict, "upload is at offset %d, not %d", status.Offset, offset)
+	}
+
+	path := filepath.Join(b.dir, "uploads", id)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return status, err
+	}
+	n, err := io.Copy(f, io.LimitReader(r, status.Size-offset+1))
+	status.Offset += n
+	if status.Offset > status.Size {
+		f.Truncate(offset)
+		status.Offset = offset
+		err = Errorf(http.StatusRequestEntityTooLarge, "chunk goes past the declared size of %d bytes", status.Size)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil || status.Offset < status.Size {
+		return status, err
+	}
+
+	info, err := b.store(path, status.Name, status.Type)
+	b.removeUpload(id)
+	if err != nil {
+		return status, err
+	}
+	status.Blob = &info
+	return status, nil
+}
+
+// CancelUpload discards an unfinished upload.
+func (b *Blobs) CancelUpload(id string) error {
+	if _, err := b.Upload(id); err != nil {
+		return err
+	}
+	b.removeUpload(id)
+	return nil
+}
+
+func (b *Blobs) removeUpload(id string) {
+	os.Remove(filepath.Join(b.dir, "uploads", id))
+	os.Remove(filepath.Join(b.dir, "uploads", id+".json"))
+	b.uploadMu.Delete(id)
+}
+
+// CleanupUploads removes uploads that haven't received data for a day.
+// It runs as the "blob-uploads" job.
+func (b *Blobs) CleanupUploads(ctx context.Context) error {
+	dir := filepath.Join(b.dir, "uploads")
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		// An upload is as old as its data file, which every chunk writes
+		// to; the .json is only written when the upload starts
+		id, isStatus := strings.CutSuffix(e.Name(), ".json")
+		if _, err := os.Stat(filepath.Join(dir, id)); isStatus && err == nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < uploadTTL {
+			continue
+		}
+		if strings.HasPrefix(e.Name(), ".put-") {
+			os.Remove(filepath.Join(dir, e.Name()))
+		} else {
+			b.removeUpload(id)
+		}
+	}
+	// Drop the locks of uploads that are gone
+	b.uploadMu.Range(func(id, lock any) bool {
+		if _, err := os.Stat(filepath.Join(dir, id.(string)+".json")); errors.Is(err, os.ErrNotExist) {
+			b.uploadMu.CompareAndDelete(id, lock)
+		}
+		return true
+	})
+	return nil
+}
+
+// Register adds the /blobs endpoints.
+func (b *Blobs) Register(api *API) {
+	blobs := api.Group("/blobs")
+
+	Get(blobs, "", "listBlobs", "List stored files", func(c *gin.Context) ([]BlobInfo, error) {
+		return b.List(), nil
+	})
+
+	blobs.Raw(http.MethodPost, "", "uploadBlobs", "Upload files as multipart/form-data, or one file as the raw body (?name=)", "application/json", func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, b.maxSize+1<<20)
+		mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if mediaType != "multipart/form-data" {
+			info, err := b.Put(c.Query("name"), c.GetHeader("Content-Type"), c.Request.Body)
+			respond(c, []BlobInfo{info}, bodyError(err))
+			return
+		}
+
+		reader, err := c.Request.MultipartReader()
+		if err != nil {
+			respond(c, nil, Errorf(http.StatusBadRequest, "invalid multipart body: %v", err))
+			return
+		}
+		stored := []BlobInfo{}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				respond(c, nil, bodyError(err))
+				return
+			}
+			if part.FileName() == "" {
+				continue // not a file field
+			}
+			info, err := b.Put(part.FileName(), part.Header.Get("Content-Type"), part)
+			if err != nil {
+				respond(c, nil, bodyError(err))
+				return
+			}
+			stored = append(stored, info)
+		}
+		if len(stored) == 0 {
+			respond(c, nil, Errorf(http.StatusBadRequest, "no files in the upload"))
+			return
+		}
+		respond(c, stored, nil)
+	})
+
+	Get(blobs, "/:id", "getBlob", "Get a file's metadata", func(c *gin.Context) (BlobInfo, error) {
+		return b.Get(c.Param("id"))
+	})
+
+	blobs.Raw(http.MethodGet, "/:id/content", "downloadBlob", "Download a file; supports Range requests, ?inline=1 to display it", "application/octet-stream", func(c *gin.Context) {
+		info, f, err := b.Open(c.Param("id"))
+		if err != nil {
+			respond(c, nil, err)
+			return
+		}
+		defer f.Close()
+
+		h := c.Writer.Header()
+		h.Set("Content-Type", info.Type)
+		h.Set("ETag", `"`+info.SHA256+`"`)
+		h.Set("Cache-Control", "private, max-age=31536000, immutable")
+		// Uploaded HTML or SVG must never run as part of the app
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Content-Security-Policy", "sandbox")
+		disposition := "attachment"
+		if c.Query("inline") != "" && inlineSafe(info.Type) {
+			disposition = "inline"
+		}
+		h.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": info.Name}))
+		http.ServeContent(c.Writer, c.Request, info.Name, info.Created, f)
+	})
+
+	Delete(blobs, "/:id", "deleteBlob", "Delete a file", func(c *gin.Context) (Empty, error) {
+		return Empty{}, b.Delete(c.Param("id"))
+	})
+
+	Post(blobs, "/uploads", "createUpload", "Start a resumable upload", func(c *gin.Context, req UploadRequest) (UploadStatus, error) {
+		return b.CreateUpload(req)
+	})
+
+	Get(blobs, "/uploads/:id", "getUpload", "Get the offset to resume an upload from", func(c *gin.Context) (UploadStatus, error) {
+		return b.Upload(c.Param("id"))
+	})
+
+	blobs.Raw(http.MethodPut, "/uploads/:id/chunks/:offset", "uploadChunk", "Write the raw body at offset, which must match the upload's offset; returns the blob after the last chunk", "application/json", func(c *gin.Context) {
+		offset, err := strconv.ParseInt(c.Param("offset"), 10, 64)
+		if err != nil {
+			respond(c, nil, Errorf(http.StatusBadRequest, "invalid offset %q", c.Param("offset")))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, b.maxSize+1)
+		status, err := b.AppendChunk(c.Param("id"), offset, c.Request.Body)
+		c.Header("Upload-Offset", strconv.FormatInt(status.Offset, 10))
+		respond(c, status, bodyError(err))
+	})
+
+	Delete(blobs, "/uploads/:id", "cancelUpload", "Discard an unfinished upload", func(c *gin.Context) (Empty, error) {
+		return Empty{}, b.CancelUpload(c.Param("id"))
+	})
+}
+
+// bodyError turns a request body over the limit into a 413.
+func bodyError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return Errorf(http.StatusRequestEntityTooLarge, "upload too large")
+	}
+	return err
+}
+
+// detectType sniffs the content type. The declared type is only used when
+// sniffing is inconclusive, so a client can't label a script as an image.
+func detectType(declared string, head []byte) string {
+	sniffed := http.DetectContentType(head)
+	declared, _, _ = mime.ParseMediaType(declared)
+	if declared != "" && declared != "application/octet-stream" && (sniffed == "application/octet-stream" || strings.HasPrefix(sniffed, "text/plain")) {
+		return declared
+	}
+	return sniffed
+}
+
+// typeAllowed matches a content type against patterns like "image/*".
+func typeAllowed(contentType string, patterns []string) bool {
+	contentType, _, _ = mime.ParseMediaType(contentType)
+	for _, p := range patterns {
+		if p == "*" || p == contentType || strings.HasSuffix(p, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(p, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// inlineSafe reports whether a type can be shown in the browser without
+// running code.
+func inlineSafe(contentType string) bool {
+	contentType, _, _ = mime.ParseMediaType(contentType)
+	if contentType == "image/svg+xml" {
+		return false
+	}
+	return contentType == "application/pdf" || contentType == "text/plain" ||
+		strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "audio/") || strings.HasPrefix(contentType, "video/")
+}
+
+// cleanName keeps only the base name of an uploaded file.
+func cleanName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" || name == "" {
+		return "file"
+	}
+	return name
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// envPrefix is prepended to every option key when reading the environment,
+// e.g. "log-level" is read from {{ENV_PREFIX}}_LOG_LEVEL.
+const envPrefix = "{{ENV_PREFIX}}_"
+
+// Config holds the runtime settings of the server. Values are resolved from,
+// in increasing priority: built-in defaults, the optional JSON config file,
+// {{ENV_PREFIX}}_* environment variables and command-line flags.
+type Config struct {
+	Host      string
+	Port      int
+	LogLevel  string
+	LogFormat string
+	DataDir   string
+
+	ShutdownTimeout time.Duration
+
+	TLSCert       string
+	TLSKey        string
+	TLSSelfSigned bool
+
+	Metrics bool
+
+	Dev     bool
+	ViteURL string
+
+	Security  bool
+	CORS      string
+	CSP       string
+	MaxBodyMB int
+	RateLimit int
+	RateBurst int
+
+	UpdateURL      string
+	UpdateInterval time.Duration
+	NoUpdate       bool
+
+	AdminToken string
+	SyncToken  string
+
+	BackupDir      string
+	BackupInterval time.Duration
+	BackupKeep     int
+	RestoreMaxMB   int
+
+	BlobMaxMB int
+	BlobTypes string
+
+	// sources records where each option got its value from
+	sources map[string]string
+}
+
+// option describes one setting. The key is used as the flag name, the
+// config file key and (upper-cased) the environment variable suffix.
+type option struct {
+	key    string
+	usage  string
+	secret bool
+	field  func(c *Config) any
+}
+
+var options = []option{
+	{key: "host", usage: "bind address (empty for all interfaces)", field: func(c *Config) any { return &c.Host }},
+	{key: "port", usage: "HTTP port", field: func(c *Config) any { return &c.Port }},
+	{key: "log-level", usage: "log level: debug, info, warn or error", field: func(c *Config) any { return &c.LogLevel }},
+	{key: "log-format", usage: "log format: text or json", field: func(c *Config) any { return &c.LogFormat }},
+	{key: "data-dir", usage: "directory for application data", field: func(c *Config) any { return &c.DataDir }},
+	{key: "shutdown-timeout", usage: "how long to wait for in-flight requests on shutdown", field: func(c *Config) any { return &c.ShutdownTimeout }},
+	{key: "tls-cert", usage: "TLS certificate file (enables HTTPS)", field: func(c *Config) any { return &c.TLSCert }},
+	{key: "tls-key", usage: "TLS private key file", field: func(c *Config) any { return &c.TLSKey }},
+	{key: "tls-self-signed", usage: "serve HTTPS with a generated self-signed certificate", field: func(c *Config) any { return &c.TLSSelfSigned }},
+	{key: "metrics", usage: "serve Prometheus metrics at /api/metrics", field: func(c *Config) any { return &c.Metrics }},
+	{key: "dev", usage: "development mode: proxy the frontend to Vite and restart on .go changes", field: func(c *Config) any { return &c.Dev }},
+	{key: "vite-url", usage: "Vite dev server URL (with --dev)", field: func(c *Config) any { return &c.ViteURL }},
+	{key: "security", usage: "enable security headers, body size limit, rate limiting and JSON panic recovery", field: func(c *Config) any { return &c.Security }},
+	{key: "cors-origins", usage: "comma-separated origins allowed to call the server cross-origin (* for any)", field: func(c *Config) any { return &c.CORS }},
+	{key: "csp", usage: "Content-Security-Policy header (with --security)", field: func(c *Config) any { return &c.CSP }},
+	{key: "max-body-mb", usage: "maximum request body size in MB (with --security)", field: func(c *Config) any { return &c.MaxBodyMB }},
+	{key: "rate-limit", usage: "requests per second per client IP, 0 to disable (with --security)", field: func(c *Config) any { return &c.RateLimit }},
+	{key: "rate-burst", usage: "requests a client IP may burst above the rate limit", field: func(c *Config) any { return &c.RateBurst }},
+	{key: "update-url", usage: "self-update manifest: an http(s) URL or a local file", field: func(c *Config) any { return &c.UpdateURL }},
+	{key: "update-interval", usage: "how often to check for updates", field: func(c *Config) any { return &c.UpdateInterval }},
+	{key: "no-update", usage: "never update this binary automatically", field: func(c *Config) any { return &c.NoUpdate }},
+	{key: "admin-token", usage: "bearer token for /api/admin (without one, admin is localhost-only)", secret: true, field: func(c *Config) any { return &c.AdminToken }},
+	{key: "sync-token", usage: "bearer token for /api/docs and replicating it (without one, the documents are localhost-only)", secret: true, field: func(c *Config) any { return &c.SyncToken }},
+	{key: "backup-dir", usage: "directory for data snapshots (outside data-dir)", field: func(c *Config) any { return &c.BackupDir }},
+	{key: "backup-interval", usage: "how often to snapshot the data directory, 0 to disable", field: func(c *Config) any { return &c.BackupInterval }},
+	{key: "backup-keep", usage: "number of snapshots to keep, 0 for all", field: func(c *Config) any { return &c.BackupKeep }},
+	{key: "restore-max-mb", usage: "maximum size of a restored backup in MB, unpacked", field: func(c *Config) any { return &c.RestoreMaxMB }},
+	{key: "blob-max-mb", usage: "maximum size of an uploaded file in MB", field: func(c *Config) any { return &c.BlobMaxMB }},
+	{key: "blob-types", usage: "comma-separated content types accepted for upload, such as image/* (* for any)", field: func(c *Config) any { return &c.BlobTypes }},
+}
+
+func (o option) envKey() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(o.key, "-", "_"))
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Host:      "",
+		Port:      {{PORT}},
+		LogLevel:  "info",
+		LogFormat: "text",
+		DataDir:   "data",
+
+		ShutdownTimeout: 15 * time.Second,
+
+		ViteURL: "http://localhost:5173",
+
+		CSP:       defaultCSP,
+		MaxBodyMB: 10,
+		RateLimit: 20,
+		RateBurst: 40,
+
+		UpdateURL:      updateURL,
+		UpdateInterval: 6 * time.Hour,
+
+		BackupDir:    "backups",
+		BackupKeep:   7,
+		RestoreMaxMB: 1024,
+
+		BlobMaxMB: 100,
+		BlobTypes: "image/*,audio/*,video/*,text/plain,text/csv,application/pdf,application/json,application/zip",
+
+		sources: map[string]string{},
+	}
+}
+
+// LoadConfig resolves the configuration from defaults, config file,
+// environment and the given command-line arguments, then validates it.
+func LoadConfig(args []string) (*Config, error) {
+	cfg := defaultConfig()
+
+	fs := flag.NewFlagSet("{{PROJECT_SLUG}}", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv(envPrefix+"CONFIG"), "path to a JSON config file")
+	flagged := map[string]string{}
+	for _, o := range options {
+		_, isBool := o.field(cfg).(*bool)
+		fs.Var(&flagValue{key: o.key, set: flagged, isBool: isBool}, o.key, o.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, o := range options {
+		if v, ok := os.LookupEnv(o.envKey()); ok {
+			if err := setField(o.field(cfg), v); err != nil {
+				return nil, fmt.Errorf("%s: %w", o.envKey(), err)
+			}
+			cfg.sources[o.key] = "env"
+		}
+	}
+
+	for _, o := range options {
+		if v, ok := flagged[o.key]; ok {
+			if err := setField(o.field(cfg), v); err != nil {
+				return nil, fmt.Errorf("-%s: %w", o.key, err)
+			}
+			cfg.sources[o.key] = "flag"
+		}
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// loadFile applies the settings found in a JSON config file. Keys match the
+// flag names; unknown keys are rejected so typos don't go unnoticed.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	for _, o := range options {
+		value, ok := raw[o.key]
+		if !ok {
+			continue
+		}
+		delete(raw, o.key)
+
+		text := string(value)
+		var s string
+		if json.Unmarshal(value, &s) == nil {
+			text = s
+		}
+		if err := setField(o.field(c), text); err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, o.key, err)
+		}
+		c.sources[o.key] = "file"
+	}
+
+	for key := range raw {
+		return fmt.Errorf("config file %s: unknown setting %q", path, key)
+	}
+	return nil
+}
+
+// Validate checks the resolved values and prepares the data directory.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port must be between 1 and 65535, got %d", c.Port))
+	}
+
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log-level must be debug, info, warn or error, got %q", c.LogLevel))
+	}
+
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		errs = append(errs, fmt.Errorf("log-format must be text or json, got %q", c.LogFormat))
+	}
+
+	if c.DataDir == "" {
+		errs = append(errs, errors.New("data-dir must not be empty"))
+	} else if err := os.MkdirAll(c.DataDir, 0755); err != nil {
+		errs = append(errs, fmt.Errorf("data-dir: %w", err))
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("shutdown-timeout must be positive, got %s", c.ShutdownTimeout))
+	}
+
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		errs = append(errs, errors.New("tls-cert and tls-key must be set together"))
+	}
+	if c.TLSCert != "" && c.TLSSelfSigned {
+		errs = append(errs, errors.New("tls-self-signed cannot be combined with tls-cert"))
+	}
+
+	if u, err := url.Parse(c.ViteURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("vite-url must be an http(s) URL, got %q", c.ViteURL))
+	}
+
+	for _, origin := range c.CORSOrigins() {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			errs = append(errs, fmt.Errorf("cors-origins: %q is not an http(s) origin", origin))
+		}
+	}
+	if c.MaxBodyMB < 1 {
+		errs = append(errs, fmt.Errorf("max-body-mb must be at least 1, got %d", c.MaxBodyMB))
+	}
+	if c.RateLimit < 0 {
+		errs = append(errs, fmt.Errorf("rate-limit must not be negative, got %d", c.RateLimit))
+	}
+	if c.RateBurst < 1 {
+		errs = append(errs, fmt.Errorf("rate-burst must be at least 1, got %d", c.RateBurst))
+	}
+
+	if c.UpdateInterval < time.Minute {
+		errs = append(errs, fmt.Errorf("update-interval must be at least 1m, got %s", c.UpdateInterval))
+	}
+
+	if c.BackupDir == "" {
+		errs = append(errs, errors.New("backup-dir must not be empty"))
+	} else if within(c.BackupDir, c.DataDir) {
+		errs = append(errs, errors.New("backup-dir must not be inside data-dir, restores replace the data directory"))
+	}
+	if c.BackupInterval != 0 && c.BackupInterval < time.Minute {
+		errs = append(errs, fmt.Errorf("backup-interval must be 0 or at least 1m, got %s", c.BackupInterval))
+	}
+	if c.BackupKeep < 0 {
+		errs = append(errs, fmt.Errorf("backup-keep must not be negative, got %d", c.BackupKeep))
+	}
+	if c.RestoreMaxMB < 1 {
+		errs = append(errs, fmt.Errorf("restore-max-mb must be at least 1, got %d", c.RestoreMaxMB))
+	}
+
+	if c.BlobMaxMB < 1 {
+		errs = append(errs, fmt.Errorf("blob-max-mb must be at least 1, got %d", c.BlobMaxMB))
+	}
+	if len(splitList(c.BlobTypes)) == 0 {
+		errs = append(errs, errors.New("blob-types must not be empty, use * to accept any type"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// CORSOrigins returns the configured cross-origin allow list.
+func (c *Config) CORSOrigins() []string {
+	return splitList(c.CORS)
+}
+
+// splitList splits a comma-separated option, dropping empty entries.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// within reports whether path is dir or inside it.
+func within(path, dir string) bool {
+	absPath, err1 := filepath.Abs(path)
+	absDir, err2 := filepath.Abs(dir)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// TLSEnabled reports whether the server should serve HTTPS.
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCert != "" || c.TLSSelfSigned
+}
+
+// Addr returns the listen address in host:port form.
+func (c *Config) Addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// Print writes the effective configuration and where each value came from.
+func (c *Config) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Configuration:")
+	for _, o := range options {
+		value := fmt.Sprintf("%v", fieldValue(o.field(c)))
+		if o.secret && value != "" {
+			value = "********"
+		} else if len(value) > 60 {
+			value = value[:57] + "..." // keep long values like csp from widening the table
+		}
+		source := c.sources[o.key]
+		if source == "" {
+			source = "default"
+		}
+		fmt.Fprintf(tw, "  %s\t%q\t(%s)\n", o.key, value, source)
+	}
+	tw.Flush()
+}
+
+// Public returns the non-secret settings, suitable for the health endpoint.
+func (c *Config) Public() map[string]any {
+	values := map[string]any{}
+	for _, o := range options {
+		if !o.secret {
+			values[o.key] = fieldValue(o.field(c))
+		}
+	}
+	return values
+}
+
+func setField(ptr any, s string) error {
+	switch p := ptr.(type) {
+	case *string:
+		*p = s
+	case *int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		*p = n
+	case *bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		*p = b
+	case *time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		*p = d
+	default:
+		return fmt.Errorf("unsupported setting type %T", ptr)
+	}
+	return nil
+}
+
+func fieldValue(ptr any) any {
+	switch p := ptr.(type) {
+	case *string:
+		return *p
+	case *int:
+		return *p
+	case *bool:
+		return *p
+	case *time.Duration:
+		return p.String()
+	default:
+		return nil
+	}
+}
+
+// flagValue records the raw value of a flag so it can be applied after the
+// config file and environment.
+type flagValue struct {
+	key    string
+	set    map[string]string
+	isBool bool
+}
+
+func (f *flagValue) String() string { return "" }
+
+func (f *flagValue) IsBoolFlag() bool { return f.isBool }
+
+func (f *flagValue) Set(s string) error {
+	f.set[f.key] = s
+	return nil
+}
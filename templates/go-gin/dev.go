@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// devChildEnv marks the server process started by the dev supervisor.
+const devChildEnv = envPrefix + "DEV_CHILD"
+
+// devPollInterval is how often the sources are checked for changes.
+const devPollInterval = 500 * time.Millisecond
+
+// runDev implements --dev: it builds the server, runs it as a child process
+// and rebuilds and restarts it whenever a .go file changes. The child
+// proxies the frontend to Vite, which does its own hot reloading.
+func runDev(cfg *Config) error {
+	if _, err := os.Stat("go.mod"); err != nil {
+		return errors.New("--dev must be run from the project directory (go run . --dev)")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dir, err := os.MkdirTemp("", "{{PROJECT_SLUG}}-dev-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	bin := filepath.Join(dir, "{{PROJECT_SLUG}}")
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+
+	checkVite(cfg.ViteURL)
+
+	var child *devChild
+	defer func() { child.stop(cfg.ShutdownTimeout) }()
+
+	restart := func() {
+		build := exec.CommandContext(ctx, "go", "build", "-o", bin, ".")
+		build.Stdout, build.Stderr = os.Stderr, os.Stderr
+		if err := build.Run(); err != nil {
+			if ctx.Err() == nil {
+				slog.Error("build failed, fix the error and save again", "error", err)
+			}
+			return
+		}
+
+		child.stop(cfg.ShutdownTimeout)
+		if child, err = startDevChild(bin); err != nil {
+			slog.Error("failed to start server", "error", err)
+		}
+	}
+
+	sources := goSources(cfg.DataDir)
+	restart()
+	slog.Info("dev mode: watching .go files", "vite", cfg.ViteURL)
+
+	ticker := time.NewTicker(devPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			current := goSources(cfg.DataDir)
+			if maps.Equal(current, sources) {
+				continue
+			}
+			sources = current
+			slog.Info("change detected, rebuilding")
+			restart()
+		}
+	}
+}
+
+// goSources returns the modification times of the files that make up the
+// server binary.
+func goSources(dataDir string) map[string]time.Time {
+	files := map[string]time.Time{}
+	filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != "." && (strings.HasPrefix(name, ".") || name == "frontend" || name == "node_modules" || path == filepath.Clean(dataDir)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".go") || path == "go.mod" || path == "go.sum" {
+			if info, err := d.Info(); err == nil {
+				files[path] = info.ModTime()
+			}
+		}
+		return nil
+	})
+	return files
+}
+
+// checkVite warns when nothing is listening at the Vite URL yet.
+func checkVite(viteURL string) {
+	client := http.Client{Timeout: time.Second}
+	resp, err := client.Get(viteURL)
+	if err != nil {
+		slog.Warn("Vite dev server not reachable, start it with `cd frontend && npm run dev`", "url", viteURL)
+		return
+	}
+	resp.Body.Close()
+}
+
+type devChild struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+}
+
+func startDevChild(bin string) (*devChild, error) {
+	cmd := exec.Command(bin, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), devChildEnv+"=1")
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c := &devChild{cmd: cmd, done: make(chan struct{})}
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			slog.Warn("server exited", "error", err)
+		}
+		close(c.done)
+	}()
+	return c, nil
+}
+
+// stop asks the child to shut down gracefully and kills it if it hasn't
+// exited within the shutdown timeout.
+func (c *devChild) stop(timeout time.Duration) {
+	if c == nil {
+		return
+	}
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	// Windows can't deliver SIGTERM; Signal fails there and we kill instead
+	if err := c.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		c.cmd.Process.Kill()
+	}
+	select {
+	case <-c.done:
+	case <-time.After(timeout + time.Second):
+		c.cmd.Process.Kill()
+		<-c.done
+	}
+}
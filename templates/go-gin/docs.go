@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PutResult is returned after storing a document.
+type PutResult struct {
+	ID string `json:"id"`
+}
+
+// ImportResult is returned by POST /api/docs/import.
+type ImportResult struct {
+	Imported int `json:"imported"`
+}
+
+// registerDocs adds the /docs endpoints of the document store and
+// publishes every change to the "docs" realtime topic, so browsers can
+// follow the store like a Fireproof live query. With /docs/changes and
+// /docs/push the server is also the hub that desktop cherries replicate
+// with (see replicate.go). Every route and the topic give access to all
+// documents, so they all need syncToken (see SyncAuth).
+func registerDocs(api *API, store *DocStore, hub *Hub, syncToken string) {
+	store.Subscribe(func(c Change) {
+		hub.Publish("docs", "change", c)
+	})
+	hub.Guard("docs", func(r *http.Request) error {
+		if err := checkBearer(r, syncToken, "sync"); err != nil {
+			return err
+		}
+		return nil
+	})
+	docs := api.Group("/docs", SyncAuth(syncToken))
+
+	Get(docs, "", "queryDocs", "List documents; ?field=type&key=todo returns those whose field equals key (JSON or a plain string)", func(c *gin.Context) ([]Doc, error) {
+		field := c.Query("field")
+		if field == "" {
+			return store.All(), nil
+		}
+		raw, ok := c.GetQuery("key")
+		if !ok {
+			return store.Query(field, nil), nil
+		}
+		var key any = raw
+		if json.Valid([]byte(raw)) {
+			json.Unmarshal([]byte(raw), &key)
+		}
+		return store.Query(field, key), nil
+	})
+
+	Post(docs, "", "createDoc", "Store a document; a missing _id is generated", func(c *gin.Context, doc Doc) (PutResult, error) {
+		id, err := store.Put(doc)
+		return PutResult{ID: id}, docError(err)
+	})
+
+	Get(docs, "/changes", "getDocChanges", "Documents changed after ?since= (a seq from an earlier call)", func(c *gin.Context) (ChangesResponse, error) {
+		since, err := strconv.ParseUint(c.DefaultQuery("since", "0"), 10, 64)
+		if err != nil {
+			return ChangesResponse{}, Errorf(http.StatusBadRequest, "invalid since %q", c.Query("since"))
+		}
+		changes, seq := store.Changes(since)
+		if changes == nil {
+			changes = []Change{}
+		}
+		return ChangesResponse{Changes: changes, Seq: seq}, nil
+	})
+
+	Post(docs, "/push", "pushDocChanges", "Replicate changes from another store; changes whose baseRev is no longer current are returned as rejected, with the current version", func(c *gin.Context, req PushRequest) (PushResult, error) {
+		rejected, err := store.Apply(req.Changes)
+		if rejected == nil {
+			rejected = []Change{}
+		}
+		return PushResult{Rejected: rejected}, docError(err)
+	})
+
+	Get(docs, "/export", "exportDocs", "Export every document, in the format of the web templates' exportDocs", func(c *gin.Context) (DocExport, error) {
+		return store.Dump(), nil
+	})
+
+	Post(docs, "/import", "importDocs", "Import documents from an export; documents with _deleted are deleted", func(c *gin.Context, export DocExport) (ImportResult, error) {
+		if _, err := store.Bulk(export.Docs); err != nil {
+			return ImportResult{}, docError(err)
+		}
+		return ImportResult{Imported: len(export.Docs)}, nil
+	})
+
+	Get(docs, "/:id", "getDoc", "Get a document", func(c *gin.Context) (Doc, error) {
+		doc, err := store.Get(c.Param("id"))
+		return doc, docError(err)
+	})
+
+	Put(docs, "/:id", "putDoc", "Create or replace a document", func(c *gin.Context, doc Doc) (PutResult, error) {
+		doc["_id"] = c.Param("id")
+		id, err := store.Put(doc)
+		return PutResult{ID: id}, docError(err)
+	})
+
+	Delete(docs, "/:id", "deleteDoc", "Delete a document", func(c *gin.Context) (Empty, error) {
+		return Empty{}, store.Del(c.Param("id"))
+	})
+}
+
+// docError maps document store errors to HTTP statuses.
+func docError(err error) error {
+	switch {
+	case errors.Is(err, ErrDocNotFound):
+		return Errorf(http.StatusNotFound, "%v", err)
+	case errors.Is(err, ErrInvalidDoc):
+		return Errorf(http.StatusBadRequest, "%v", err)
+	}
+	return err
+}
@@ -0,0 +1,776 @@
+package main
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DocStore is a JSON document store with the data model of Fireproof, the
+// database of the web templates: documents are JSON objects identified by
+// "_id", usually with a "type" and a "created" time in milliseconds, queried
+// by the value of a field and followed through a change feed. An export
+// from a web cherry imports here, and the other way around.
+//
+// Every write gives the document a new revision, which replication (see
+// replicate.go) uses to tell which side changed it. Documents are kept in
+// memory and saved to one JSON file after every write, which suits the few
+// thousand documents of a desktop or small server app. This file is the
+// same in every Go template.
+type DocStore struct {
+	path  string
+	name  string
+	guard func(func() error) error
+
+	mu      sync.Mutex
+	seq     uint64
+	docs    map[string]docEntry
+	deleted map[string]tombstone
+	indexes map[string]map[string][]string // field -> JSON key -> IDs
+	subs    map[int]func(Change)
+	nextSub int
+
+	// notifyMu keeps subscribers seeing changes in order
+	notifyMu sync.Mutex
+}
+
+// Doc is a JSON document.
+type Doc map[string]any
+
+// ID returns the document's "_id".
+func (d Doc) ID() string {
+	id, _ := d["_id"].(string)
+	return id
+}
+
+// Change is one entry of the change feed. Deleted changes have no Doc.
+// BaseRev is only set on changes pushed to a replication hub.
+type Change struct {
+	Seq     uint64 `json:"seq"`
+	ID      string `json:"id"`
+	Doc     Doc    `json:"doc,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+	Rev     string `json:"rev,omitempty"`
+	BaseRev string `json:"baseRev,omitempty"`
+}
+
+// DocExport is the JSON export format, shared with the web templates'
+// exportDocs and importDocs.
+type DocExport struct {
+	Name     string    `json:"name"`
+	Exported time.Time `json:"exported"`
+	Docs     []Doc     `json:"docs"`
+}
+
+var (
+	// ErrDocNotFound is returned by Get for unknown IDs.
+	ErrDocNotFound = errors.New("document not found")
+	// ErrInvalidDoc is returned for documents that can't be stored.
+	ErrInvalidDoc = errors.New("invalid document")
+)
+
+// docStoreVersion is the version of the file layout. Bump it when the
+// layout changes and upgrade older files in load.
+const docStoreVersion = 2
+
+type docEntry struct {
+	Seq uint64 `json:"seq"`
+	Rev string `json:"rev"`
+	Doc Doc    `json:"doc"`
+}
+
+// tombstone records a delete, so it reaches the change feed and replicas.
+type tombstone struct {
+	Seq uint64 `json:"seq"`
+	Rev string `json:"rev"`
+}
+
+type docFile struct {
+	Version int                  `json:"version"`
+	Name    string               `json:"name"`
+	Seq     uint64               `json:"seq"`
+	Docs    []docEntry           `json:"docs"`
+	Deleted map[string]tombstone `json:"deleted,omitempty"`
+}
+
+// OpenDocStore opens the store saved at path, named name in exports. An
+// empty path keeps the documents in memory only. A file that can't be
+// parsed is moved aside, so it can still be recovered by hand, and the
+// store starts empty; a file from a newer version is an error.
+func OpenDocStore(path, name string) (*DocStore, error) {
+	s := &DocStore{
+		path:  path,
+		name:  name,
+		guard: func(write func() error) error { return write() },
+		subs:  map[int]func(Change){},
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Guard runs every write through guard, e.g. a backup lock.
+func (s *DocStore) Guard(guard func(write func() error) error) {
+	s.guard = guard
+}
+
+// Reload reads the file again, e.g. after it was restored from a backup.
+func (s *DocStore) Reload() error {
+	return s.load()
+}
+
+func (s *DocStore) load() error {
+	file := docFile{Version: docStoreVersion}
+	if s.path != "" {
+		data, err := os.ReadFile(s.path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return err
+		default:
+			var head struct {
+				Version int `json:"version"`
+			}
+			if json.Unmarshal(data, &head) == nil && head.Version > docStoreVersion {
+				return fmt.Errorf("%s was written by a newer version (schema %d, this build reads up to %d)", s.path, head.Version, docStoreVersion)
+			}
+			if file, err = parseDocFile(data); err != nil {
+				aside := fmt.Sprintf("%s.corrupt-%s", s.path, time.Now().Format("20060102-150405"))
+				if rerr := os.Rename(s.path, aside); rerr != nil {
+					return fmt.Errorf("%s is damaged (%v) and could not be moved aside: %w", s.path, err, rerr)
+				}
+				log.Printf("%s is damaged (%v), moved it to %s and started empty", s.path, err, aside)
+				file = docFile{Version: docStoreVersion}
+			}
+		}
+	}
+
+	docs := make(map[string]docEntry, len(file.Docs))
+	for _, e := range file.Docs {
+		if id := e.Doc.ID(); id != "" {
+			docs[id] = e
+			file.Seq = max(file.Seq, e.Seq)
+		}
+	}
+	if file.Deleted == nil {
+		file.Deleted = map[string]tombstone{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq = file.Seq
+	s.docs = docs
+	s.deleted = file.Deleted
+	s.indexes = map[string]map[string][]string{}
+	return nil
+}
+
+// parseDocFile reads a store file, upgrading older layouts.
+func parseDocFile(data []byte) (docFile, error) {
+	var file docFile
+	var head struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return file, err
+	}
+	if head.Version < 2 {
+		// Version 1 had no revisions and kept only the seq of deletes
+		var v1 struct {
+			docFile
+			Deleted map[string]uint64 `json:"deleted"`
+		}
+		if err := json.Unmarshal(data, &v1); err != nil {
+			return file, err
+		}
+		file = v1.docFile
+		file.Deleted = map[string]tombstone{}
+		for id, seq := range v1.Deleted {
+			file.Deleted[id] = tombstone{Seq: seq, Rev: newDocID()}
+		}
+		for i := range file.Docs {
+			file.Docs[i].Rev = newDocID()
+		}
+		file.Version = 2
+		return file, nil
+	}
+	err := json.Unmarshal(data, &file)
+	return file, err
+}
+
+// save writes the store to its file. The caller holds s.mu.
+func (s *DocStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	file := docFile{Version: docStoreVersion, Name: s.name, Seq: s.seq, Deleted: s.deleted}
+	for _, e := range s.docs {
+		file.Docs = append(file.Docs, e)
+	}
+	slices.SortFunc(file.Docs, func(a, b docEntry) int { return strings.Compare(a.Doc.ID(), b.Doc.ID()) })
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory,
+// flushes it to disk and renames it over path, so a crash never leaves a
+// half-written file.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Put stores a document and returns its ID. A document without "_id"
+// gets a new one.
+func (s *DocStore) Put(doc Doc) (string, error) {
+	ids, err := s.Bulk([]Doc{doc})
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+// Del deletes a document. Deleting a missing document does nothing.
+func (s *DocStore) Del(id string) error {
+	_, err := s.Bulk([]Doc{{"_id": id, "_deleted": true}})
+	return err
+}
+
+// Bulk writes several documents at once, with a single save. Documents
+// with "_deleted": true are deleted. Either every document is written or,
+// on error, none is.
+func (s *DocStore) Bulk(docs []Doc) ([]string, error) {
+	ops := make([]docOp, len(docs))
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		d, err := normalizeDoc(doc)
+		if err != nil {
+			return nil, fmt.Errorf("%w %d: %v", ErrInvalidDoc, i, err)
+		}
+		switch id := d["_id"].(type) {
+		case nil:
+			d["_id"] = newDocID()
+		case string:
+			if id == "" {
+				d["_id"] = newDocID()
+			}
+		default:
+			return nil, fmt.Errorf("%w %d: _id must be a string", ErrInvalidDoc, i)
+		}
+		ops[i] = docOp{doc: d}
+		ids[i] = d.ID()
+	}
+	_, err := s.write(ops)
+	return ids, err
+}
+
+// Apply writes changes replicated from another store, keeping their
+// revisions. A change is only written while the document is still at its
+// BaseRev; for the others the current version is returned, to be merged
+// by the caller. Changes the store already has are skipped.
+func (s *DocStore) Apply(changes []Change) ([]Change, error) {
+	ops := make([]docOp, len(changes))
+	for i, c := range changes {
+		if c.ID == "" || c.Rev == "" {
+			return nil, fmt.Errorf("%w %d: a replicated change needs an id and a rev", ErrInvalidDoc, i)
+		}
+		d := Doc{"_deleted": true}
+		if !c.Deleted {
+			var err error
+			if d, err = normalizeDoc(c.Doc); err != nil {
+				return nil, fmt.Errorf("%w %d: %v", ErrInvalidDoc, i, err)
+			}
+		}
+		d["_id"] = c.ID
+		ops[i] = docOp{doc: d, rev: c.Rev, base: c.BaseRev, replicated: true}
+	}
+	return s.write(ops)
+}
+
+// docOp is one write of Bulk or Apply.
+type docOp struct {
+	doc        Doc    // normalized, with an _id
+	rev        string // the revision to store, or "" for a new one
+	base       string // for replicated writes, the revision to replace
+	replicated bool
+}
+
+// write performs ops with a single save and notifies subscribers. It
+// returns the current version of replicated documents that were not at
+// their base revision.
+func (s *DocStore) write(ops []docOp) ([]Change, error) {
+	var rejected []Change
+	return rejected, s.guard(func() error {
+		rejected = nil
+		s.mu.Lock()
+		locked := true
+		defer func() {
+			if locked {
+				s.mu.Unlock()
+			}
+		}()
+
+		// Remember the previous state, to roll back if saving fails
+		type previous struct {
+			entry     docEntry
+			existed   bool
+			tombstone tombstone
+			wasGone   bool
+		}
+		prev := map[string]previous{}
+		seq := s.seq
+		var changes []Change
+		for _, op := range ops {
+			d := op.doc
+			id := d.ID()
+			if _, seen := prev[id]; !seen {
+				e, ok := s.docs[id]
+				t, gone := s.deleted[id]
+				prev[id] = previous{entry: e, existed: ok, tombstone: t, wasGone: gone}
+			}
+			if op.replicated {
+				current := s.version(id)
+				if current.Rev == op.rev {
+					continue
+				}
+				if current.Rev != op.base {
+					rejected = append(rejected, current)
+					continue
+				}
+			}
+			rev := op.rev
+			if rev == "" {
+				rev = newDocID()
+			}
+			if deleted, _ := d["_deleted"].(bool); deleted {
+				if _, ok := s.docs[id]; !ok && !op.replicated {
+					continue
+				}
+				s.seq++
+				delete(s.docs, id)
+				s.deleted[id] = tombstone{Seq: s.seq, Rev: rev}
+				changes = append(changes, Change{Seq: s.seq, ID: id, Deleted: true, Rev: rev})
+				continue
+			}
+			delete(d, "_deleted")
+			s.seq++
+			s.docs[id] = docEntry{Seq: s.seq, Rev: rev, Doc: d}
+			delete(s.deleted, id)
+			changes = append(changes, Change{Seq: s.seq, ID: id, Doc: d, Rev: rev})
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+
+		if err := s.save(); err != nil {
+			for id, p := range prev {
+				if p.existed {
+					s.docs[id] = p.entry
+				} else {
+					delete(s.docs, id)
+				}
+				if p.wasGone {
+					s.deleted[id] = p.tombstone
+				} else {
+					delete(s.deleted, id)
+				}
+			}
+			s.seq = seq
+			return err
+		}
+		for id, p := range prev {
+			var old Doc
+			if p.existed {
+				old = p.entry.Doc
+			}
+			s.reindex(id, old, s.docs[id].Doc)
+		}
+
+		// Hand over to notifyMu before unlocking, so subscribers get the
+		// changes in order but may read the store
+		subs := make([]func(Change), 0, len(s.subs))
+		for _, fn := range s.subs {
+			subs = append(subs, fn)
+		}
+		s.notifyMu.Lock()
+		defer s.notifyMu.Unlock()
+		s.mu.Unlock()
+		locked = false
+		for _, c := range changes {
+			for _, fn := range subs {
+				fn(Change{Seq: c.Seq, ID: c.ID, Doc: cloneDoc(c.Doc), Deleted: c.Deleted, Rev: c.Rev})
+			}
+		}
+		return nil
+	})
+}
+
+// Get returns a copy of a document.
+func (s *DocStore) Get(id string) (Doc, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.docs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrDocNotFound, id)
+	}
+	return cloneDoc(e.Doc), nil
+}
+
+// Version returns the latest change of a document, with its revision. For
+// a document the store has never seen, Rev is empty.
+func (s *DocStore) Version(id string) Change {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version(id)
+}
+
+// version is Version for callers that hold s.mu.
+func (s *DocStore) version(id string) Change {
+	if e, ok := s.docs[id]; ok {
+		return Change{Seq: e.Seq, ID: id, Doc: cloneDoc(e.Doc), Rev: e.Rev}
+	}
+	if t, ok := s.deleted[id]; ok {
+		return Change{Seq: t.Seq, ID: id, Deleted: true, Rev: t.Rev}
+	}
+	return Change{ID: id}
+}
+
+// Query returns the documents whose field equals key, ordered by ID, like
+// Fireproof's db.query(field, { key }). With a nil key it returns every
+// document that has the field, ordered by its value. The first query of a
+// field builds an index that later writes keep up to date.
+func (s *DocStore) Query(field string, key any) []Doc {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index := s.index(field)
+
+	var ids []string
+	if key != nil {
+		k, err := indexKey(key)
+		if err != nil {
+			return nil
+		}
+		ids = index[k]
+	} else {
+		for _, keyIDs := range index {
+			ids = append(ids, keyIDs...)
+		}
+	}
+
+	docs := make([]Doc, 0, len(ids))
+	for _, id := range ids {
+		docs = append(docs, cloneDoc(s.docs[id].Doc))
+	}
+	slices.SortFunc(docs, func(a, b Doc) int {
+		if c := compareValues(a[field], b[field]); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID(), b.ID())
+	})
+	return docs
+}
+
+// All returns every document, ordered by ID.
+func (s *DocStore) All() []Doc {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	docs := make([]Doc, 0, len(s.docs))
+	for _, e := range s.docs {
+		docs = append(docs, cloneDoc(e.Doc))
+	}
+	slices.SortFunc(docs, func(a, b Doc) int { return strings.Compare(a.ID(), b.ID()) })
+	return docs
+}
+
+// Seq returns the sequence number of the latest write; 0 means the store
+// has never been written to.
+func (s *DocStore) Seq() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seq
+}
+
+// Changes returns the latest change of every document written after the
+// given sequence number, oldest first, and the current sequence number to
+// pass next time.
+func (s *DocStore) Changes(since uint64) ([]Change, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var changes []Change
+	for id, e := range s.docs {
+		if e.Seq > since {
+			changes = append(changes, Change{Seq: e.Seq, ID: id, Doc: cloneDoc(e.Doc), Rev: e.Rev})
+		}
+	}
+	for id, t := range s.deleted {
+		if t.Seq > since {
+			changes = append(changes, Change{Seq: t.Seq, ID: id, Deleted: true, Rev: t.Rev})
+		}
+	}
+	slices.SortFunc(changes, func(a, b Change) int { return cmp.Compare(a.Seq, b.Seq) })
+	return changes, s.seq
+}
+
+// Subscribe calls fn after every change until cancel is called. fn runs
+// on the writing goroutine and must not write to the store.
+func (s *DocStore) Subscribe(fn func(Change)) (cancel func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextSub
+	s.nextSub++
+	s.subs[id] = fn
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subs, id)
+	}
+}
+
+// Dump returns every document as a DocExport.
+func (s *DocStore) Dump() DocExport {
+	return DocExport{Name: s.name, Exported: time.Now().UTC(), Docs: s.All()}
+}
+
+// Export writes every document as a DocExport.
+func (s *DocStore) Export(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s.Dump())
+}
+
+// Import stores the documents of an export and returns how many it read.
+// Besides a DocExport it accepts a plain array of documents and the
+// { rows: [{ value }] } result of Fireproof's db.allDocs().
+func (s *DocStore) Import(r io.Reader) (int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	var docs []Doc
+	if err := json.Unmarshal(data, &docs); err != nil {
+		var export struct {
+			Docs []Doc `json:"docs"`
+			Rows []struct {
+				Value Doc `json:"value"`
+			} `json:"rows"`
+		}
+		if err := json.Unmarshal(data, &export); err != nil {
+			return 0, fmt.Errorf("not a document export: %w", err)
+		}
+		docs = export.Docs
+		for _, row := range export.Rows {
+			docs = append(docs, row.Value)
+		}
+	}
+	if len(docs) == 0 {
+		return 0, nil
+	}
+	if _, err := s.Bulk(docs); err != nil {
+		return 0, err
+	}
+	return len(docs), nil
+}
+
+// index returns the index of field, building it on first use. The caller
+// holds s.mu.
+func (s *DocStore) index(field string) map[string][]string {
+	if index, ok := s.indexes[field]; ok {
+		return index
+	}
+	index := map[string][]string{}
+	for id, e := range s.docs {
+		if v, ok := e.Doc[field]; ok {
+			if k, err := indexKey(v); err == nil {
+				index[k] = append(index[k], id)
+			}
+		}
+	}
+	s.indexes[field] = index
+	return index
+}
+
+// reindex moves a document between index keys after a write. The caller
+// holds s.mu.
+func (s *DocStore) reindex(id string, old, doc Doc) {
+	for field, index := range s.indexes {
+		if v, ok := old[field]; ok {
+			if k, err := indexKey(v); err == nil {
+				index[k] = slices.DeleteFunc(index[k], func(other string) bool { return other == id })
+				if len(index[k]) == 0 {
+					delete(index, k)
+				}
+			}
+		}
+		if v, ok := doc[field]; ok {
+			if k, err := indexKey(v); err == nil {
+				index[k] = append(index[k], id)
+			}
+		}
+	}
+}
+
+// indexKey is the canonical JSON of a value, so 2 and 2.0 are the same key.
+func indexKey(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// normalizeDoc turns doc into plain JSON values (string, float64, bool,
+// nil, []any, map[string]any), which also copies it.
+func normalizeDoc(doc Doc) (Doc, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var d Doc
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, errors.New("document must be a JSON object")
+	}
+	return d, nil
+}
+
+func cloneDoc(doc Doc) Doc {
+	if doc == nil {
+		return nil
+	}
+	return Doc(cloneValue(map[string]any(doc)).(map[string]any))
+}
+
+func cloneValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for k, e := range v {
+			c[k] = cloneValue(e)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, e := range v {
+			c[i] = cloneValue(e)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// compareValues orders JSON values: null, false, true, numbers, strings,
+// arrays, objects.
+func compareValues(a, b any) int {
+	rank := func(v any) int {
+		switch v.(type) {
+		case nil:
+			return 0
+		case bool:
+			return 1
+		case float64:
+			return 2
+		case string:
+			return 3
+		case []any:
+			return 4
+		default:
+			return 5
+		}
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	switch a := a.(type) {
+	case bool:
+		if a == b.(bool) {
+			return 0
+		} else if a {
+			return 1
+		}
+		return -1
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case string:
+		return strings.Compare(a, b.(string))
+	case []any:
+		bs := b.([]any)
+		for i := 0; i < len(a) && i < len(bs); i++ {
+			if c := compareValues(a[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+		return len(a) - len(bs)
+	}
+	ka, _ := indexKey(a)
+	kb, _ := indexKey(b)
+	return strings.Compare(ka, kb)
+}
+
+var docIDs struct {
+	sync.Mutex
+	ms    int64
+	count uint16
+}
+
+// newDocID returns a UUIDv7: random like the crypto.randomUUID() IDs of
+// the web templates, but in creation order, also within a millisecond.
+func newDocID() string {
+	docIDs.Lock()
+	ms := time.Now().UnixMilli()
+	if ms <= docIDs.ms {
+		docIDs.count++
+		if docIDs.count == 0x1000 {
+			docIDs.ms++ // counter full, borrow the next millisecond
+			docIDs.count = 0
+		}
+		ms = docIDs.ms
+	} else {
+		docIDs.ms = ms
+		docIDs.count = 0
+	}
+	count := docIDs.count
+	docIDs.Unlock()
+
+	var u [16]byte
+	if _, err := rand.Read(u[8:]); err != nil {
+		panic(fmt.Sprintf("crypto/rand: %v", err))
+	}
+	binary.BigEndian.PutUint64(u[:8], uint64(ms)<<16|0x7000|uint64(count))
+	u[8] = u[8]&0x3f | 0x80
+	h := hex.EncodeToString(u[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
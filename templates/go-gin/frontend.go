@@ -0,0 +1,102 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"html"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// frontend holds the production build of the React app. The directory
+// always contains a .gitkeep, so the server compiles before the first
+// `npm run build`; "all:" is needed to embed Vite's _-prefixed chunks too.
+//
+//go:embed all:frontend/dist
+var frontend embed.FS
+
+// frontendHandler serves the React app: proxied from the Vite dev server in
+// dev mode, otherwise from the embedded build, or a placeholder page when
+// the frontend hasn't been built yet.
+func frontendHandler(cfg *Config) (http.Handler, error) {
+	if cfg.Dev {
+		target, err := url.Parse(cfg.ViteURL)
+		if err != nil {
+			return nil, fmt.Errorf("vite-url: %w", err)
+		}
+		return viteProxy(target), nil
+	}
+
+	if !frontendBuilt() {
+		return placeholderPage(), nil
+	}
+	dist, err := fs.Sub(frontend, "frontend/dist")
+	if err != nil {
+		return nil, err
+	}
+	return http.FileServer(http.FS(dist)), nil
+}
+
+// frontendBuilt reports whether a frontend build was embedded.
+func frontendBuilt() bool {
+	_, err := fs.Stat(frontend, "frontend/dist/index.html")
+	return err == nil
+}
+
+// viteProxy forwards requests, including Vite's HMR WebSocket, to the
+// dev server so the app is served from one origin just like in production.
+func viteProxy(target *url.URL) http.Handler {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		slog.Warn("Vite dev server unavailable", "url", target.String(), "error", err)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprintf(w, pageTemplate, `<meta http-equiv="refresh" content="3">`, "Vite dev server not running",
+			fmt.Sprintf("Nothing is answering at <code>%s</code>. Start it in another terminal:", html.EscapeString(target.String())),
+			"cd frontend && npm run dev")
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Vite injects inline scripts for hot reload, which --csp would block
+		w.Header().Del("Content-Security-Policy")
+		proxy.ServeHTTP(w, r)
+	})
+}
+
+func placeholderPage() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK) // NoRoute would default to 404
+		fmt.Fprintf(w, pageTemplate, "", "Frontend not built yet",
+			"The API is running, but no frontend was embedded in this binary. Build it and restart, or use dev mode:",
+			"cd frontend && npm run build\ngo run . --dev")
+	})
+}
+
+// pageTemplate takes extra head tags, a title, an HTML message and a shell
+// command.
+const pageTemplate = `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{PROJECT_NAME}}</title>
+%s
+<style>
+  body { font-family: system-ui, sans-serif; background: #1e1b4b; color: #e0e7ff; display: flex; align-items: center; justify-content: center; min-height: 100vh; margin: 0; }
+  main { max-width: 36rem; padding: 2rem; }
+  pre { background: rgba(255,255,255,.1); padding: 1rem; border-radius: .5rem; }
+  a { color: #c4b5fd; }
+</style>
+</head>
+<body>
+<main>
+  <h1>🍒 %s</h1>
+  <p>%s</p>
+  <pre>%s</pre>
+  <p>The API is available at <a href="/api/health">/api/health</a>.</p>
+</main>
+</body>
+</html>
+`
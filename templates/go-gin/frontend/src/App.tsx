@@ -1,14 +1,10 @@
 import { useState, useEffect } from 'react'
 import { useDatabaseStatus, useTodoOperations } from './hooks/useDatabase'
 import { TodoList } from './components/TodoList'
+import { useRealtime, useRealtimeStatus } from './hooks/useRealtime'
+import { api, ApiError, HealthResponse } from './lib/api'
 import './App.css'
 
-interface HealthResponse {
-  status: string
-  message: string
-  stack: string
-}
-
 function App() {
   const [health, setHealth] = useState<HealthResponse | null>(null)
   const [loading, setLoading] = useState(true)
@@ -18,18 +14,16 @@ function App() {
   const dbStatus = useDatabaseStatus()
   const { addTodo } = useTodoOperations()
 
+  // Server push: events published to the "system" topic by the Go hub
+  const realtime = useRealtimeStatus()
+  useRealtime('system', (event) => console.log('📡', event.type, event.data))
+
   useEffect(() => {
     const checkHealth = async () => {
       try {
-        const response = await fetch('/api/health')
-        if (response.ok) {
-          const data = await response.json()
-          setHealth(data)
-        } else {
-          setError('Failed to connect to backend')
-        }
+        setHealth(await api.getHealth())
       } catch (err) {
-        setError('Backend not available')
+        setError(err instanceof ApiError ? 'Failed to connect to backend' : 'Backend not available')
       } finally {
         setLoading(false)
       }
@@ -83,6 +77,12 @@ function App() {
                     Backend Connected
                   </span>
                 </div>
+                <div className="flex items-center space-x-3">
+                  <div className={`w-3 h-3 rounded-full ${realtime.status === 'open' ? 'bg-green-400 animate-pulse' : 'bg-yellow-400'}`}></div>
+                  <span className={`font-medium ${realtime.status === 'open' ? 'text-green-200' : 'text-yellow-200'}`}>
+                    Realtime {realtime.status === 'open' ? `Live (${realtime.transport === 'sse' ? 'SSE' : 'WebSocket'})` : 'Connecting...'}
+                  </span>
+                </div>
                 <div className="bg-white/5 rounded-lg p-4">
                   <p className="text-white/80">
                     <strong>Status:</strong> {health.status}
@@ -93,6 +93,9 @@ function App() {
                   <p className="text-white/80">
                     <strong>Stack:</strong> {health.stack}
                   </p>
+                  <p className="text-white/80">
+                    <strong>Version:</strong> {health.version} • {(health.binarySize / (1024 * 1024)).toFixed(1)} MB
+                  </p>
                 </div>
               </div>
             )}
@@ -161,7 +164,7 @@ function App() {
               </div>
               <div className="flex items-start space-x-3">
                 <span className="text-purple-400 font-bold">2.</span>
-                <p>Add API endpoints in <code className="bg-white/10 px-2 py-1 rounded">main.go</code></p>
+                <p>Add API endpoints in <code className="bg-white/10 px-2 py-1 rounded">routes.go</code></p>
               </div>
               <div className="flex items-start space-x-3">
                 <span className="text-purple-400 font-bold">3.</span>
@@ -0,0 +1,34 @@
+import { useState, useEffect, useRef, useSyncExternalStore } from 'react'
+import { realtime, RealtimeEvent } from '../lib/realtime'
+
+// Call onEvent for every event published to topic (pass null to pause)
+export function useRealtime<T = unknown>(
+  topic: string | null,
+  onEvent: (event: RealtimeEvent & { data?: T }) => void
+) {
+  const handler = useRef(onEvent)
+  handler.current = onEvent
+
+  useEffect(() => {
+    if (!topic) return
+    return realtime.subscribe(topic, (event) => handler.current(event as RealtimeEvent & { data?: T }))
+  }, [topic])
+}
+
+// The most recent event on topic, e.g. for progress notifications
+export function useLatestEvent<T = unknown>(topic: string | null) {
+  const [event, setEvent] = useState<(RealtimeEvent & { data?: T }) | null>(null)
+  useRealtime<T>(topic, setEvent)
+  return event
+}
+
+// Connection status for a live indicator
+export function useRealtimeStatus() {
+  const status = useSyncExternalStore(
+    (onChange) => realtime.onStatusChange(onChange),
+    () => realtime.status
+  )
+  return { status, transport: realtime.transport }
+}
+
+export { realtime }
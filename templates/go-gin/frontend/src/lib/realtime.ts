@@ -0,0 +1,182 @@
+import { api, RealtimeEvent } from './api'
+
+// Realtime client for the Go hub at /api/realtime. One shared connection
+// carries every topic; WebSocket is preferred and Server-Sent Events are
+// used when WebSockets are blocked (e.g. by a corporate proxy).
+
+export type { RealtimeEvent }
+export type RealtimeStatus = 'connecting' | 'open' | 'closed'
+export type RealtimeTransport = 'websocket' | 'sse'
+
+type Listener = (event: RealtimeEvent) => void
+
+// The server sends a heartbeat every 25s; reconnect after missing two
+const HEARTBEAT_TIMEOUT = 60_000
+const MAX_BACKOFF = 30_000
+
+class RealtimeClient {
+  status: RealtimeStatus = 'closed'
+  transport: RealtimeTransport = typeof WebSocket === 'undefined' ? 'sse' : 'websocket'
+
+  private listeners = new Map<string, Set<Listener>>()
+  private statusListeners = new Set<() => void>()
+  private ws?: WebSocket
+  private es?: EventSource
+  private lastId = 0
+  private retries = 0
+  private wsFailures = 0
+  private reconnectTimer?: ReturnType<typeof setTimeout>
+  private watchdog?: ReturnType<typeof setTimeout>
+
+  // Listen to events on topic; returns a function that stops listening
+  subscribe(topic: string, listener: Listener): () => void {
+    let set = this.listeners.get(topic)
+    if (!set) {
+      set = new Set()
+      this.listeners.set(topic, set)
+      this.topicsChanged('subscribe', topic)
+    }
+    set.add(listener)
+
+    return () => {
+      set!.delete(listener)
+      if (set!.size === 0 && this.listeners.get(topic) === set) {
+        this.listeners.delete(topic)
+        this.topicsChanged('unsubscribe', topic)
+      }
+    }
+  }
+
+  // Publish an event to every client subscribed to topic
+  publish(topic: string, type: string, data?: unknown): Promise<RealtimeEvent> {
+    return api.publishEvent({ topic, type, data })
+  }
+
+  onStatusChange(fn: () => void): () => void {
+    this.statusListeners.add(fn)
+    return () => this.statusListeners.delete(fn)
+  }
+
+  private topicsChanged(action: 'subscribe' | 'unsubscribe', topic: string) {
+    if (this.listeners.size === 0) {
+      clearTimeout(this.reconnectTimer)
+      this.reconnectTimer = undefined
+      this.teardown()
+      this.setStatus('closed')
+      return
+    }
+
+    if (this.ws?.readyState === WebSocket.OPEN) {
+      this.ws.send(JSON.stringify({ action, topics: [topic] }))
+      return
+    }
+    if (this.ws || this.reconnectTimer !== undefined) {
+      return // picked up when the connection opens
+    }
+
+    // SSE topics are fixed per stream, so batch changes into one reconnect
+    this.reconnectTimer = setTimeout(() => this.connect(), 0)
+  }
+
+  private connect() {
+    this.reconnectTimer = undefined
+    this.teardown()
+    if (this.listeners.size === 0) return
+
+    this.setStatus('connecting')
+    if (this.transport === 'websocket') {
+      this.connectWebSocket()
+    } else {
+      this.connectEventSource()
+    }
+  }
+
+  private url(path: string): string {
+    const params = new URLSearchParams()
+    for (const topic of this.listeners.keys()) params.append('topic', topic)
+    if (this.lastId) params.set('since', String(this.lastId))
+    return `${path}?${params}`
+  }
+
+  private connectWebSocket() {
+    const scheme = location.protocol === 'https:' ? 'wss:' : 'ws:'
+    const ws = new WebSocket(`${scheme}//${location.host}${this.url('/api/realtime/ws')}`)
+    let opened = false
+
+    ws.onopen = () => {
+      opened = true
+      this.wsFailures = 0
+      // Topics added while connecting
+      ws.send(JSON.stringify({ action: 'subscribe', topics: [...this.listeners.keys()] }))
+      this.opened()
+    }
+    ws.onmessage = (message) => this.receive(JSON.parse(message.data))
+    ws.onclose = () => {
+      if (this.ws !== ws) return
+      if (!opened && ++this.wsFailures >= 2) {
+        console.warn('⚠️ WebSocket unavailable, falling back to Server-Sent Events')
+        this.transport = 'sse'
+      }
+      this.reconnect()
+    }
+    this.ws = ws
+  }
+
+  private connectEventSource() {
+    const es = new EventSource(this.url('/api/realtime/sse'))
+    es.onopen = () => this.opened()
+    es.onmessage = (message) => this.receive(JSON.parse(message.data))
+    es.onerror = () => {
+      // Reconnect ourselves so the URL carries the current topics and position
+      if (this.es === es) this.reconnect()
+    }
+    this.es = es
+  }
+
+  private opened() {
+    this.retries = 0
+    this.setStatus('open')
+    this.resetWatchdog()
+  }
+
+  private receive(event: RealtimeEvent) {
+    this.resetWatchdog()
+    if (event.type === 'heartbeat') return
+
+    if (event.id > this.lastId) this.lastId = event.id
+    this.listeners.get(event.topic)?.forEach((listener) => listener(event))
+  }
+
+  private resetWatchdog() {
+    clearTimeout(this.watchdog)
+    this.watchdog = setTimeout(() => this.reconnect(), HEARTBEAT_TIMEOUT)
+  }
+
+  private reconnect() {
+    this.teardown()
+    this.setStatus('connecting')
+
+    // Exponential backoff with jitter so clients don't reconnect in lockstep
+    const delay = Math.min(1000 * 2 ** this.retries, MAX_BACKOFF) * (0.5 + Math.random() / 2)
+    this.retries++
+    clearTimeout(this.reconnectTimer)
+    this.reconnectTimer = setTimeout(() => this.connect(), delay)
+  }
+
+  private teardown() {
+    clearTimeout(this.watchdog)
+    const { ws, es } = this
+    this.ws = undefined
+    this.es = undefined
+    ws?.close()
+    es?.close()
+  }
+
+  private setStatus(status: RealtimeStatus) {
+    if (this.status === status) return
+    this.status = status
+    this.statusListeners.forEach((fn) => fn())
+  }
+}
+
+export const realtime = new RealtimeClient()
@@ -7,6 +7,11 @@ export default defineConfig({
   server: {
     port: 5173,
     proxy: {
+      // WebSockets keep the original Host so the server's origin check passes
+      '/api/realtime': {
+        target: 'http://localhost:3000',
+        ws: true,
+      },
       '/api': {
         target: 'http://localhost:3000',
         changeOrigin: true,
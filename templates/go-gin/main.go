@@ -1,43 +1,216 @@
 package main
 
 import (
-	"embed"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
 )
 
-//go:embed frontend/dist/*
-var frontend embed.FS
-
 func main() {
-	// Set Gin to release mode for production
-	gin.SetMode(gin.ReleaseMode)
-	
-	r := gin.Default()
-	
-	// Serve static files from embedded frontend
-	r.StaticFS("/", http.FS(frontend))
-	
-	// API routes
-	api := r.Group("/api")
-	{
-		api.GET("/health", func(c *gin.Context) {
-			c.JSON(http.StatusOK, gin.H{
-				"status": "ok",
-				"message": "{{PROJECT_NAME}} is running!",
-				"stack": "{{STACK}}",
-			})
-		})
+	// "--version" prints the build metadata, e.g. for package managers
+	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-version" || os.Args[1] == "version") {
+		fmt.Println("{{PROJECT_NAME}}", buildVersion())
+		return
+	}
+
+	// update, rollback, update-keygen and update-sign manage self-updates
+	if handled, err := updateCommand(os.Args[1:]); handled {
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// "gen-client" writes the TypeScript API client instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "gen-client" {
+		gin.SetMode(gin.ReleaseMode)
+		cfg := defaultConfig()
+		cfg.Metrics = true // describe optional routes too
+		backups := NewBackups(cfg)
+		docs, _ := OpenDocStore("", "{{PROJECT_SLUG}}")
+		_, api := newRouter(cfg, slog.Default(), NewHub(), backups, NewScheduler(""), NewBlobs(cfg, backups), docs)
+		if err := genClient(api, os.Args[2:]); err != nil {
+			log.Fatal("Failed to generate client: ", err)
+		}
+		return
+	}
+
+	// Load configuration from flags, environment and config file
+	cfg, err := LoadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+
+	logger := newLogger(cfg, os.Stderr)
+
+	// In dev mode this process only watches the sources and restarts a
+	// child server built from them
+	if cfg.Dev && os.Getenv(devChildEnv) == "" {
+		if err := runDev(cfg); err != nil {
+			logger.Error("dev mode failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.LogFormat == "json" {
+		logger.Info("configuration", "config", cfg.Public())
+	} else {
+		cfg.Print(os.Stderr)
+	}
+
+	// Gin runs in release mode unless debug logging is requested
+	if cfg.LogLevel == "debug" {
+		gin.SetMode(gin.DebugMode)
+	} else {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	hub := NewHub()
+	hub.AllowOrigins(cfg.CORSOrigins())
+	backups := NewBackups(cfg)
+	blobs := NewBlobs(cfg, backups)
+
+	// Documents shared with the desktop template; see docstore.go
+	docs, err := OpenDocStore(filepath.Join(cfg.DataDir, "docs.json"), "{{PROJECT_SLUG}}")
+	if err != nil {
+		log.Fatal("Failed to open documents: ", err)
+	}
+	docs.Guard(backups.Modify)
+	backups.OnRestore(func() {
+		if err := docs.Reload(); err != nil {
+			logger.Error("reloading documents failed", "error", err)
+		}
+	})
+
+	// Periodic work runs as scheduler jobs, e.g.
+	// jobs.Add(Job{Name: "cleanup", Schedule: "@daily", Jitter: time.Minute, Run: cleanup})
+	jobs := NewScheduler(filepath.Join(cfg.DataDir, "jobs.json"))
+	jobs.Guard(backups.Modify)
+	if cfg.BackupInterval > 0 {
+		err := jobs.Add(Job{Name: "backup", Schedule: "@every " + cfg.BackupInterval.String(), Run: func(ctx context.Context) error {
+			_, err := backups.Save("")
+			return err
+		}})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := jobs.Add(Job{Name: "blob-uploads", Schedule: "@hourly", Run: blobs.CleanupUploads}); err != nil {
+		log.Fatal(err)
 	}
-	
+
+	r, _ := newRouter(cfg, logger, hub, backups, jobs, blobs, docs)
+	srv := NewServer(cfg, Security(cfg, r))
+	srv.BeforeDrain(hub.Close)
+	srv.OnShutdown(jobs.Shutdown)
+	jobs.Start()
+
+	// Close databases and other resources once requests have drained, e.g.
+	// srv.OnShutdown(func(ctx context.Context) error { return db.Close() })
+
+	scheme := "http"
+	if cfg.TLSEnabled() {
+		scheme = "https"
+	}
+
+	if !cfg.Dev && !frontendBuilt() {
+		logger.Warn("frontend not built, serving a placeholder page; run `npm run build` in frontend/ or start with --dev")
+	}
+
 	// Start server
-	log.Printf("🚀 {{PROJECT_NAME}} starting on port {{PORT}}")
-	log.Printf("📊 Stack: {{STACK}}")
-	log.Printf("🌐 Open http://localhost:{{PORT}}")
-	
-	if err := r.Run(":{{PORT}}"); err != nil {
-		log.Fatal("Failed to start server:", err)
+	logger.Info("starting {{PROJECT_NAME}}",
+		"version", buildVersion().Version,
+		"addr", cfg.Addr(),
+		"stack", "{{STACK}}",
+		"url", fmt.Sprintf("%s://localhost:%d", scheme, cfg.Port),
+	)
+
+	// Check for signed updates in the background; once one is installed
+	// the server drains and restarts into the new binary
+	var updated atomic.Bool
+	if cfg.UpdateURL != "" && !cfg.NoUpdate && !cfg.Dev {
+		updater, err := NewUpdater(cfg.UpdateURL)
+		if err != nil {
+			logger.Warn("self-update disabled", "error", err)
+		} else {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go updater.Run(ctx, cfg.UpdateInterval, func(version string) {
+				logger.Info("update installed, restarting", "version", version)
+				updated.Store(true)
+				srv.Stop()
+			})
+		}
+	}
+
+	if err := srv.Run(); err != nil {
+		logger.Error("server error", "error", err)
+		os.Exit(1)
+	}
+	if updated.Load() {
+		if err := restart(); err != nil {
+			logger.Error("restart failed, start the server again to run the update", "error", err)
+			os.Exit(1)
+		}
 	}
+	logger.Info("{{PROJECT_NAME}} stopped")
+}
+
+// newRouter builds the gin engine: middleware, the /api routes, the
+// realtime endpoints of hub, file uploads, the document store, the backup
+// and job admin endpoints and the frontend. It also returns the API
+// registry, from which the OpenAPI document and the generated client are
+// made.
+func newRouter(cfg *Config, logger *slog.Logger, hub *Hub, backups *Backups, jobs *Scheduler, blobs *Blobs, docs *DocStore) (*gin.Engine, *API) {
+	r := gin.New()
+	r.Use(RecoverJSON(), RequestID(), AccessLog(logger))
+
+	// Prometheus metrics are opt-in (--metrics)
+	var metrics *Metrics
+	if cfg.Metrics {
+		metrics = NewMetrics()
+		r.Use(metrics.Middleware())
+	}
+
+	// API routes
+	api := NewAPI(r.Group("/api"))
+	registerRoutes(api, cfg, hub)
+	hub.Register(api)
+	blobs.Register(api)
+	registerDocs(api, docs, hub, cfg.SyncToken)
+
+	// Admin routes: bearer token, or localhost only without one
+	admin := api.Group("/admin", AdminAuth(cfg.AdminToken))
+	backups.Register(admin)
+	jobs.Register(admin)
+
+	if metrics != nil {
+		api.Raw(http.MethodGet, "/metrics", "getMetrics", "Prometheus metrics", "text/plain", metrics.Handler())
+	}
+	api.Raw(http.MethodGet, "/openapi.json", "getOpenAPI", "OpenAPI document for this API", "application/json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, api.OpenAPI())
+	})
+
+	// Serve the frontend for everything else. Registered as NoRoute so the
+	// catch-all doesn't conflict with the /api routes.
+	web, err := frontendHandler(cfg)
+	if err != nil {
+		log.Fatal("Failed to load frontend: ", err)
+	}
+	r.NoRoute(gin.WrapH(web))
+
+	return r, api
 }
@@ -0,0 +1,673 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testApp is the server wired like in main, with its data in a temporary
+// directory.
+type testApp struct {
+	cfg     *Config
+	handler http.Handler
+	jobs    *Scheduler
+	blobs   *Blobs
+}
+
+func newTestApp(t *testing.T, configure func(cfg *Config)) *testApp {
+	t.Helper()
+	cfg := defaultConfig()
+	cfg.DataDir = filepath.Join(t.TempDir(), "data")
+	cfg.BackupDir = filepath.Join(t.TempDir(), "backups")
+	if configure != nil {
+		configure(cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	backups := NewBackups(cfg)
+	jobs := NewScheduler(filepath.Join(cfg.DataDir, "jobs.json"))
+	jobs.Guard(backups.Modify)
+	docs, err := OpenDocStore(filepath.Join(cfg.DataDir, "docs.json"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs.Guard(backups.Modify)
+	blobs := NewBlobs(cfg, backups)
+	r, _ := newRouter(cfg, logger, NewHub(), backups, jobs, blobs, docs)
+	t.Cleanup(func() { jobs.Shutdown(context.Background()) })
+	return &testApp{cfg: cfg, handler: Security(cfg, r), jobs: jobs, blobs: blobs}
+}
+
+// do sends a request from localhost and returns the recorded response.
+// Headers are given as name, value pairs.
+func (a *testApp) do(method, path string, body io.Reader, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	req.RemoteAddr = "127.0.0.1:40000"
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	a.handler.ServeHTTP(w, req)
+	return w
+}
+
+// decode unmarshals a JSON response, failing the test on a non-200 status.
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("invalid JSON %q: %v", w.Body, err)
+	}
+	return v
+}
+
+func TestHealth(t *testing.T) {
+	app := newTestApp(t, nil)
+
+	health := decode[HealthResponse](t, app.do("GET", "/api/health", nil))
+	if health.Status != "ok" || health.Version == "" {
+		t.Errorf("health = %+v", health)
+	}
+	if _, ok := health.Config["port"]; !ok {
+		t.Errorf("health config is missing port: %v", health.Config)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	app := newTestApp(t, nil)
+
+	version := decode[VersionInfo](t, app.do("GET", "/api/version", nil))
+	if version != buildVersion() {
+		t.Errorf("version = %+v, want %+v", version, buildVersion())
+	}
+}
+
+func TestUpdateChecks(t *testing.T) {
+	info := VersionInfo{Version: "1.2.0", Commit: "abc1234", BuildTime: "2026-05-01T12:00:00Z", Target: "linux/amd64", GoVersion: "go1.22"}
+	out := fmt.Sprintln("{{PROJECT_NAME}}", info)
+	// Not "2026" from the build date, which a release "2026" would match
+	if got := reportedVersion([]byte(out)); got != "1.2.0" {
+		t.Errorf("reportedVersion(%q) = %q", out, got)
+	}
+	if got := reportedVersion([]byte("other-app 1.2.0, built today")); got != "" {
+		t.Errorf("reportedVersion of another app = %q", got)
+	}
+
+	// A signature is only good for the app and version it was made for
+	sum := []byte{1, 2, 3}
+	a := updateMessage("{{PROJECT_SLUG}}", "1.2.0", sum)
+	for _, b := range [][]byte{updateMessage("{{PROJECT_SLUG}}", "1.3.0", sum), updateMessage("other-app", "1.2.0", sum)} {
+		if bytes.Equal(a, b) {
+			t.Errorf("update message %q is the same for another release", a)
+		}
+	}
+}
+
+func TestFrontend(t *testing.T) {
+	app := newTestApp(t, nil)
+
+	// The built app, or the placeholder page before `npm run build`
+	w := app.do("GET", "/", nil)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("GET / = %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !frontendBuilt() && !strings.Contains(w.Body.String(), "Frontend not built yet") {
+		t.Errorf("placeholder page missing: %s", w.Body)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	app := newTestApp(t, nil)
+
+	doc := decode[struct {
+		Paths map[string]any `json:"paths"`
+	}](t, app.do("GET", "/api/openapi.json", nil))
+	for _, path := range []string{"/api/health", "/api/realtime/publish", "/api/blobs", "/api/admin/jobs"} {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("OpenAPI document is missing %s", path)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	app := newTestApp(t, func(cfg *Config) { cfg.Metrics = true })
+
+	app.do("GET", "/api/health", nil)
+	w := app.do("GET", "/api/metrics", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "http_requests_total") {
+		t.Fatalf("GET /api/metrics = %d: %.200s", w.Code, w.Body)
+	}
+}
+
+func TestRealtimePublish(t *testing.T) {
+	app := newTestApp(t, nil)
+
+	ev := decode[RealtimeEvent](t, app.do("POST", "/api/realtime/publish", strings.NewReader(`{"topic":"tasks","type":"created","data":{"id":1}}`)))
+	if ev.Topic != "tasks" || ev.Type != "created" || ev.ID == 0 {
+		t.Errorf("event = %+v", ev)
+	}
+	if w := app.do("POST", "/api/realtime/publish", strings.NewReader(`{"topic":"tasks"}`)); w.Code != http.StatusBadRequest {
+		t.Errorf("publish without type = %d, want 400", w.Code)
+	}
+	for _, topic := range []string{"docs", "server.jobs", "sys.update"} {
+		if w := app.do("POST", "/api/realtime/publish", strings.NewReader(`{"topic":"`+topic+`","type":"change"}`)); w.Code != http.StatusForbidden {
+			t.Errorf("publish to %s = %d, want 403", topic, w.Code)
+		}
+	}
+
+	stats := decode[RealtimeStats](t, app.do("GET", "/api/realtime/stats", nil))
+	if stats.Published != 1 {
+		t.Errorf("stats = %+v, want 1 published", stats)
+	}
+}
+
+func TestRealtimeReplay(t *testing.T) {
+	app := newTestApp(t, nil)
+	for i := 0; i < 2*subscriberBuffer; i++ {
+		decode[RealtimeEvent](t, app.do("POST", "/api/realtime/publish", strings.NewReader(`{"topic":"tasks","type":"created"}`)))
+	}
+	srv := httptest.NewServer(app.handler)
+	t.Cleanup(srv.Close)
+
+	// A client that reconnects further behind than its queue gets every
+	// event it missed, instead of being dropped as a slow consumer
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/api/realtime/sse?topic=tasks&since=1", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var ids []string
+	for lines := bufio.NewScanner(resp.Body); lines.Scan(); {
+		if id, ok := strings.CutPrefix(lines.Text(), "id: "); ok {
+			if ids = append(ids, id); id == fmt.Sprint(2*subscriberBuffer) {
+				break
+			}
+		}
+	}
+	if len(ids) != 2*subscriberBuffer-1 || ids[0] != "2" {
+		t.Errorf("replayed %d events, want %d from id 2", len(ids), 2*subscriberBuffer-1)
+	}
+	if stats := decode[RealtimeStats](t, app.do("GET", "/api/realtime/stats", nil)); stats.Dropped != 0 {
+		t.Errorf("stats = %+v, want none dropped", stats)
+	}
+}
+
+func TestSecurity(t *testing.T) {
+	app := newTestApp(t, func(cfg *Config) {
+		cfg.Security = true
+		cfg.MaxBodyMB = 1
+	})
+
+	w := app.do("GET", "/api/health", nil)
+	if w.Header().Get("X-Frame-Options") != "DENY" || w.Header().Get("Content-Security-Policy") == "" {
+		t.Errorf("security headers missing: %v", w.Header())
+	}
+
+	big := strings.NewReader(`{"topic":"t","type":"x","data":"` + strings.Repeat("a", 2<<20) + `"}`)
+	if w := app.do("POST", "/api/realtime/publish", big); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body = %d, want 413", w.Code)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	app := newTestApp(t, func(cfg *Config) { cfg.CORS = "https://example.com" })
+
+	w := app.do("GET", "/api/health", nil, "Origin", "https://example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://example.com" {
+		t.Errorf("allowed origin = %q", got)
+	}
+	w = app.do("GET", "/api/health", nil, "Origin", "https://evil.example")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("disallowed origin got Access-Control-Allow-Origin %q", got)
+	}
+}
+
+func TestAdminAuth(t *testing.T) {
+	app := newTestApp(t, nil)
+
+	req := httptest.NewRequest("GET", "/api/admin/jobs", nil)
+	req.RemoteAddr = "192.0.2.1:40000"
+	w := httptest.NewRecorder()
+	app.handler.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("remote admin request without token = %d, want 403", w.Code)
+	}
+	if w := app.do("GET", "/api/admin/jobs", nil); w.Code != http.StatusOK {
+		t.Errorf("local admin request = %d, want 200", w.Code)
+	}
+
+	// Pages of other sites can send requests from this machine too
+	for _, header := range [][]string{
+		{"Origin", "https://evil.example"},
+		{"Origin", "null"},
+		{"Sec-Fetch-Site", "cross-site"},
+	} {
+		if w := app.do("POST", "/api/admin/jobs/backup/run", nil, header...); w.Code != http.StatusForbidden {
+			t.Errorf("admin request with %s %q = %d, want 403", header[0], header[1], w.Code)
+		}
+	}
+	if w := app.do("GET", "/api/admin/jobs", nil, "Origin", "http://localhost:5173"); w.Code != http.StatusOK {
+		t.Errorf("admin request from the dev server = %d, want 200", w.Code)
+	}
+
+	app = newTestApp(t, func(cfg *Config) { cfg.AdminToken = "s3cret" })
+	if w := app.do("GET", "/api/admin/jobs", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("admin request without token = %d, want 401", w.Code)
+	}
+	if w := app.do("GET", "/api/admin/jobs", nil, "Authorization", "Bearer wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("admin request with wrong token = %d, want 401", w.Code)
+	}
+	if w := app.do("GET", "/api/admin/jobs", nil, "Authorization", "Bearer s3cret"); w.Code != http.StatusOK {
+		t.Errorf("admin request with token = %d, want 200", w.Code)
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	app := newTestApp(t, nil)
+	if backups := decode[[]BackupInfo](t, app.do("GET", "/api/admin/backups", nil)); len(backups) != 0 {
+		t.Errorf("got %d stored backups before the first one", len(backups))
+	}
+	if _, err := os.Stat(app.cfg.BackupDir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("backup-dir was made before it was needed: %v", err)
+	}
+	notes := filepath.Join(app.cfg.DataDir, "notes.txt")
+	if err := os.WriteFile(notes, []byte("before"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := app.do("GET", "/api/admin/backup", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("backup = %d: %s", w.Code, w.Body)
+	}
+	archive := w.Body.Bytes()
+
+	if err := os.WriteFile(notes, []byte("after"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	decode[RestoreResult](t, app.do("POST", "/api/admin/restore", bytes.NewReader(archive), "Content-Type", "application/gzip"))
+
+	if data, _ := os.ReadFile(notes); string(data) != "before" {
+		t.Errorf("notes.txt after restore = %q, want %q", data, "before")
+	}
+	if backups := decode[[]BackupInfo](t, app.do("GET", "/api/admin/backups", nil)); len(backups) != 1 {
+		t.Errorf("got %d stored backups, want the pre-restore snapshot", len(backups))
+	}
+
+	if w := app.do("POST", "/api/admin/restore", strings.NewReader("not a backup"), "Content-Type", "application/gzip"); w.Code != http.StatusBadRequest {
+		t.Errorf("restoring garbage = %d, want 400", w.Code)
+	}
+	// Forms can only post text or form data, so a page can't restore one
+	if w := app.do("POST", "/api/admin/restore", bytes.NewReader(archive), "Content-Type", "text/plain"); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("restoring a text/plain body = %d, want 415", w.Code)
+	}
+
+	// An archive that unpacks to more than restore-max-mb is rejected,
+	// however well it compresses
+	app = newTestApp(t, func(cfg *Config) { cfg.RestoreMaxMB = 1 })
+	if err := os.WriteFile(filepath.Join(app.cfg.DataDir, "zeros.bin"), make([]byte, 2<<20), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w = app.do("GET", "/api/admin/backup", nil)
+	if w := app.do("POST", "/api/admin/restore", w.Body, "Content-Type", "application/gzip"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("restoring 2 MB with restore-max-mb 1 = %d, want 413", w.Code)
+	}
+	if fi, err := os.Stat(filepath.Join(app.cfg.DataDir, "zeros.bin")); err != nil || fi.Size() != 2<<20 {
+		t.Errorf("data after a rejected restore: %v, %v", fi, err)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	app := newTestApp(t, nil)
+	ran := make(chan struct{}, 1)
+	err := app.jobs.Add(Job{Name: "test", Schedule: "@yearly", Run: func(ctx context.Context) error {
+		ran <- struct{}{}
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.jobs.Start()
+
+	if w := app.do("POST", "/api/admin/jobs/test/run", nil); w.Code != http.StatusOK {
+		t.Fatalf("run job = %d: %s", w.Code, w.Body)
+	}
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not run")
+	}
+	if w := app.do("POST", "/api/admin/jobs/missing/run", nil); w.Code != http.StatusNotFound {
+		t.Errorf("run unknown job = %d, want 404", w.Code)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		jobs := decode[[]JobStatus](t, app.do("GET", "/api/admin/jobs", nil))
+		_, err := os.Stat(filepath.Join(app.cfg.DataDir, "jobs.json"))
+		if len(jobs) == 1 && jobs[0].Runs == 1 && err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("jobs = %+v, state file: %v; want one job with one run, saved", jobs, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC) // a Wednesday
+	tests := map[string]time.Time{
+		"0 * * * *":     time.Date(2025, 1, 1, 11, 0, 0, 0, time.UTC),
+		"*/15 * * * *":  time.Date(2025, 1, 1, 10, 45, 0, 0, time.UTC),
+		"0 9 * * mon":   time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC),
+		"@daily":        time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		"@every 90m":    from.Add(90 * time.Minute),
+		"30 10 1 2 *":   time.Date(2025, 2, 1, 10, 30, 0, 0, time.UTC),
+		"0 0 29 2 *":    time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		"0 12 * * 7":    time.Date(2025, 1, 5, 12, 0, 0, 0, time.UTC),
+		"0 0 1-7 * fri": time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		"0 0 */2 * 1":   time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC), // an odd day that is a Monday
+	}
+	for spec, want := range tests {
+		sched, err := ParseSchedule(spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", spec, err)
+			continue
+		}
+		if got := sched.Next(from); !got.Equal(want) {
+			t.Errorf("ParseSchedule(%q).Next = %v, want %v", spec, got, want)
+		}
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "0 0 31 2 *", "@every 1ms", "@sometimes"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+// multipartBody builds a form with one file field per name/content pair.
+func multipartBody(t *testing.T, files ...string) (io.Reader, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for i := 0; i+1 < len(files); i += 2 {
+		part, err := mw.CreateFormFile("file", files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(files[i+1]))
+	}
+	mw.Close()
+	return &buf, mw.FormDataContentType()
+}
+
+func TestBlobs(t *testing.T) {
+	app := newTestApp(t, nil)
+	content := "hello, cherries\n"
+
+	body, contentType := multipartBody(t, "a.txt", content, "b.txt", content)
+	stored := decode[[]BlobInfo](t, app.do("POST", "/api/blobs", body, "Content-Type", contentType))
+	if len(stored) != 2 || stored[0].SHA256 != stored[1].SHA256 || !strings.HasPrefix(stored[0].Type, "text/plain") {
+		t.Fatalf("stored = %+v", stored)
+	}
+	objects, _ := filepath.Glob(filepath.Join(app.cfg.DataDir, "blobs", "objects", "*", "*"))
+	if len(objects) != 1 {
+		t.Errorf("identical files stored as %d objects, want 1", len(objects))
+	}
+
+	w := app.do("GET", "/api/blobs/"+stored[0].ID+"/content", nil, "Range", "bytes=7-14")
+	if w.Code != http.StatusPartialContent || w.Body.String() != "cherries" {
+		t.Errorf("range download = %d %q", w.Code, w.Body)
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment") {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	body, contentType = multipartBody(t, "page.html", "<html><script>alert(1)</script></html>")
+	if w := app.do("POST", "/api/blobs", body, "Content-Type", contentType); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("HTML upload = %d, want 415", w.Code)
+	}
+
+	decode[Empty](t, app.do("DELETE", "/api/blobs/"+stored[0].ID, nil))
+	if w := app.do("GET", "/api/blobs/"+stored[1].ID+"/content", nil); w.Body.String() != content {
+		t.Errorf("deleting one copy removed the shared contents: %d %q", w.Code, w.Body)
+	}
+	if blobs := decode[[]BlobInfo](t, app.do("GET", "/api/blobs", nil)); len(blobs) != 1 {
+		t.Errorf("got %d blobs after delete, want 1", len(blobs))
+	}
+}
+
+func TestBlobResumableUpload(t *testing.T) {
+	app := newTestApp(t, func(cfg *Config) { cfg.BlobMaxMB = 1 })
+	content := "%PDF-1.4\n" + strings.Repeat("x", 1000)
+
+	upload := decode[UploadStatus](t, app.do("POST", "/api/blobs/uploads", strings.NewReader(`{"name":"doc.pdf","size":1009}`)))
+	chunks := "/api/blobs/uploads/" + upload.ID + "/chunks/"
+
+	status := decode[UploadStatus](t, app.do("PUT", chunks+"0", strings.NewReader(content[:500])))
+	if status.Offset != 500 || status.Blob != nil {
+		t.Fatalf("after first chunk = %+v", status)
+	}
+	if w := app.do("PUT", chunks+"0", strings.NewReader(content[:500])); w.Code != http.StatusConflict {
+		t.Errorf("chunk at the wrong offset = %d, want 409", w.Code)
+	}
+	if status := decode[UploadStatus](t, app.do("GET", "/api/blobs/uploads/"+upload.ID, nil)); status.Offset != 500 {
+		t.Errorf("resume offset = %d, want 500", status.Offset)
+	}
+
+	status = decode[UploadStatus](t, app.do("PUT", chunks+"500", strings.NewReader(content[500:])))
+	if status.Blob == nil || status.Blob.Type != "application/pdf" || status.Blob.Size != int64(len(content)) {
+		t.Fatalf("after last chunk = %+v", status)
+	}
+	if w := app.do("GET", "/api/blobs/"+status.Blob.ID+"/content", nil); w.Body.String() != content {
+		t.Errorf("downloaded content differs")
+	}
+
+	if w := app.do("POST", "/api/blobs/uploads", strings.NewReader(`{"name":"big.bin","size":2000000}`)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("upload over blob-max-mb = %d, want 413", w.Code)
+	}
+
+	// Chunks for uploads that don't exist, or no longer do, leave no locks
+	for _, id := range []string{"nope", strings.Repeat("ab", 16), upload.ID} {
+		if w := app.do("PUT", "/api/blobs/uploads/"+id+"/chunks/0", strings.NewReader("x")); w.Code != http.StatusNotFound {
+			t.Errorf("chunk for upload %s = %d, want 404", id, w.Code)
+		}
+	}
+	cancelled := decode[UploadStatus](t, app.do("POST", "/api/blobs/uploads", strings.NewReader(`{"name":"a.txt","size":10}`)))
+	decode[UploadStatus](t, app.do("PUT", "/api/blobs/uploads/"+cancelled.ID+"/chunks/0", strings.NewReader("12345")))
+	decode[Empty](t, app.do("DELETE", "/api/blobs/uploads/"+cancelled.ID, nil))
+	app.blobs.uploadMu.Store(strings.Repeat("cd", 16), &sync.Mutex{})
+	app.blobs.CleanupUploads(context.Background())
+	app.blobs.uploadMu.Range(func(id, _ any) bool {
+		t.Errorf("upload %s still has a lock", id)
+		return true
+	})
+
+	// An upload is stale once its data stops growing; its .json is only
+	// written when it starts
+	active := decode[UploadStatus](t, app.do("POST", "/api/blobs/uploads", strings.NewReader(`{"name":"a.txt","size":10}`)))
+	stale := decode[UploadStatus](t, app.do("POST", "/api/blobs/uploads", strings.NewReader(`{"name":"b.txt","size":10}`)))
+	old := time.Now().Add(-2 * uploadTTL)
+	uploads := filepath.Join(app.blobs.dir, "uploads")
+	for _, name := range []string{active.ID + ".json", stale.ID + ".json", stale.ID} {
+		if err := os.Chtimes(filepath.Join(uploads, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app.blobs.CleanupUploads(context.Background())
+	if w := app.do("GET", "/api/blobs/uploads/"+active.ID, nil); w.Code != http.StatusOK {
+		t.Errorf("upload with recent data after cleanup = %d, want 200", w.Code)
+	}
+	if w := app.do("GET", "/api/blobs/uploads/"+stale.ID, nil); w.Code != http.StatusNotFound {
+		t.Errorf("stale upload after cleanup = %d, want 404", w.Code)
+	}
+}
+
+func TestDocs(t *testing.T) {
+	app := newTestApp(t, nil)
+
+	created := decode[PutResult](t, app.do("POST", "/api/docs", strings.NewReader(`{"type":"todo","text":"first","done":false}`)))
+	if created.ID == "" {
+		t.Fatal("no ID generated")
+	}
+	decode[PutResult](t, app.do("PUT", "/api/docs/note-1", strings.NewReader(`{"type":"note","text":"hi"}`)))
+	since := decode[ChangesResponse](t, app.do("GET", "/api/docs/changes", nil)).Seq
+	decode[PutResult](t, app.do("PUT", "/api/docs/"+created.ID, strings.NewReader(`{"type":"todo","text":"first","done":true}`)))
+
+	doc := decode[Doc](t, app.do("GET", "/api/docs/"+created.ID, nil))
+	if doc["done"] != true || doc["_id"] != created.ID {
+		t.Errorf("doc = %v", doc)
+	}
+	if todos := decode[[]Doc](t, app.do("GET", "/api/docs?field=type&key=todo", nil)); len(todos) != 1 {
+		t.Errorf("todos = %v", todos)
+	}
+	if done := decode[[]Doc](t, app.do("GET", "/api/docs?field=done&key=true", nil)); len(done) != 1 {
+		t.Errorf("done = %v", done)
+	}
+	changes := decode[ChangesResponse](t, app.do("GET", fmt.Sprintf("/api/docs/changes?since=%d", since), nil))
+	if len(changes.Changes) != 1 || changes.Changes[0].ID != created.ID {
+		t.Errorf("changes since %d = %+v", since, changes)
+	}
+
+	if w := app.do("POST", "/api/docs", strings.NewReader(`{"_id":7}`)); w.Code != http.StatusBadRequest {
+		t.Errorf("numeric _id = %d, want 400", w.Code)
+	}
+	decode[Empty](t, app.do("DELETE", "/api/docs/note-1", nil))
+	if w := app.do("GET", "/api/docs/note-1", nil); w.Code != http.StatusNotFound {
+		t.Errorf("deleted doc = %d, want 404", w.Code)
+	}
+
+	// An export imports into another server
+	export := app.do("GET", "/api/docs/export", nil)
+	other := newTestApp(t, nil)
+	if res := decode[ImportResult](t, other.do("POST", "/api/docs/import", export.Body)); res.Imported != 1 {
+		t.Errorf("imported %d, want 1", res.Imported)
+	}
+	if all := decode[[]Doc](t, other.do("GET", "/api/docs", nil)); len(all) != 1 || all[0]["text"] != "first" {
+		t.Errorf("imported docs = %v", all)
+	}
+}
+
+func TestDocsReplication(t *testing.T) {
+	app := newTestApp(t, nil)
+	hub := httptest.NewServer(app.handler)
+	defer hub.Close()
+
+	dir := t.TempDir()
+	replica, _ := OpenDocStore(filepath.Join(dir, "docs.json"), "desktop")
+	r, err := NewReplicator(replica, hub.URL, filepath.Join(dir, "docs.sync.json"), MergeFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica.Put(Doc{"_id": "1", "type": "todo", "text": "from the desktop"})
+	decode[PutResult](t, app.do("PUT", "/api/docs/2", strings.NewReader(`{"type":"todo","text":"from the web"}`)))
+	if err := r.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if doc, err := replica.Get("2"); err != nil || doc["text"] != "from the web" {
+		t.Errorf("pulled %v, %v", doc, err)
+	}
+	if doc := decode[Doc](t, app.do("GET", "/api/docs/1", nil, "Authorization", "Bearer s3cret")); doc["text"] != "from the desktop" {
+		t.Errorf("pushed %v", doc)
+	}
+
+	// A push based on an outdated revision comes back rejected
+	stale := PushRequest{Changes: []Change{{ID: "2", Doc: Doc{"text": "stale"}, Rev: newDocID(), BaseRev: "old"}}}
+	body, _ := json.Marshal(stale)
+	if res := decode[PushResult](t, app.do("POST", "/api/docs/push", bytes.NewReader(body))); len(res.Rejected) != 1 || res.Rejected[0].Doc["text"] != "from the web" {
+		t.Errorf("push result = %+v", res)
+	}
+}
+
+func TestDocsSyncAuth(t *testing.T) {
+	app := newTestApp(t, nil)
+	for _, path := range []string{"/api/docs", "/api/docs/changes", "/api/docs/export", "/api/docs/1", "/api/realtime/sse?topic=docs"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.0.2.1:40000"
+		w := httptest.NewRecorder()
+		app.handler.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("remote %s without sync token = %d, want 403", path, w.Code)
+		}
+	}
+
+	if w := app.do("POST", "/api/docs/push", strings.NewReader(`{"changes":[]}`), "Origin", "https://evil.example"); w.Code != http.StatusForbidden {
+		t.Errorf("cross-site push without sync token = %d, want 403", w.Code)
+	}
+
+	app = newTestApp(t, func(cfg *Config) { cfg.SyncToken = "s3cret" })
+	if w := app.do("POST", "/api/docs/push", strings.NewReader(`{"changes":[]}`)); w.Code != http.StatusUnauthorized {
+		t.Errorf("push without token = %d, want 401", w.Code)
+	}
+	// The plain routes and the realtime topic give away the same documents
+	for _, req := range []struct{ method, path, body string }{
+		{"GET", "/api/docs", ""},
+		{"PUT", "/api/docs/1", `{"text": "x"}`},
+		{"DELETE", "/api/docs/1", ""},
+		{"GET", "/api/realtime/sse?topic=docs", ""},
+	} {
+		if w := app.do(req.method, req.path, strings.NewReader(req.body)); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without token = %d, want 401", req.method, req.path, w.Code)
+		}
+	}
+	if w := app.do("GET", "/api/docs", nil, "Authorization", "Bearer s3cret"); w.Code != http.StatusOK {
+		t.Errorf("listing documents with token = %d, want 200", w.Code)
+	}
+
+	hub := httptest.NewServer(app.handler)
+	defer hub.Close()
+	dir := t.TempDir()
+	replica, _ := OpenDocStore(filepath.Join(dir, "docs.json"), "desktop")
+	r, err := NewReplicator(replica, hub.URL, filepath.Join(dir, "docs.sync.json"), MergeFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica.Put(Doc{"_id": "1", "type": "todo", "text": "from the desktop"})
+	if err := r.Sync(context.Background()); err == nil || !strings.Contains(err.Error(), "sync token") {
+		t.Errorf("sync without token: %v", err)
+	}
+	r.SetToken("s3cret")
+	if err := r.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if doc := decode[Doc](t, app.do("GET", "/api/docs/1", nil, "Authorization", "Bearer s3cret")); doc["text"] != "from the desktop" {
+		t.Errorf("pushed %v", doc)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDHeader carries the request ID in both directions, so IDs set by a
+// reverse proxy are kept and clients can quote them in bug reports.
+const requestIDHeader = "X-Request-ID"
+
+// requestIDKey is the gin context key holding the request ID.
+const requestIDKey = "requestID"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// newLogger creates the application logger and installs it as the default
+// for both slog and the standard log package.
+func newLogger(cfg *Config, w io.Writer) *slog.Logger {
+	var level slog.Level
+	level.UnmarshalText([]byte(cfg.LogLevel))
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if cfg.LogFormat == "json" {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// RequestID assigns every request an ID, taken from the incoming
+// X-Request-ID header when it looks sane, and echoes it in the response.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestLogger returns a logger tagged with the request ID of c, for use
+// inside handlers.
+func RequestLogger(c *gin.Context) *slog.Logger {
+	return slog.Default().With("request_id", c.GetString(requestIDKey))
+}
+
+// AccessLog logs one structured line per request.
+func AccessLog(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		logger.LogAttrs(c.Request.Context(), level, "request",
+			slog.String("request_id", c.GetString(requestIDKey)),
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", routeLabel(c)),
+			slog.Int("status", status),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
+
+// routeLabel returns the matched route pattern rather than the raw path, so
+// metrics don't grow a series per ID or file name.
+func routeLabel(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return "unmatched"
+}
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency
+// histogram.
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	method, route string
+	status        int
+}
+
+type routeKey struct {
+	method, route string
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, non-cumulative; last entry is +Inf
+	sum    float64
+	count  uint64
+}
+
+// Metrics collects request counts and latencies and renders them, together
+// with Go runtime statistics, in the Prometheus text format.
+type Metrics struct {
+	started  time.Time
+	inFlight atomic.Int64
+
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	latencies map[routeKey]*histogram
+}
+
+// NewMetrics creates an empty metrics registry.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		started:   time.Now(),
+		requests:  map[requestKey]uint64{},
+		latencies: map[routeKey]*histogram{},
+	}
+}
+
+// Middleware records every request handled by the engine.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		m.inFlight.Add(1)
+		defer m.inFlight.Add(-1)
+
+		c.Next()
+
+		m.observe(c.Request.Method, routeLabel(c), c.Writer.Status(), time.Since(start))
+	}
+}
+
+func (m *Metrics) observe(method, route string, status int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{method, route, status}]++
+
+	h := m.latencies[routeKey{method, route}]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets)+1)}
+		m.latencies[routeKey{method, route}] = h
+	}
+	seconds := d.Seconds()
+	h.counts[sort.SearchFloat64s(latencyBuckets, seconds)]++
+	h.sum += seconds
+	h.count++
+}
+
+// Handler serves the metrics in the Prometheus text exposition format.
+func (m *Metrics) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Status(200)
+		m.WriteTo(c.Writer)
+	}
+}
+
+// WriteTo writes all metrics to w.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	m.mu.Lock()
+	requests := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		requests = append(requests, k)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		a, b := requests[i], requests[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+	b.WriteString("# HELP http_requests_total Total number of HTTP requests.\n")
+	b.WriteString("# TYPE http_requests_total counter\n")
+	for _, k := range requests {
+		fmt.Fprintf(&b, "http_requests_total{method=%q,route=%q,status=\"%d\"} %d\n", k.method, k.route, k.status, m.requests[k])
+	}
+
+	routes := make([]routeKey, 0, len(m.latencies))
+	for k := range m.latencies {
+		routes = append(routes, k)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].route != routes[j].route {
+			return routes[i].route < routes[j].route
+		}
+		return routes[i].method < routes[j].method
+	})
+	b.WriteString("# HELP http_request_duration_seconds HTTP request latency.\n")
+	b.WriteString("# TYPE http_request_duration_seconds histogram\n")
+	for _, k := range routes {
+		h := m.latencies[k]
+		var cumulative uint64
+		for i, bound := range latencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "http_request_duration_seconds_bucket{method=%q,route=%q,le=%q} %d\n", k.method, k.route, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&b, "http_request_duration_seconds_bucket{method=%q,route=%q,le=\"+Inf\"} %d\n", k.method, k.route, h.count)
+		fmt.Fprintf(&b, "http_request_duration_seconds_sum{method=%q,route=%q} %g\n", k.method, k.route, h.sum)
+		fmt.Fprintf(&b, "http_request_duration_seconds_count{method=%q,route=%q} %d\n", k.method, k.route, h.count)
+	}
+	m.mu.Unlock()
+
+	writeGauge(&b, "http_requests_in_flight", "Requests currently being served.", float64(m.inFlight.Load()))
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	writeGauge(&b, "go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine()))
+	writeGauge(&b, "go_memstats_alloc_bytes", "Bytes of allocated heap objects.", float64(mem.Alloc))
+	writeGauge(&b, "go_memstats_heap_inuse_bytes", "Bytes in in-use heap spans.", float64(mem.HeapInuse))
+	writeGauge(&b, "go_memstats_sys_bytes", "Bytes of memory obtained from the OS.", float64(mem.Sys))
+	b.WriteString("# HELP go_gc_cycles_total Number of completed GC cycles.\n")
+	b.WriteString("# TYPE go_gc_cycles_total counter\n")
+	fmt.Fprintf(&b, "go_gc_cycles_total %d\n", mem.NumGC)
+	b.WriteString("# HELP go_info Information about the Go environment.\n")
+	b.WriteString("# TYPE go_info gauge\n")
+	fmt.Fprintf(&b, "go_info{version=%q} 1\n", runtime.Version())
+	writeGauge(&b, "process_start_time_seconds", "Start time of the process since unix epoch in seconds.", float64(m.started.Unix()))
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func writeGauge(b *strings.Builder, name, help string, value float64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", name, help, name, name, value)
+}
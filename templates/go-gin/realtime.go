@@ -0,0 +1,556 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// heartbeatInterval is how often idle connections get a heartbeat event
+	// (and, on WebSockets, a ping). Clients reconnect when they miss two.
+	heartbeatInterval = 25 * time.Second
+
+	// pongWait is how long a WebSocket may stay silent before it is
+	// considered dead.
+	pongWait = 2 * heartbeatInterval
+
+	// writeWait bounds every write, so a stalled client can't hold a
+	// connection goroutine forever.
+	writeWait = 10 * time.Second
+
+	// subscriberBuffer is the number of events queued per connection.
+	// Subscribers that fall this far behind are disconnected and resume
+	// from the replay buffer when they reconnect.
+	subscriberBuffer = 64
+
+	// replaySize is the number of recent events kept for clients that
+	// reconnect with the ID of the last event they saw.
+	replaySize = 256
+
+	// maxTopics limits the subscriptions of a single connection.
+	maxTopics = 32
+)
+
+var validTopic = regexp.MustCompile(`^[A-Za-z0-9._:/-]{1,128}$`)
+
+// serverTopic reports whether only the server may publish to topic: "docs",
+// where the document store announces its changes, and anything under
+// "server." or "sys.". Clients can still subscribe to them.
+func serverTopic(topic string) bool {
+	return topic == "docs" || strings.HasPrefix(topic, "server.") || strings.HasPrefix(topic, "sys.")
+}
+
+// RealtimeEvent is a message pushed to the subscribers of a topic.
+type RealtimeEvent struct {
+	ID    uint64          `json:"id"`
+	Topic string          `json:"topic"`
+	Type  string          `json:"type"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Time  time.Time       `json:"time"`
+}
+
+// PublishRequest is the body of POST /api/realtime/publish.
+type PublishRequest struct {
+	Topic string          `json:"topic" binding:"required"`
+	Type  string          `json:"type" binding:"required"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// RealtimeStats is returned by GET /api/realtime/stats.
+type RealtimeStats struct {
+	Connections int            `json:"connections"`
+	Topics      map[string]int `json:"topics"`
+	Published   uint64         `json:"published"`
+	Dropped     uint64         `json:"dropped"`
+}
+
+// Hub fans events out to WebSocket and Server-Sent Events clients by topic.
+// Publishing never blocks: a subscriber whose queue is full is disconnected
+// and catches up from the replay buffer when it reconnects.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+	topics      map[string]map[*subscriber]struct{}
+	history     []RealtimeEvent // the last replaySize events, oldest first
+	lastID      uint64
+	dropped     uint64
+	closed      bool
+	origins     map[string]bool // cross-origin pages allowed to open WebSockets
+	guards      map[string]func(*http.Request) error
+}
+
+type subscriber struct {
+	events chan RealtimeEvent
+	topics map[string]bool // guarded by Hub.mu
+	req    *http.Request   // that opened the connection, for Hub.guards
+
+	done   chan struct{}
+	once   sync.Once
+	reason string // why done was closed, set before closing
+}
+
+func (s *subscriber) stop(reason string) {
+	s.once.Do(func() {
+		s.reason = reason
+		close(s.done)
+	})
+}
+
+// NewHub creates an empty hub.
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: map[*subscriber]struct{}{},
+		topics:      map[string]map[*subscriber]struct{}{},
+		guards:      map[string]func(*http.Request) error{},
+	}
+}
+
+// Guard only lets clients subscribe to topic when check accepts the
+// request of their connection, e.g. with SyncAuth's bearer token.
+func (h *Hub) Guard(topic string, check func(*http.Request) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.guards[topic] = check
+}
+
+// Publish sends an event to every subscriber of topic. data is encoded as
+// JSON; pass nil for events without a payload.
+func (h *Hub) Publish(topic, eventType string, data any) (RealtimeEvent, error) {
+	var raw json.RawMessage
+	if data != nil {
+		var err error
+		if raw, err = json.Marshal(data); err != nil {
+			return RealtimeEvent{}, err
+		}
+	}
+	return h.publish(topic, eventType, raw)
+}
+
+func (h *Hub) publish(topic, eventType string, data json.RawMessage) (RealtimeEvent, error) {
+	if !validTopic.MatchString(topic) {
+		return RealtimeEvent{}, fmt.Errorf("invalid topic %q", topic)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	ev := RealtimeEvent{ID: h.lastID, Topic: topic, Type: eventType, Data: data, Time: time.Now().UTC()}
+
+	if len(h.history) == replaySize {
+		copy(h.history, h.history[1:])
+		h.history = h.history[:replaySize-1]
+	}
+	h.history = append(h.history, ev)
+
+	for s := range h.topics[topic] {
+		h.deliver(s, ev)
+	}
+	return ev, nil
+}
+
+// deliver queues ev for s without blocking. h.mu must be held.
+func (h *Hub) deliver(s *subscriber, ev RealtimeEvent) {
+	select {
+	case s.events <- ev:
+	default:
+		h.dropped++
+		h.remove(s)
+		s.stop("slow consumer")
+	}
+}
+
+// connect registers a new subscriber for the connection opened by r, or
+// returns nil once the hub is closed.
+func (h *Hub) connect(r *http.Request) *subscriber {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	s := &subscriber{
+		events: make(chan RealtimeEvent, subscriberBuffer),
+		topics: map[string]bool{},
+		req:    r,
+		done:   make(chan struct{}),
+	}
+	h.subscribers[s] = struct{}{}
+	return s
+}
+
+func (h *Hub) disconnect(s *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(s)
+	s.stop("")
+}
+
+// remove unregisters s from the hub. h.mu must be held.
+func (h *Hub) remove(s *subscriber) {
+	delete(h.subscribers, s)
+	for topic := range s.topics {
+		delete(h.topics[topic], s)
+		if len(h.topics[topic]) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+}
+
+// subscribe adds topics to s and returns the buffered events of those
+// topics newer than since. The caller sends them before anything queued on
+// s; they bypass the queue, which is smaller than the replay buffer and
+// would drop a client that reconnects far behind.
+func (h *Hub) subscribe(s *subscriber, topics []string, since uint64) ([]RealtimeEvent, error) {
+	for _, topic := range topics {
+		if !validTopic.MatchString(topic) {
+			return nil, fmt.Errorf("invalid topic %q", topic)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[s]; !ok {
+		return nil, errors.New("subscriber is closed")
+	}
+	for _, topic := range topics {
+		if check := h.guards[topic]; check != nil {
+			if err := check(s.req); err != nil {
+				return nil, fmt.Errorf("topic %q: %w", topic, err)
+			}
+		}
+	}
+
+	added := map[string]bool{}
+	for _, topic := range topics {
+		if s.topics[topic] {
+			continue
+		}
+		if len(s.topics) >= maxTopics {
+			return nil, fmt.Errorf("too many topics (max %d)", maxTopics)
+		}
+		s.topics[topic] = true
+		added[topic] = true
+		if h.topics[topic] == nil {
+			h.topics[topic] = map[*subscriber]struct{}{}
+		}
+		h.topics[topic][s] = struct{}{}
+	}
+
+	var replay []RealtimeEvent
+	if since > 0 {
+		for _, ev := range h.history {
+			if ev.ID > since && added[ev.Topic] {
+				replay = append(replay, ev)
+			}
+		}
+	}
+	return replay, nil
+}
+
+func (h *Hub) unsubscribe(s *subscriber, topics []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, topic := range topics {
+		if !s.topics[topic] {
+			continue
+		}
+		delete(s.topics, topic)
+		delete(h.topics[topic], s)
+		if len(h.topics[topic]) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+}
+
+// Stats returns the number of connections and subscribers per topic.
+func (h *Hub) Stats() RealtimeStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stats := RealtimeStats{
+		Connections: len(h.subscribers),
+		Topics:      map[string]int{},
+		Published:   h.lastID,
+		Dropped:     h.dropped,
+	}
+	for topic, subs := range h.topics {
+		stats.Topics[topic] = len(subs)
+	}
+	return stats
+}
+
+// Close disconnects all clients and rejects new ones. Register it with
+// Server.BeforeDrain: open streams would otherwise hold up the shutdown
+// until the drain timeout.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for s := range h.subscribers {
+		h.remove(s)
+		s.stop("server shutting down")
+	}
+}
+
+// Register adds the realtime endpoints to api. The stream endpoints are
+// not part of the generated client; use frontend/src/lib/realtime.ts.
+func (h *Hub) Register(api *API) {
+	api.group.GET("/realtime/ws", h.serveWebSocket)
+	api.group.GET("/realtime/sse", h.serveEvents)
+
+	Post(api, "/realtime/publish", "publishEvent", "Publish an event to a topic", func(c *gin.Context, req PublishRequest) (RealtimeEvent, error) {
+		if serverTopic(req.Topic) {
+			return RealtimeEvent{}, Errorf(http.StatusForbidden, "only the server publishes to %q", req.Topic)
+		}
+		ev, err := h.publish(req.Topic, req.Type, req.Data)
+		if err != nil {
+			return RealtimeEvent{}, Errorf(http.StatusBadRequest, "%v", err)
+		}
+		return ev, nil
+	})
+	Get(api, "/realtime/stats", "getRealtimeStats", "Realtime connection statistics", func(c *gin.Context) (RealtimeStats, error) {
+		return h.Stats(), nil
+	})
+}
+
+// lastEventID returns the ID a reconnecting client has seen, from the
+// "since" query parameter or the standard Last-Event-ID header.
+func lastEventID(c *gin.Context) uint64 {
+	value := c.Query("since")
+	if value == "" {
+		value = c.GetHeader("Last-Event-ID")
+	}
+	id, _ := strconv.ParseUint(value, 10, 64)
+	return id
+}
+
+func heartbeat() RealtimeEvent {
+	return RealtimeEvent{Type: "heartbeat", Time: time.Now().UTC()}
+}
+
+// AllowOrigins lets pages from the given origins ("*" for any) open
+// WebSockets, in addition to same-origin pages. Pass the CORS allow list.
+func (h *Hub) AllowOrigins(origins []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.origins = map[string]bool{}
+	for _, o := range origins {
+		h.origins[strings.TrimSuffix(o, "/")] = true
+	}
+}
+
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // not a browser
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.origins["*"] || h.origins[origin]
+}
+
+// clientMessage is sent by WebSocket clients to change their subscriptions.
+type clientMessage struct {
+	Action string   `json:"action"` // "subscribe" or "unsubscribe"
+	Topics []string `json:"topics"`
+}
+
+// serveWebSocket streams events over a WebSocket. Topics are taken from
+// the "topic" query parameters and can be changed later with
+// clientMessage frames.
+func (h *Hub) serveWebSocket(c *gin.Context) {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     h.checkOrigin,
+	}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return // Upgrade has already replied with an HTTP error
+	}
+	defer conn.Close()
+
+	s := h.connect(c.Request)
+	if s == nil {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(writeWait))
+		return
+	}
+	defer h.disconnect(s)
+
+	logger := RequestLogger(c)
+	replay, err := h.subscribe(s, c.QueryArray("topic"), lastEventID(c))
+	if err != nil {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()), time.Now().Add(writeWait))
+		return
+	}
+
+	go h.readWebSocket(conn, s, logger)
+
+	for _, ev := range replay {
+		conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := conn.WriteJSON(ev); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case ev := <-s.events:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteJSON(ev); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteJSON(heartbeat()); err != nil {
+				return
+			}
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		case <-s.done:
+			if s.reason != "" {
+				logger.Debug("closing realtime connection", "reason", s.reason)
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, s.reason), time.Now().Add(writeWait))
+			}
+			return
+		}
+	}
+}
+
+// readWebSocket handles subscription changes and pongs until the client
+// goes away. All writes happen in serveWebSocket.
+func (h *Hub) readWebSocket(conn *websocket.Conn, s *subscriber, logger *slog.Logger) {
+	defer s.stop("")
+
+	conn.SetReadLimit(4096)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var msg clientMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				logger.Debug("realtime connection lost", "error", err)
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		switch msg.Action {
+		case "subscribe":
+			if _, err := h.subscribe(s, msg.Topics, 0); err != nil {
+				logger.Warn("realtime subscribe rejected", "error", err)
+				s.stop(err.Error())
+				return
+			}
+		case "unsubscribe":
+			h.unsubscribe(s, msg.Topics)
+		default:
+			logger.Warn("unknown realtime action", "action", msg.Action)
+		}
+	}
+}
+
+// serveEvents streams events as Server-Sent Events, for clients and proxies
+// that can't use WebSockets. Topics are fixed for the life of the stream.
+func (h *Hub) serveEvents(c *gin.Context) {
+	topics := c.QueryArray("topic")
+	if len(topics) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "at least one topic is required"})
+		return
+	}
+
+	s := h.connect(c.Request)
+	if s == nil {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: "server shutting down"})
+		return
+	}
+	defer h.disconnect(s)
+
+	replay, err := h.subscribe(s, topics, lastEventID(c))
+	if err != nil {
+		status := http.StatusBadRequest
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			status = apiErr.Status
+		}
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable nginx response buffering
+	c.Status(http.StatusOK)
+
+	rc := http.NewResponseController(c.Writer)
+	write := func(format string, args ...any) bool {
+		rc.SetWriteDeadline(time.Now().Add(writeWait))
+		if _, err := fmt.Fprintf(c.Writer, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	writeEvent := func(ev RealtimeEvent) bool {
+		data, _ := json.Marshal(ev)
+		if ev.ID == 0 {
+			return write("data: %s\n\n", data)
+		}
+		return write("id: %d\ndata: %s\n\n", ev.ID, data)
+	}
+
+	if !write("retry: 3000\n\n") {
+		return
+	}
+	for _, ev := range replay {
+		if !writeEvent(ev) {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case ev := <-s.events:
+			if !writeEvent(ev) {
+				return
+			}
+		case <-ticker.C:
+			if !writeEvent(heartbeat()) {
+				return
+			}
+		case <-s.done:
+			return
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
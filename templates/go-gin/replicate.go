@@ -0,0 +1,585 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Replication keeps a DocStore in sync with a hub: the /api/docs endpoints
+// of a Go + Gin server. The hub is the authority. A replica pulls the
+// hub's changes, merges those that collide with its own unsynced edits,
+// and pushes its edits with the revision they were based on. The hub only
+// accepts an edit of the revision it has, so a replica always merges
+// before its edit lands. This file is the same in every Go template.
+
+// ChangesResponse is returned by GET /api/docs/changes.
+type ChangesResponse struct {
+	Changes []Change `json:"changes"`
+	Seq     uint64   `json:"seq"` // pass as ?since= next time
+}
+
+// PushRequest is the body of POST /api/docs/push.
+type PushRequest struct {
+	Changes []Change `json:"changes"` // with the BaseRev each was edited from
+}
+
+// PushResult lists the hub's current version of every pushed change it
+// rejected because the document changed there in the meantime.
+type PushResult struct {
+	Rejected []Change `json:"rejected"`
+}
+
+// MergeMode decides how a document edited on both sides is merged.
+type MergeMode int
+
+const (
+	// MergeFields keeps the fields each side changed. A field changed on
+	// both sides gets the later value and is reported as a conflict.
+	MergeFields MergeMode = iota
+	// MergeLWW keeps the later version of the whole document (last writer
+	// wins) and reports the other as a conflict.
+	MergeLWW
+)
+
+// Conflict is a document that was edited on both sides. The merged
+// version is stored; Local and Remote are the versions that collided, nil
+// for a side that deleted the document.
+type Conflict struct {
+	ID     string    `json:"id"`
+	Local  Doc       `json:"local"`
+	Remote Doc       `json:"remote"`
+	Fields []string  `json:"fields,omitempty"` // changed differently on both sides
+	Time   time.Time `json:"time"`
+}
+
+// SyncStatus describes the replicator for the UI.
+type SyncStatus struct {
+	Hub       string
+	Syncing   bool
+	Online    bool // the last sync reached the hub
+	LastSync  time.Time
+	Pending   int // local changes the hub doesn't have yet
+	Conflicts int
+	Pulled    int // documents the last sync changed here
+	Err       error
+}
+
+// Replicator syncs a store with a hub. Unsynced changes are simply the
+// documents whose revision differs from the last one agreed with the hub,
+// so edits made offline stay queued across restarts until a sync succeeds.
+type Replicator struct {
+	store  *DocStore
+	hub    string
+	path   string
+	mode   MergeMode
+	client *http.Client
+	kick   chan struct{}
+
+	syncMu sync.Mutex // one sync at a time
+	saveMu sync.Mutex
+
+	mu       sync.Mutex
+	token    string
+	state    syncState
+	status   SyncStatus
+	onStatus func(SyncStatus)
+}
+
+// syncState is saved next to the store.
+type syncState struct {
+	Hub        string            `json:"hub"`
+	Checkpoint uint64            `json:"checkpoint"` // hub seq pulled up to
+	Base       map[string]Change `json:"base"`       // last version agreed with the hub
+	Conflicts  []Conflict        `json:"conflicts,omitempty"`
+}
+
+// maxPushRounds bounds how often a sync pushes again after the hub
+// rejected changes that were merged in the meantime.
+const maxPushRounds = 3
+
+// NewReplicator syncs store with the hub at hubURL (e.g.
+// http://localhost:3000), keeping its state in the file at path.
+// Switching to another hub starts over with a full sync.
+func NewReplicator(store *DocStore, hubURL, path string, mode MergeMode) (*Replicator, error) {
+	u, err := url.Parse(hubURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid hub URL %q: use http(s)://host[:port]", hubURL)
+	}
+	r := &Replicator{
+		store:  store,
+		hub:    strings.TrimRight(hubURL, "/"),
+		path:   path,
+		mode:   mode,
+		client: &http.Client{Timeout: 30 * time.Second},
+		kick:   make(chan struct{}, 1),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &r.state); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+	}
+	if r.state.Hub != r.hub {
+		r.state = syncState{Hub: r.hub}
+	}
+	if r.state.Base == nil {
+		r.state.Base = map[string]Change{}
+	}
+	r.status = SyncStatus{Hub: r.hub, Pending: len(r.pending()), Conflicts: len(r.state.Conflicts)}
+	return r, nil
+}
+
+// SetToken sends token to the hub as a bearer token, for a hub started
+// with --sync-token.
+func (r *Replicator) SetToken(token string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.token = token
+}
+
+// OnStatus calls fn whenever the status changes, from the syncing goroutine.
+func (r *Replicator) OnStatus(fn func(SyncStatus)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onStatus = fn
+}
+
+// Status returns the current status.
+func (r *Replicator) Status() SyncStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// Run syncs every interval, and shortly after local changes, until ctx is
+// done. While the hub is unreachable changes wait in the store and go out
+// with the first sync that gets through.
+func (r *Replicator) Run(ctx context.Context, interval time.Duration) {
+	cancel := r.store.Subscribe(func(Change) {
+		select {
+		case r.kick <- struct{}{}:
+		default:
+		}
+	})
+	defer cancel()
+
+	next := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.kick:
+			// Wait a moment so a burst of edits goes out as one push
+			if soon := time.Now().Add(time.Second); soon.Before(next) && r.Pending() > 0 {
+				next = soon
+			}
+		case <-time.After(time.Until(next)):
+			r.Sync(ctx)
+			next = time.Now().Add(interval)
+		}
+	}
+}
+
+// Sync pulls the hub's changes, merges them and pushes local changes.
+func (r *Replicator) Sync(ctx context.Context) error {
+	r.syncMu.Lock()
+	defer r.syncMu.Unlock()
+	r.update(func(st *SyncStatus) { st.Syncing = true })
+
+	pulled, err := r.pull(ctx)
+	for round := 0; err == nil && round < maxPushRounds; round++ {
+		var rejected, merged int
+		rejected, merged, err = r.push(ctx)
+		pulled += merged
+		if rejected == 0 {
+			break
+		}
+	}
+
+	if serr := r.save(); err == nil {
+		err = serr
+	}
+	r.update(func(st *SyncStatus) {
+		st.Syncing = false
+		st.Online = err == nil || !isNetworkError(err)
+		st.Pulled = pulled
+		st.Err = err
+		if err == nil {
+			st.LastSync = time.Now()
+		}
+	})
+	return err
+}
+
+// pull applies the hub's changes since the checkpoint and returns how
+// many documents changed here.
+func (r *Replicator) pull(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	since := r.state.Checkpoint
+	r.mu.Unlock()
+
+	var resp ChangesResponse
+	if err := r.call(ctx, http.MethodGet, fmt.Sprintf("/api/docs/changes?since=%d", since), nil, &resp); err != nil {
+		return 0, err
+	}
+	if resp.Seq < since {
+		// The hub went back in time, e.g. restored from a backup: pull everything
+		if err := r.call(ctx, http.MethodGet, "/api/docs/changes?since=0", nil, &resp); err != nil {
+			return 0, err
+		}
+	}
+	changed := 0
+	for _, c := range resp.Changes {
+		ok, err := r.receive(c)
+		if err != nil {
+			return changed, err
+		}
+		if ok {
+			changed++
+		}
+	}
+	r.mu.Lock()
+	r.state.Checkpoint = resp.Seq
+	r.mu.Unlock()
+	return changed, nil
+}
+
+// push sends the pending changes and merges the hub's version of the ones
+// it rejected, which then need another push. It returns how many were
+// rejected and how many documents the merges changed here.
+func (r *Replicator) push(ctx context.Context) (rejected, merged int, err error) {
+	r.mu.Lock()
+	pending := r.pending()
+	r.mu.Unlock()
+	if len(pending) == 0 {
+		return 0, 0, nil
+	}
+
+	var resp PushResult
+	if err := r.call(ctx, http.MethodPost, "/api/docs/push", PushRequest{Changes: pending}, &resp); err != nil {
+		return 0, 0, err
+	}
+	isRejected := map[string]bool{}
+	for _, c := range resp.Rejected {
+		isRejected[c.ID] = true
+		changed, err := r.receive(c)
+		if err != nil {
+			return len(resp.Rejected), merged, err
+		}
+		if changed {
+			merged++
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range pending {
+		if !isRejected[c.ID] {
+			c.BaseRev = ""
+			r.state.Base[c.ID] = c
+		}
+	}
+	return len(resp.Rejected), merged, nil
+}
+
+// receive brings a version from the hub into the store and reports
+// whether the store changed.
+func (r *Replicator) receive(remote Change) (bool, error) {
+	remote.Seq, remote.BaseRev = 0, ""
+	local := r.store.Version(remote.ID)
+	r.mu.Lock()
+	base := r.state.Base[remote.ID]
+	r.mu.Unlock()
+
+	if local.Rev == remote.Rev {
+		r.setBase(remote)
+		return false, nil
+	}
+	if remote.Rev == base.Rev && base.Rev != "" {
+		return false, nil // what we synced last, e.g. our own push coming back
+	}
+	if remote.Rev == "" {
+		// The hub doesn't have it (any more): push it as new
+		r.setBase(Change{ID: remote.ID})
+		return false, nil
+	}
+	if local.Rev == base.Rev {
+		// Not changed here since the last sync: take the hub's version
+		write := remote
+		write.BaseRev = local.Rev
+		rejected, err := r.store.Apply([]Change{write})
+		if err != nil || len(rejected) > 0 {
+			return false, err // edited just now; merged on the next sync
+		}
+		r.setBase(remote)
+		return true, nil
+	}
+
+	// Changed on both sides
+	merged, conflict := mergeChange(base, local, remote, r.mode)
+	merged.BaseRev = local.Rev
+	if sameContent(merged, remote) {
+		merged.Rev = remote.Rev
+	} else {
+		merged.Rev = newDocID()
+	}
+	rejected, err := r.store.Apply([]Change{merged})
+	if err != nil || len(rejected) > 0 {
+		return false, err
+	}
+	r.setBase(remote)
+	if conflict != nil {
+		r.mu.Lock()
+		r.state.Conflicts = slices.DeleteFunc(r.state.Conflicts, func(c Conflict) bool { return c.ID == conflict.ID })
+		r.state.Conflicts = append(r.state.Conflicts, *conflict)
+		r.mu.Unlock()
+	}
+	return true, nil
+}
+
+func (r *Replicator) setBase(c Change) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state.Base[c.ID] = c
+}
+
+// pending returns the local changes the hub doesn't have, each with the
+// revision it was based on. The caller holds r.mu.
+func (r *Replicator) pending() []Change {
+	changes, _ := r.store.Changes(0)
+	var pending []Change
+	for _, c := range changes {
+		base, known := r.state.Base[c.ID]
+		if c.Rev == base.Rev || (c.Deleted && !known) {
+			continue
+		}
+		c.Seq, c.BaseRev = 0, base.Rev
+		pending = append(pending, c)
+	}
+	return pending
+}
+
+// Pending returns the number of local changes waiting to be pushed.
+func (r *Replicator) Pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pending())
+}
+
+// Conflicts returns the conflicts not resolved yet, oldest first.
+func (r *Replicator) Conflicts() []Conflict {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.state.Conflicts)
+}
+
+// Resolve settles a conflict by storing version, or deleting the document
+// if version is nil. The result is pushed with the next sync.
+func (r *Replicator) Resolve(id string, version Doc) error {
+	var err error
+	if version == nil {
+		err = r.store.Del(id)
+	} else {
+		version = cloneDoc(version)
+		version["_id"] = id
+		_, err = r.store.Put(version)
+	}
+	if err != nil {
+		return err
+	}
+	r.Dismiss(id)
+	return nil
+}
+
+// Dismiss settles a conflict by keeping the merged version.
+func (r *Replicator) Dismiss(id string) {
+	r.mu.Lock()
+	r.state.Conflicts = slices.DeleteFunc(r.state.Conflicts, func(c Conflict) bool { return c.ID == id })
+	r.mu.Unlock()
+	if err := r.save(); err != nil {
+		log.Println("saving sync state:", err)
+	}
+	r.update(func(*SyncStatus) {})
+}
+
+// save writes the sync state next to the store.
+func (r *Replicator) save() error {
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.state, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(r.path, data)
+}
+
+// update changes the status, refreshes the counts and calls onStatus.
+func (r *Replicator) update(fn func(*SyncStatus)) {
+	r.mu.Lock()
+	fn(&r.status)
+	r.status.Pending = len(r.pending())
+	r.status.Conflicts = len(r.state.Conflicts)
+	status, onStatus := r.status, r.onStatus
+	r.mu.Unlock()
+	if onStatus != nil {
+		onStatus(status)
+	}
+}
+
+// call sends a JSON request to the hub and decodes the JSON response.
+func (r *Replicator) call(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.hub+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	r.mu.Lock()
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	r.mu.Unlock()
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&apiErr)
+		if apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		return fmt.Errorf("hub: %s %s: %s", method, path, apiErr.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// isNetworkError tells an unreachable hub from one that answered with an
+// error.
+func isNetworkError(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// mergeChange merges a document changed both here (local) and on the hub
+// (remote) since their common version base. It returns the merged version
+// and, when the edits collided, the conflict.
+func mergeChange(base, local, remote Change, mode MergeMode) (Change, *Conflict) {
+	conflict := &Conflict{ID: remote.ID, Local: local.Doc, Remote: remote.Doc, Time: time.Now()}
+	// Revisions are time-ordered, so the greater one was written last
+	later := remote
+	if local.Rev > remote.Rev {
+		later = local
+	}
+
+	// Deleted on one side and edited on the other: keep the edit
+	if local.Deleted || remote.Deleted {
+		switch {
+		case local.Deleted && remote.Deleted:
+			return remote, nil
+		case local.Deleted:
+			return remote, conflict
+		default:
+			return local, conflict
+		}
+	}
+
+	var baseDoc Doc
+	if !base.Deleted {
+		baseDoc = base.Doc
+	}
+	keys := map[string]bool{}
+	for _, d := range []Doc{baseDoc, local.Doc, remote.Doc} {
+		for k := range d {
+			keys[k] = true
+		}
+	}
+
+	if mode == MergeLWW {
+		for k := range keys {
+			l, inLocal := local.Doc[k]
+			rv, inRemote := remote.Doc[k]
+			if !sameValue(l, inLocal, rv, inRemote) {
+				conflict.Fields = append(conflict.Fields, k)
+			}
+		}
+		if len(conflict.Fields) == 0 {
+			return later, nil
+		}
+		slices.Sort(conflict.Fields)
+		return later, conflict
+	}
+
+	merged := Doc{}
+	for k := range keys {
+		b, inBase := baseDoc[k]
+		l, inLocal := local.Doc[k]
+		rv, inRemote := remote.Doc[k]
+		take, ok := l, inLocal
+		switch {
+		case sameValue(l, inLocal, rv, inRemote):
+		case sameValue(l, inLocal, b, inBase):
+			take, ok = rv, inRemote
+		case sameValue(rv, inRemote, b, inBase):
+		default:
+			conflict.Fields = append(conflict.Fields, k)
+			take, ok = later.Doc[k]
+		}
+		if ok {
+			merged[k] = take
+		}
+	}
+	if len(conflict.Fields) == 0 {
+		return Change{ID: remote.ID, Doc: merged}, nil
+	}
+	slices.Sort(conflict.Fields)
+	return Change{ID: remote.ID, Doc: merged}, conflict
+}
+
+// sameValue compares two optional JSON values.
+func sameValue(a any, aok bool, b any, bok bool) bool {
+	if aok != bok {
+		return false
+	}
+	ka, _ := indexKey(a)
+	kb, _ := indexKey(b)
+	return ka == kb
+}
+
+// sameContent tells whether two changes store the same document.
+func sameContent(a, b Change) bool {
+	if a.Deleted || b.Deleted {
+		return a.Deleted == b.Deleted
+	}
+	ka, _ := indexKey(a.Doc)
+	kb, _ := indexKey(b.Doc)
+	return ka == kb
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// HealthResponse is returned by GET /api/health.
+type HealthResponse struct {
+	Status        string         `json:"status"`
+	Message       string         `json:"message"`
+	Stack         string         `json:"stack"`
+	Version       string         `json:"version"`
+	UptimeSeconds int64          `json:"uptimeSeconds"`
+	BinarySize    int64          `json:"binarySize"` // bytes
+	Config        map[string]any `json:"config"`
+}
+
+// registerRoutes adds the application's /api endpoints. Use the typed
+// helpers (Get, Post, Put, Delete) so every route shows up in
+// /api/openapi.json and in the generated frontend client; run
+// `go run . gen-client` after changing them. Call hub.Publish to push
+// changes to connected clients.
+func registerRoutes(api *API, cfg *Config, hub *Hub) {
+	Get(api, "/health", "getHealth", "Health check", func(c *gin.Context) (HealthResponse, error) {
+		return HealthResponse{
+			Status:        "ok",
+			Message:       "{{PROJECT_NAME}} is running!",
+			Stack:         "{{STACK}}",
+			Version:       buildVersion().Version,
+			UptimeSeconds: uptime(),
+			BinarySize:    binarySize(),
+			Config:        cfg.Public(),
+		}, nil
+	})
+	Get(api, "/version", "getVersion", "Build metadata of the running binary", func(c *gin.Context) (VersionInfo, error) {
+		return buildVersion(), nil
+	})
+}
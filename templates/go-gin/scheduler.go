@@ -0,0 +1,467 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Job is a unit of periodic work, such as cleanup or sending digests.
+type Job struct {
+	Name     string
+	Schedule string        // cron expression ("*/15 * * * *"), @daily, @hourly, "@every 10m", ...
+	Jitter   time.Duration // random delay added to every run, to spread load
+	Run      func(ctx context.Context) error
+}
+
+// JobStatus is the state of a job, persisted across restarts.
+type JobStatus struct {
+	Name           string     `json:"name"`
+	Schedule       string     `json:"schedule"`
+	Running        bool       `json:"running"`
+	LastRun        *time.Time `json:"lastRun"`
+	LastDurationMs int64      `json:"lastDurationMs"`
+	LastError      string     `json:"lastError,omitempty"`
+	NextRun        time.Time  `json:"nextRun"`
+	Runs           int        `json:"runs"`
+	Failures       int        `json:"failures"`
+}
+
+// ErrJobRunning is returned by Trigger while the job is already running.
+var ErrJobRunning = errors.New("job is already running")
+
+// Scheduler runs jobs on their schedules. A job never overlaps itself:
+// runs that come due while it is still busy are skipped. Last and next run
+// times are saved to a state file, so a run missed while the server was
+// down happens right after it starts again.
+type Scheduler struct {
+	path string
+
+	mu     sync.Mutex
+	jobs   map[string]*scheduledJob
+	saved  map[string]JobStatus
+	saveMu sync.Mutex // serializes writes of the state file
+	guard  func(write func() error) error
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+}
+
+type scheduledJob struct {
+	Job
+	schedule Schedule
+	status   JobStatus
+	trigger  chan struct{}
+}
+
+// NewScheduler creates a scheduler keeping its state in the file at path,
+// or only in memory if path is empty.
+func NewScheduler(path string) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{path: path, jobs: map[string]*scheduledJob{}, saved: map[string]JobStatus{}, ctx: ctx, cancel: cancel}
+	s.guard = func(write func() error) error { return write() }
+	if data, err := os.ReadFile(path); err == nil && path != "" {
+		if err := json.Unmarshal(data, &s.saved); err != nil {
+			slog.Warn("ignoring unreadable job state", "path", path, "error", err)
+		}
+	}
+	return s
+}
+
+// Guard runs every write of the state file through guard, e.g. a backup
+// lock. Call it before Start.
+func (s *Scheduler) Guard(guard func(write func() error) error) {
+	s.guard = guard
+}
+
+// Add registers a job. Jobs added after Start are started right away.
+func (s *Scheduler) Add(job Job) error {
+	schedule, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.jobs[job.Name]; exists {
+		return fmt.Errorf("job %s already exists", job.Name)
+	}
+
+	j := &scheduledJob{Job: job, schedule: schedule, trigger: make(chan struct{}, 1)}
+	j.status = s.saved[job.Name]
+	j.status.Name, j.status.Schedule, j.status.Running = job.Name, job.Schedule, false
+	// Keep a saved next run, even one in the past, unless the schedule changed
+	if saved, ok := s.saved[job.Name]; !ok || saved.Schedule != job.Schedule || saved.NextRun.IsZero() {
+		j.status.NextRun = j.next(time.Now())
+	}
+	s.jobs[job.Name] = j
+
+	if s.started {
+		s.launch(j)
+	}
+	return nil
+}
+
+// Start begins running the registered jobs.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started = true
+	for _, j := range s.jobs {
+		s.launch(j)
+	}
+}
+
+func (s *Scheduler) launch(j *scheduledJob) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			s.mu.Lock()
+			wait := time.Until(j.status.NextRun)
+			s.mu.Unlock()
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-s.ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			case <-j.trigger:
+				timer.Stop()
+			}
+			if s.ctx.Err() != nil {
+				return
+			}
+			s.run(j)
+		}
+	}()
+}
+
+// run executes one run of j and records the outcome.
+func (s *Scheduler) run(j *scheduledJob) {
+	s.mu.Lock()
+	j.status.Running = true
+	s.mu.Unlock()
+
+	start := time.Now()
+	slog.Info("job started", "job", j.Name)
+	err := func() (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				slog.Error("job panicked", "job", j.Name, "panic", p, "stack", string(debug.Stack()))
+				err = fmt.Errorf("panic: %v", p)
+			}
+		}()
+		return j.Run(s.ctx)
+	}()
+	elapsed := time.Since(start)
+
+	s.mu.Lock()
+	j.status.Running = false
+	j.status.LastRun = &start
+	j.status.LastDurationMs = elapsed.Milliseconds()
+	j.status.Runs++
+	j.status.LastError = ""
+	if err != nil {
+		j.status.Failures++
+		j.status.LastError = err.Error()
+	}
+	j.status.NextRun = j.next(time.Now())
+	next := j.status.NextRun
+	s.mu.Unlock()
+
+	if err != nil {
+		slog.Error("job failed", "job", j.Name, "duration", elapsed.String(), "error", err)
+	} else {
+		slog.Info("job finished", "job", j.Name, "duration", elapsed.String(), "next", next.Format(time.RFC3339))
+	}
+	if err := s.save(); err != nil {
+		slog.Warn("could not save job state", "path", s.path, "error", err)
+	}
+}
+
+// next returns the first run after t, plus jitter.
+func (j *scheduledJob) next(t time.Time) time.Time {
+	next := j.schedule.Next(t)
+	if j.Jitter > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(j.Jitter))))
+	}
+	return next
+}
+
+// Trigger runs a job now, outside its schedule.
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return Errorf(http.StatusNotFound, "job %s not found", name)
+	}
+	if !s.started {
+		return Errorf(http.StatusServiceUnavailable, "scheduler is not running")
+	}
+	if j.status.Running {
+		return ErrJobRunning
+	}
+	select {
+	case j.trigger <- struct{}{}:
+		return nil
+	default:
+		return ErrJobRunning // already triggered
+	}
+}
+
+// Status returns the state of every job, sorted by name.
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := []JobStatus{}
+	for _, j := range s.jobs {
+		statuses = append(statuses, j.status)
+	}
+	slices.SortFunc(statuses, func(a, b JobStatus) int { return strings.Compare(a.Name, b.Name) })
+	return statuses
+}
+
+// save writes the job state atomically, through the guard.
+func (s *Scheduler) save() error {
+	if s.path == "" {
+		return nil
+	}
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.Lock()
+	for name, j := range s.jobs {
+		s.saved[name] = j.status
+	}
+	data, err := json.MarshalIndent(s.saved, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return s.guard(func() error {
+		tmp := s.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0644); err != nil {
+			return err
+		}
+		return os.Rename(tmp, s.path)
+	})
+}
+
+// Shutdown stops scheduling, cancels the context of running jobs and waits
+// for them to return until ctx is done. Register it with srv.OnShutdown.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.cancel()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("jobs still running at shutdown: %w", ctx.Err())
+	}
+}
+
+// Register adds the job endpoints to the admin API.
+func (s *Scheduler) Register(admin *API) {
+	Get(admin, "/jobs", "listJobs", "List scheduled jobs with their last and next runs", func(c *gin.Context) ([]JobStatus, error) {
+		return s.Status(), nil
+	})
+	Post(admin, "/jobs/:name/run", "runJob", "Run a job now", func(c *gin.Context, _ Empty) (Empty, error) {
+		err := s.Trigger(c.Param("name"))
+		if errors.Is(err, ErrJobRunning) {
+			err = Errorf(http.StatusConflict, "job %s is already running", c.Param("name"))
+		}
+		return Empty{}, err
+	})
+}
+
+// Schedule computes when a job runs next.
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// every runs at a fixed interval.
+type every time.Duration
+
+func (e every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+// cronSchedule is a standard five-field cron expression, one bit per
+// allowed value.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// ParseSchedule parses a cron expression (minute hour day-of-month month
+// day-of-week, with *, lists, ranges, steps and month/day names), one of
+// the @yearly, @monthly, @weekly, @daily and @hourly shorthands, or
+// "@every <duration>". Cron times are in the local time zone.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || interval < time.Second {
+			return nil, fmt.Errorf("invalid interval in %q", spec)
+		}
+		return every(interval), nil
+	}
+	if expanded, ok := cronDescriptors[spec]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q must have 5 fields: minute hour day-of-month month day-of-week", spec)
+	}
+	var c cronSchedule
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1 // 7 is Sunday too
+	}
+	// As in cron, a field starting with * (such as */2) doesn't restrict
+	// the day, so the other day field alone decides
+	c.domAny, c.dowAny = strings.HasPrefix(fields[2], "*"), strings.HasPrefix(fields[4], "*")
+	if c.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("schedule %q never runs", spec)
+	}
+	return c, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps
+// into a bit set. names, if given, are accepted for min, min+1, ...
+func parseCronField(field string, min, max int, names []string) (uint64, error) {
+	value := func(s string) (int, error) {
+		for i, name := range names {
+			if strings.EqualFold(s, name) {
+				return min + i, nil
+			}
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("%q is not between %d and %d", s, min, max)
+		}
+		return n, nil
+	}
+
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = value(from); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = value(to); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = max // "5/15" means from 5 to the end in steps of 15
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		}
+		for i := lo; i <= hi; i += step {
+			bits |= 1 << i
+		}
+	}
+	return bits, nil
+}
+
+func (c cronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0) // impossible dates like Feb 30 never match
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<t.Month()) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches applies cron's rule that when both day fields are restricted,
+// a day matching either one qualifies.
+func (c cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<t.Weekday()) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
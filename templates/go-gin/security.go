@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultCSP allows the embedded frontend (including Tailwind's inline
+// styles) and same-origin API and realtime connections.
+const defaultCSP = "default-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' data: blob:; connect-src 'self' ws: wss:; frame-ancestors 'none'; base-uri 'self'; form-action 'self'"
+
+// bodyLimitExempt lists path prefixes that take large uploads, such as
+// backup archives and blobs, and enforce their own limits.
+var bodyLimitExempt = []string{"/api/admin/restore", "/api/blobs"}
+
+// Security wraps h with the middleware enabled in cfg. It works for any
+// http.Handler, including a gin engine. CORS is applied whenever origins
+// are configured; everything else only with --security.
+func Security(cfg *Config, h http.Handler) http.Handler {
+	if cfg.Security {
+		h = MaxBody(int64(cfg.MaxBodyMB)<<20, bodyLimitExempt...)(h)
+		if cfg.RateLimit > 0 {
+			h = NewRateLimiter(float64(cfg.RateLimit), cfg.RateBurst).Middleware(h)
+		}
+	}
+	if origins := cfg.CORSOrigins(); len(origins) > 0 {
+		h = CORS(origins)(h)
+	}
+	if cfg.Security {
+		h = SecureHeaders(cfg.CSP, cfg.TLSEnabled())(h)
+		h = Recover(h)
+	}
+	return h
+}
+
+// writeJSONError writes {"error": message} with the given status.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// Recover turns a panic in next into a logged 500 JSON response instead of
+// a dropped connection.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err) // deliberate abort, let net/http handle it
+			}
+			slog.Error("panic serving request", "method", r.Method, "path", r.URL.Path, "panic", err, "stack", string(debug.Stack()))
+			writeJSONError(w, http.StatusInternalServerError, "internal server error")
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SecureHeaders sets the Content-Security-Policy and the usual hardening
+// headers. HSTS is only sent over HTTPS, where browsers honour it.
+func SecureHeaders(csp string, hsts bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			if csp != "" {
+				h.Set("Content-Security-Policy", csp)
+			}
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("X-Frame-Options", "DENY")
+			h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+			h.Set("Cross-Origin-Opener-Policy", "same-origin")
+			if hsts {
+				h.Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CORS allows cross-origin requests from the given origins ("*" for any)
+// and answers preflight requests.
+func CORS(origins []string) func(http.Handler) http.Handler {
+	allowed := map[string]bool{}
+	for _, o := range origins {
+		allowed[strings.TrimSuffix(o, "/")] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if origin == "" || !(allowed["*"] || allowed[origin]) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if allowed["*"] {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			h.Set("Access-Control-Expose-Headers", "X-Request-ID, Retry-After")
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+				if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+					h.Set("Access-Control-Allow-Headers", headers)
+				}
+				h.Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MaxBody rejects request bodies larger than limit bytes with 413, except
+// for paths starting with one of the exempt prefixes.
+func MaxBody(limit int64, exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, prefix := range exempt {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			if r.ContentLength > limit {
+				writeJSONError(w, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimiter is a per-client-IP token bucket: each IP may make rate
+// requests per second on average, with bursts of up to burst requests.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a limiter allowing rate requests per second per IP.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		rate:      rate,
+		burst:     math.Max(float64(burst), 1),
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token for key, or reports how long until one is available.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Forget idle clients now and then so the map doesn't grow forever
+	if now.Sub(l.lastSweep) > time.Minute {
+		full := time.Duration(l.burst / l.rate * float64(time.Second))
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > full {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b := l.buckets[key]
+	if b == nil {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// Middleware answers 429 with a Retry-After header once a client IP runs
+// out of tokens. The IP is the connection's remote address; forwarded
+// headers are ignored since they are trivial to spoof.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		if ok, wait := l.Allow(ip); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeJSONError(w, http.StatusTooManyRequests, "too many requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Self-update settings, stamped by the TinyApp Factory build when the
+// project has an update.pub (see `update-keygen`):
+//
+//	go build -ldflags "-X main.updateURL=https://example.com/catalog.json -X main.updatePublicKey=<base64>"
+//
+// Without a public key the app never updates itself.
+var (
+	updateURL       string
+	updatePublicKey string
+)
+
+// maxUpdateSize caps the size of a downloaded binary.
+const maxUpdateSize = 512 << 20
+
+// UpdateRelease is one entry of an update manifest. The manifest uses the
+// marketplace catalog format, a JSON array of cherries (or an object with a
+// "cherries" array); the entry whose id matches this app is used. The
+// download may be overridden per GOOS/GOARCH in platforms.
+type UpdateRelease struct {
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	Version   string                 `json:"version"`
+	Platforms map[string]UpdateAsset `json:"platforms,omitempty"`
+	UpdateAsset
+}
+
+// UpdateAsset is a downloadable binary. The signature is the ed25519
+// signature of updateMessage for it, base64-encoded, as printed by
+// `update-sign`.
+type UpdateAsset struct {
+	DownloadURL string `json:"downloadUrl"`
+	SHA256      string `json:"sha256,omitempty"`
+	Signature   string `json:"signature"`
+}
+
+// Updater checks a manifest for newer releases of this app and installs
+// them in place of the running executable.
+type Updater struct {
+	feed      string // http(s) URL, file:// URL or local path
+	publicKey ed25519.PublicKey
+	appID     string
+	client    *http.Client
+}
+
+// NewUpdater creates an updater reading the manifest at feed. Local files
+// are accepted as feeds, which makes updates easy to test.
+func NewUpdater(feed string) (*Updater, error) {
+	if feed == "" {
+		return nil, errors.New("no update feed configured")
+	}
+	if updatePublicKey == "" {
+		return nil, errors.New("no update public key in this build, releases can't be verified")
+	}
+	key, err := base64.StdEncoding.DecodeString(updatePublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid update public key")
+	}
+	return &Updater{
+		feed:      feed,
+		publicKey: key,
+		appID:     "{{PROJECT_SLUG}}",
+		client:    &http.Client{Timeout: 10 * time.Minute},
+	}, nil
+}
+
+// Check returns the release and asset to install, or nil if this build is
+// up to date.
+func (u *Updater) Check(ctx context.Context) (*UpdateRelease, *UpdateAsset, error) {
+	body, err := u.open(ctx, u.feed)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, 16<<20))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var releases []UpdateRelease
+	if err := json.Unmarshal(data, &releases); err != nil {
+		var catalog struct {
+			Cherries []UpdateRelease `json:"cherries"`
+		}
+		if json.Unmarshal(data, &catalog) != nil {
+			return nil, nil, fmt.Errorf("parse update manifest: %w", err)
+		}
+		releases = catalog.Cherries
+	}
+
+	current := buildVersion().Version
+	for i := range releases {
+		rel := &releases[i]
+		if rel.ID != u.appID || compareVersions(rel.Version, current) <= 0 {
+			continue
+		}
+		asset, ok := rel.Platforms[runtime.GOOS+"/"+runtime.GOARCH]
+		if !ok {
+			asset = rel.UpdateAsset
+		}
+		if asset.DownloadURL == "" {
+			return nil, nil, fmt.Errorf("release %s has no download for %s/%s", rel.Version, runtime.GOOS, runtime.GOARCH)
+		}
+		return rel, &asset, nil
+	}
+	return nil, nil, nil
+}
+
+// updateMessage is what release signatures sign: the app, the version and
+// the SHA-256 of the binary. Signing the digest alone would let an older
+// binary, or one of another app with the same key, pass as a new release.
+func updateMessage(appID, version string, sum []byte) []byte {
+	return []byte(appID + "|" + version + "|" + hex.EncodeToString(sum))
+}
+
+// reportedVersion returns the version from the --version output of a build
+// of this app, "<name> <version> (<commit>), built …", or "" if out is
+// something else.
+func reportedVersion(out []byte) string {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(string(out)), "{{PROJECT_NAME}} ")
+	if !ok {
+		return ""
+	}
+	version, _, _ := strings.Cut(rest, ",")
+	version, _, _ = strings.Cut(version, " ")
+	return version
+}
+
+// Apply downloads and verifies the asset, checks that it runs and reports
+// the release's version, then swaps it in for the running executable. The
+// previous binary is kept next to it with an .old suffix for `rollback`.
+// The new version runs after a restart.
+func (u *Updater) Apply(ctx context.Context, rel *UpdateRelease, asset *UpdateAsset) error {
+	exe, err := executablePath()
+	if err != nil {
+		return err
+	}
+
+	// Download next to the executable so the final rename stays on one
+	// filesystem and is atomic
+	tmp := exe + ".new"
+	defer os.Remove(tmp)
+	sum, err := u.download(ctx, u.resolve(asset.DownloadURL), tmp)
+	if err != nil {
+		return fmt.Errorf("download update: %w", err)
+	}
+
+	if asset.SHA256 != "" && !strings.EqualFold(asset.SHA256, hex.EncodeToString(sum)) {
+		return errors.New("update checksum mismatch")
+	}
+	sig, err := base64.StdEncoding.DecodeString(asset.Signature)
+	if err != nil || !ed25519.Verify(u.publicKey, updateMessage(u.appID, rel.Version, sum), sig) {
+		return errors.New("update signature is missing or invalid")
+	}
+
+	smokeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(smokeCtx, tmp, "--version").Output()
+	if err != nil {
+		return fmt.Errorf("new binary doesn't run: %w", err)
+	}
+	if reportedVersion(out) != rel.Version {
+		return fmt.Errorf("new binary reports %q, expected version %s", strings.TrimSpace(string(out)), rel.Version)
+	}
+
+	old := exe + ".old"
+	os.Remove(old)
+	if runtime.GOOS == "windows" {
+		// A running executable can be renamed but not replaced
+		if err := os.Rename(exe, old); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, exe); err != nil {
+			os.Rename(old, exe)
+			return err
+		}
+		return nil
+	}
+	if err := os.Link(exe, old); err != nil {
+		slog.Warn("could not keep a rollback copy", "error", err)
+	}
+	return os.Rename(tmp, exe)
+}
+
+// Run checks for updates now and then every interval until ctx is done.
+// Once a release has been installed it calls installed and returns.
+func (u *Updater) Run(ctx context.Context, interval time.Duration, installed func(version string)) {
+	if buildVersion().Version == "dev" {
+		slog.Info("self-update skipped for an unversioned build")
+		return
+	}
+	for {
+		rel, asset, err := u.Check(ctx)
+		if err != nil {
+			slog.Warn("update check failed", "feed", u.feed, "error", err)
+		} else if rel != nil {
+			slog.Info("installing update", "version", rel.Version, "current", buildVersion().Version)
+			if err := u.Apply(ctx, rel, asset); err != nil {
+				slog.Error("update failed", "version", rel.Version, "error", err)
+			} else {
+				installed(rel.Version)
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// open reads a feed or download location: an http(s) URL, a file:// URL or
+// a local path.
+func (u *Updater) open(ctx context.Context, location string) (io.ReadCloser, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", "{{PROJECT_SLUG}}/"+buildVersion().Version)
+		resp, err := u.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("GET %s: %s", location, resp.Status)
+		}
+		return resp.Body, nil
+	}
+	if after, ok := strings.CutPrefix(location, "file://"); ok {
+		location = after
+	}
+	return os.Open(location)
+}
+
+// resolve makes a download location relative to the feed absolute, so a
+// manifest can sit next to its binaries.
+func (u *Updater) resolve(location string) string {
+	if strings.Contains(location, "://") || filepath.IsAbs(location) {
+		return location
+	}
+	if base, err := url.Parse(u.feed); err == nil && (base.Scheme == "http" || base.Scheme == "https") {
+		if ref, err := url.Parse(location); err == nil {
+			return base.ResolveReference(ref).String()
+		}
+	}
+	return filepath.Join(filepath.Dir(strings.TrimPrefix(u.feed, "file://")), location)
+}
+
+// download writes location to path and returns its SHA-256 digest.
+func (u *Updater) download(ctx context.Context, location, path string) ([]byte, error) {
+	body, err := u.open(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(body, maxUpdateSize+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n > maxUpdateSize {
+		return nil, fmt.Errorf("larger than %d MB", maxUpdateSize>>20)
+	}
+	return h.Sum(nil), nil
+}
+
+// Rollback restores the binary that the last update replaced.
+func Rollback() error {
+	exe, err := executablePath()
+	if err != nil {
+		return err
+	}
+	old := exe + ".old"
+	if _, err := os.Stat(old); err != nil {
+		return fmt.Errorf("no previous version to roll back to (%s)", old)
+	}
+	if runtime.GOOS == "windows" {
+		replaced := exe + ".rolledback"
+		os.Remove(replaced)
+		if err := os.Rename(exe, replaced); err != nil {
+			return err
+		}
+	}
+	return os.Rename(old, exe)
+}
+
+func executablePath() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
+
+// compareVersions compares dotted versions like "1.2.0", "v1.10" or
+// "2.0.0-beta.1". A release sorts after its prereleases.
+func compareVersions(a, b string) int {
+	a, aPre, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	b, bPre, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return cmp.Compare(x, y)
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return strings.Compare(aPre, bPre)
+}
+
+// updateCommand runs the update subcommands and reports whether args named
+// one:
+//
+//	update [-url feed]                   install a newer release now (no restart)
+//	rollback                             restore the binary replaced by the last update
+//	update-keygen                        create update.key and update.pub for signing releases
+//	update-sign -version VERSION BINARY  print the manifest fields for a release binary
+func updateCommand(args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+	switch args[0] {
+	case "update":
+		fs := flag.NewFlagSet("update", flag.ContinueOnError)
+		feed := fs.String("url", updateURL, "update manifest URL or file")
+		if err := fs.Parse(args[1:]); err != nil {
+			return true, err
+		}
+		if env := os.Getenv("{{ENV_PREFIX}}_UPDATE_URL"); env != "" && *feed == updateURL {
+			*feed = env
+		}
+		u, err := NewUpdater(*feed)
+		if err != nil {
+			return true, err
+		}
+		rel, asset, err := u.Check(context.Background())
+		if err != nil {
+			return true, err
+		}
+		if rel == nil {
+			fmt.Println("Already up to date:", buildVersion().Version)
+			return true, nil
+		}
+		if err := u.Apply(context.Background(), rel, asset); err != nil {
+			return true, err
+		}
+		fmt.Printf("Updated %s -> %s, restart to run the new version\n", buildVersion().Version, rel.Version)
+		return true, nil
+
+	case "rollback":
+		if err := Rollback(); err != nil {
+			return true, err
+		}
+		fmt.Println("Restored the previous version, restart to run it")
+		return true, nil
+
+	case "update-keygen":
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return true, err
+		}
+		if _, err := os.Stat("update.key"); err == nil {
+			return true, errors.New("update.key already exists")
+		}
+		if err := os.WriteFile("update.key", []byte(base64.StdEncoding.EncodeToString(priv.Seed())+"\n"), 0600); err != nil {
+			return true, err
+		}
+		if err := os.WriteFile("update.pub", []byte(base64.StdEncoding.EncodeToString(pub)+"\n"), 0644); err != nil {
+			return true, err
+		}
+		fmt.Println("Wrote update.key (keep it secret, out of git) and update.pub (stamped into builds)")
+		return true, nil
+
+	case "update-sign":
+		fs := flag.NewFlagSet("update-sign", flag.ContinueOnError)
+		keyFile := fs.String("key", "update.key", "private key from update-keygen")
+		version := fs.String("version", "", "version of the release, as in the manifest")
+		if err := fs.Parse(args[1:]); err != nil {
+			return true, err
+		}
+		if fs.NArg() != 1 || *version == "" {
+			return true, errors.New("usage: update-sign [-key update.key] -version VERSION BINARY")
+		}
+		seed, err := os.ReadFile(*keyFile)
+		if err != nil {
+			return true, err
+		}
+		seed, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(seed)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return true, fmt.Errorf("%s is not an update key", *keyFile)
+		}
+		data, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return true, err
+		}
+		sum := sha256.Sum256(data)
+		asset := UpdateAsset{
+			DownloadURL: filepath.Base(fs.Arg(0)),
+			SHA256:      hex.EncodeToString(sum[:]),
+			Signature:   base64.StdEncoding.EncodeToString(ed25519.Sign(ed25519.NewKeyFromSeed(seed), updateMessage("{{PROJECT_SLUG}}", *version, sum[:]))),
+		}
+		out, _ := json.MarshalIndent(asset, "", "  ")
+		fmt.Println(string(out))
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Build metadata, stamped by the TinyApp Factory build:
+//
+//	go build -ldflags "-X main.version=1.2.0 -X main.commit=abc1234 -X main.buildTime=2024-05-01T12:00:00Z -X main.target=linux/amd64"
+//
+// Values left empty are filled in from debug.ReadBuildInfo.
+var (
+	version   string
+	commit    string
+	buildTime string
+	target    string
+)
+
+// startTime is used to report uptime.
+var startTime = time.Now()
+
+// VersionInfo describes the running binary.
+type VersionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	Target    string `json:"target"`
+	GoVersion string `json:"goVersion"`
+	Modified  bool   `json:"modified"` // built from a working tree with uncommitted changes
+}
+
+// buildVersion returns the build metadata, read once.
+var buildVersion = sync.OnceValue(func() VersionInfo {
+	info := VersionInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+		Target:    target,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value[:min(len(s.Value), 12)]
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if info.Target == "" {
+		info.Target = runtime.GOOS + "/" + runtime.GOARCH
+	}
+	if info.BuildTime == "" {
+		// Unstamped builds: the executable's modification time is close enough
+		if exe, err := os.Executable(); err == nil {
+			if fi, err := os.Stat(exe); err == nil {
+				info.BuildTime = fi.ModTime().UTC().Format(time.RFC3339)
+			}
+		}
+	}
+	return info
+})
+
+// String formats the build metadata for --version.
+func (v VersionInfo) String() string {
+	s := v.Version
+	if v.Commit != "" {
+		s += " (" + v.Commit
+		if v.Modified {
+			s += "-dirty"
+		}
+		s += ")"
+	}
+	return fmt.Sprintf("%s, built %s for %s with %s", s, v.BuildTime, v.Target, v.GoVersion)
+}
+
+// binarySize returns the size in bytes of the running executable, or 0 if
+// it can't be determined.
+var binarySize = sync.OnceValue(func() int64 {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0
+	}
+	fi, err := os.Stat(exe)
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+})
+
+// uptime returns how long the server has been running, in whole seconds.
+func uptime() int64 {
+	return int64(time.Since(startTime).Seconds())
+}
@@ -50,6 +50,30 @@ export async function getAllNotes() {
   return await db.query('type', { key: 'note' })
 }
 
+// Export/import in the format of the Go document store (docstore.go), so
+// data moves between web, server and desktop cherries
+export async function exportDocs() {
+  const { rows } = await db.allDocs()
+  return {
+    name: '{{PROJECT_SLUG}}',
+    exported: new Date().toISOString(),
+    docs: rows.map((row: any) => row.value)
+  }
+}
+
+export async function importDocs(data: any) {
+  // An export from exportDocs, a plain array of documents or db.allDocs()
+  const docs: any[] = Array.isArray(data) ? data : data.docs ?? data.rows?.map((row: any) => row.value) ?? []
+  for (const doc of docs) {
+    if (doc._deleted) {
+      await db.del(doc._id)
+    } else {
+      await db.put(doc)
+    }
+  }
+  return docs.length
+}
+
 // Database status and info
 export async function getDatabaseInfo() {
   const stats = await db.stats()